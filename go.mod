@@ -3,3 +3,8 @@ module github.com/coin-tracker/transaction-tracker
 go 1.22.4
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0 // indirect
+)
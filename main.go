@@ -1,58 +1,350 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/server"
 	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
 	usecase "github.com/coin-tracker/transaction-tracker/usecase"
-	"gopkg.in/yaml.v3"
+	"github.com/coin-tracker/transaction-tracker/watch"
 )
 
 // Config struct to hold the configuration from config.yml
 
 func main() {
+	// Exit only once the deferred calls of the command ran, the commands log their failures
+	if err := run(os.Args[1:]); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run executes the command selected by the arguments, reports are generated without a command
+func run(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			return serve(args[1:])
+		case "watch":
+			return watchWallets(args[1:])
+		case "validate-config":
+			return validateConfig(args[1:])
+		}
+	}
+	return generateReports(args)
+}
+
+// generateReports writes the reports of all configured wallets and chains
+func generateReports(args []string) error {
+	offline := flag.Bool("offline", false, "serve provider responses only from the response cache")
+	record := flag.String("record", "", "record every provider response as a fixture into this directory")
+	replay := flag.String("replay", "", "serve provider responses from the fixtures in this directory")
+	failFast := flag.Bool("fail-fast", false, "cancel all report tasks once one failed, same as FAIL_FAST")
+	timeout := flag.Duration("timeout", 0, "deadline of the whole run, e.g. 10m, overrides RUN_TIMEOUT_SECONDS")
+	verifyManifest := flag.String("verify-manifest", "", "verify the reports listed in this manifest against their checksums and exit")
+	metricsFile := flag.String("metrics-file", "", "write the metrics of the run to this file, overrides METRICS.TEXTFILE")
+	logLevel, logFormat := logFlags(flag.CommandLine)
+	profile := profileFlag(flag.CommandLine)
+	flag.CommandLine.Parse(args)
+
+	if *verifyManifest != "" {
+		manifest, err := usecase.VerifyManifest(*verifyManifest)
+		if err != nil {
+			fmt.Printf("Manifest verification failed: %v\n", err)
+			return err
+		}
+		fmt.Printf("All %d reports match the manifest.\n", len(manifest.Files))
+		return nil
+	}
 
 	// Record the start time right at the beginning
 	startTime := time.Now()
 
-	// Use defer to execute this function just before main exits
+	// Use defer to execute this function just before the run returns
 	defer func() {
-		elapsedTime := time.Since(startTime) // Calculate elapsed time
-		fmt.Printf("\n--------------------\n")
-		fmt.Printf("Total execution time: %s\n", elapsedTime) // Print the duration
-		fmt.Printf("--------------------\n")
+		slog.Info("total execution time", "duration", time.Since(startTime))
 	}()
-	// Read the config file
-	configFile, err := os.ReadFile("config.yml")
+	config, err := loadConfig(*profile)
 	if err != nil {
-		fmt.Printf("Error reading config file: %v\n", err)
-		os.Exit(1)
+		slog.Error(err.Error())
+		return err
+	}
+	if err := setupLogging(config, *logLevel, *logFormat); err != nil {
+		return err
 	}
 
-	// Define a variable of type Config
-	config := models.Config{}
+	if *offline {
+		config.Cache.Offline = true
+	}
+	if *record != "" {
+		config.Fixtures.RecordDirectory = *record
+	}
+	if *replay != "" {
+		config.Fixtures.ReplayDirectory = *replay
+	}
+	if *failFast {
+		config.FailFast = true
+	}
+	if *metricsFile != "" {
+		config.Metrics.Textfile = *metricsFile
+	}
+
+	providerType := resolveProviderType(config)
+	if err := checkConfig(config, providerType, true); err != nil {
+		return err
+	}
+
+	// Cancel all in-flight requests on Ctrl-C or SIGTERM, a second signal terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	runTimeout := time.Duration(config.RunTimeoutSeconds) * time.Second
+	if *timeout > 0 {
+		runTimeout = *timeout
+	}
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
 
-	// Unmarshal the YAML data into the config struct
-	err = yaml.Unmarshal(configFile, &config)
+	_, err = usecase.GenerateTransactionReports(ctx, providerType, config)
+	writeMetricsFile(config.Metrics.Textfile)
 	if err != nil {
-		fmt.Printf("Error unmarshalling config data: %v\n", err)
-		os.Exit(1)
+		slog.Error("generating transaction reports failed", logging.KeyError, err)
+		return err
+	}
+	slog.Info("operation completed")
+	return nil
+}
+
+// loadConfig reads config.yml with the profile, the environment overrides and the secrets
+func loadConfig(profile string) (models.Config, error) {
+	return usecase.LoadConfig(constants.CONFIG_DEFAULT_PATH, profile)
+}
+
+// profileFlag adds the flag selecting a PROFILES entry of config.yml to a command
+func profileFlag(flags *flag.FlagSet) *string {
+	return flags.String("profile", "", "config profile from PROFILES, e.g. prod or testnet, overrides TRACKER_PROFILE")
+}
+
+// checkConfig logs every problem of the config, nothing is requested with an invalid config
+func checkConfig(config models.Config, providerType string, requireWallets bool) error {
+	if err := usecase.ValidateConfig(config, providerType, requireWallets); err != nil {
+		slog.Error("invalid config", logging.KeyError, err)
+		return err
 	}
+	return nil
+}
 
-	err = usecase.GenerateTransactionReports(constants.PROVIDER_ETHERSCAN, config)
+// validateConfig checks config.yml with the profile and the environment overrides without running anything
+func validateConfig(args []string) error {
+	flags := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	profile := profileFlag(flags)
+	serveOnly := flags.Bool("serve", false, "validate for the serve command, which takes the wallets from the job requests")
+	flags.Parse(args)
+
+	config, err := loadConfig(*profile)
 	if err != nil {
-		fmt.Printf("Error generating transaction reports: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("Config could not be loaded: %v\n", err)
+		return err
+	}
+	if err := usecase.ValidateConfig(config, resolveProviderType(config), !*serveOnly); err != nil {
+		fmt.Printf("Config is invalid:\n%v\n", err)
+		return err
 	}
+	fmt.Println("Config is valid.")
+	return nil
+}
 
-	// --- Data Fetching ---
-	// Use the provider interface to fetch data
+// logFlags adds the flags overriding the LOG config to a command
+func logFlags(flags *flag.FlagSet) (level, format *string) {
+	level = flags.String("log-level", "", "debug, info, warn or error, overrides LOG.LEVEL")
+	format = flags.String("log-format", "", "text or json, overrides LOG.FORMAT")
+	return level, format
+}
 
-	// --- Output ---
-	fmt.Println("\n--- Fetched Data ---")
-	fmt.Println("--------------------")
-	fmt.Println("Operation completed.")
+// setupLogging makes the logger of the LOG config the default logger, logs are written to stderr
+func setupLogging(config models.Config, level, format string) error {
+	if level != "" {
+		config.Log.Level = level
+	}
+	if format != "" {
+		config.Log.Format = format
+	}
+	logger, err := logging.New(config.Log, os.Stderr)
+	if err != nil {
+		slog.Error("invalid LOG config", logging.KeyError, err)
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// resolveProviderType returns the configured provider, several providers are wrapped in a failover provider
+func resolveProviderType(config models.Config) string {
+	providerType := constants.PROVIDER_ETHERSCAN
+	if config.Provider != "" {
+		providerType = config.Provider
+	}
+	if len(config.Providers) > 0 {
+		providerType = constants.PROVIDER_FAILOVER
+	}
+	return providerType
+}
+
+// serve runs the HTTP API until Ctrl-C or SIGTERM
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	address := flags.String("addr", "", "listen address of the API, overrides SERVER.ADDRESS")
+	replay := flags.String("replay", "", "serve provider responses from the fixtures in this directory")
+	logLevel, logFormat := logFlags(flags)
+	profile := profileFlag(flags)
+	flags.Parse(args)
+
+	config, err := loadConfig(*profile)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if err := setupLogging(config, *logLevel, *logFormat); err != nil {
+		return err
+	}
+	if *replay != "" {
+		config.Fixtures.ReplayDirectory = *replay
+	}
+
+	listenAddress := constants.SERVER_DEFAULT_ADDRESS
+	if config.Server.Address != "" {
+		listenAddress = config.Server.Address
+	}
+	if *address != "" {
+		listenAddress = *address
+	}
+
+	providerType := resolveProviderType(config)
+	if err := checkConfig(config, providerType, false); err != nil {
+		return err
+	}
+
+	jobs, err := server.NewJobManager(providerType, config)
+	if err != nil {
+		slog.Error("starting the job manager failed", logging.KeyError, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.New(jobs).ListenAndServe(ctx, listenAddress); err != nil {
+		slog.Error("serving the API failed", logging.KeyError, err)
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+// watchWallets polls the wallets for new transfers and sends them as webhooks until Ctrl-C or SIGTERM
+func watchWallets(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", 0, "poll interval, e.g. 30s, overrides WATCH.INTERVAL_SECONDS")
+	once := flags.Bool("once", false, "poll once and exit")
+	metricsAddress := flags.String("metrics-addr", "", "serve /metrics on this address, overrides METRICS.ADDRESS")
+	metricsFile := flags.String("metrics-file", "", "write the metrics to this file after a poll with --once, overrides METRICS.TEXTFILE")
+	logLevel, logFormat := logFlags(flags)
+	profile := profileFlag(flags)
+	flags.Parse(args)
+
+	config, err := loadConfig(*profile)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if err := setupLogging(config, *logLevel, *logFormat); err != nil {
+		return err
+	}
+	if *interval > 0 {
+		config.Watch.IntervalSeconds = int(interval.Seconds())
+	}
+	if *metricsAddress != "" {
+		config.Metrics.Address = *metricsAddress
+	}
+	if *metricsFile != "" {
+		config.Metrics.Textfile = *metricsFile
+	}
+
+	providerType := resolveProviderType(config)
+	if err := checkConfig(config, providerType, true); err != nil {
+		return err
+	}
+
+	watcher, err := watch.New(providerType, config)
+	if err != nil {
+		slog.Error("starting the watcher failed", logging.KeyError, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		sent, err := watcher.Poll(ctx)
+		slog.Info("sent new transfers", "transfers", sent)
+		writeMetricsFile(config.Metrics.Textfile)
+		if err != nil {
+			slog.Error("polling wallets failed", logging.KeyError, err)
+			return err
+		}
+		return nil
+	}
+	if config.Metrics.Address != "" {
+		go serveMetrics(ctx, config.Metrics.Address)
+	}
+	if err := watcher.Run(ctx); err != nil {
+		slog.Error("watching wallets failed", logging.KeyError, err)
+		return err
+	}
+	slog.Info("watcher stopped")
+	return nil
+}
+
+// serveMetrics serves /metrics until the context is canceled
+func serveMetrics(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	metricsServer := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		metricsServer.Close()
+	}()
+
+	slog.Info("serving metrics", "address", address)
+	if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("serving metrics failed", logging.KeyError, err)
+	}
+}
+
+// writeMetricsFile writes the metrics of a one-shot run, nothing is written without a path
+func writeMetricsFile(path string) {
+	if path == "" {
+		return
+	}
+	if err := metrics.Default.WriteFile(path); err != nil {
+		slog.Error("writing metrics file failed", logging.KeyError, err)
+	}
 }
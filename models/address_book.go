@@ -0,0 +1,15 @@
+package models
+
+type (
+	// Single entry of the local address book
+	AddressBookEntry struct {
+		Address  string `yaml:"ADDRESS" csv:"Address"`
+		Label    string `yaml:"LABEL" csv:"Label"`
+		Category string `yaml:"CATEGORY" csv:"Category"` // e.g. "Exchange", "Payroll", "DeFi"
+	}
+
+	// Layout of a YAML address book file
+	AddressBookFile struct {
+		Addresses []AddressBookEntry `yaml:"ADDRESSES"`
+	}
+)
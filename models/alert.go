@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type (
+	/*
+		Alert rule, a rule matches an event when every condition that is set matches.
+		Addresses are compared case-insensitively, FROM, TO and WALLET also match the address book label.
+	*/
+	AlertRule struct {
+		Name                string `yaml:"NAME"`
+		Severity            string `yaml:"SEVERITY"`             // Free text, e.g. info, warning or critical
+		Event               string `yaml:"EVENT"`                // transfer or approval, empty for both
+		TransactionType     string `yaml:"TRANSACTION_TYPE"`     // e.g. "ERC-20 Transfer"
+		Direction           string `yaml:"DIRECTION"`            // in, out or self, seen from the wallet
+		Status              string `yaml:"STATUS"`               // success or failed for transfers, active or revoked for approvals
+		Wallet              string `yaml:"WALLET"`               // Address or label of the wallet
+		From                string `yaml:"FROM"`                 // Address or label of the sender
+		To                  string `yaml:"TO"`                   // Address or label of the receiver
+		Asset               string `yaml:"ASSET"`                // Token symbol, e.g. ETH or USDC
+		CounterpartyLabeled *bool  `yaml:"COUNTERPARTY_LABELED"` // false matches counterparties (or spenders) missing in the address book
+		AmountAbove         string `yaml:"AMOUNT_ABOVE"`         // Transfer amount in whole units, e.g. "10" for 10 ETH
+		GasFeeAbove         string `yaml:"GAS_FEE_ABOVE"`        // Gas fee in the native currency
+	}
+
+	// Layout of an alert rules file
+	AlertRulesFile struct {
+		Rules []AlertRule `yaml:"RULES"`
+	}
+
+	// Wallet activity the alert rules are evaluated against, either a transfer or an approval
+	AlertEvent struct {
+		Type        string                  `json:"type"` // transfer or approval
+		Wallet      string                  `json:"wallet"`
+		WalletLabel string                  `json:"walletLabel,omitempty"`
+		Transfer    *WalletActivity         `json:"transfer,omitempty"`
+		Approval    *ApprovalReportResponse `json:"approval,omitempty"`
+	}
+
+	// Event that matched an alert rule
+	Alert struct {
+		Rule        string    `json:"rule"`
+		Severity    string    `json:"severity,omitempty"`
+		Message     string    `json:"message"`
+		TriggeredAt time.Time `json:"triggeredAt"`
+		AlertEvent
+	}
+)
@@ -0,0 +1,10 @@
+package models
+
+// Chain describes an EVM chain supported by the explorer APIs
+type Chain struct {
+	Name            string `json:"name"`
+	ChainID         int64  `json:"chainId"`
+	NativeSymbol    string `json:"nativeSymbol"`
+	ExplorerBaseURL string `json:"explorerBaseUrl"`
+	Decimals        int    `json:"decimals"` // Decimals of the native currency
+}
@@ -2,13 +2,121 @@ package models
 
 type (
 	ThirdPartyApiConfig struct {
-		BaseURL string `yaml:"BASE_URL"`
-		ApiKey  string `yaml:"API_KEY"`
-		Retries int    `yaml:"RETRIES"`
+		BaseURL    string            `yaml:"BASE_URL"`
+		BaseURLs   map[string]string `yaml:"BASE_URLS"` // Optional base URL per chain name, overrides BASE_URL
+		ApiKey     string            `yaml:"API_KEY"`
+		ApiKeyEnv  string            `yaml:"API_KEY_ENV"`  // Environment variable holding the API key, overrides API_KEY
+		ApiKeyFile string            `yaml:"API_KEY_FILE"` // File holding the API key, overrides API_KEY_ENV and API_KEY
+		Retries    int               `yaml:"RETRIES"`
+	}
+	FailoverConfig struct {
+		FailureThreshold int `yaml:"FAILURE_THRESHOLD"` // Consecutive failures before a provider is skipped
+		CooldownSeconds  int `yaml:"COOLDOWN_SECONDS"`  // How long a failing provider is skipped before it is tried again
+	}
+	WalletConfig struct {
+		Address string `yaml:"ADDRESS"`
+		Label   string `yaml:"LABEL"` // Optional, used to label the wallet in reports
+	}
+	AbiConfig struct {
+		Directory      string `yaml:"DIRECTORY"`       // Local directory with <address>.json ABI files
+		CacheDirectory string `yaml:"CACHE_DIRECTORY"` // Where ABIs fetched from the provider are stored
+		FetchRemote    bool   `yaml:"FETCH_REMOTE"`    // Fetch unknown ABIs via the provider (Etherscan contract/getabi)
+		DetailedReport bool   `yaml:"DETAILED_REPORT"` // Write the external report with decoded calldata
+	}
+	RpcConfig struct {
+		Endpoints     map[string]string `yaml:"ENDPOINTS"`       // JSON-RPC URL per chain name, ${VAR} is read from the environment
+		StartBlock    uint64            `yaml:"START_BLOCK"`     // First block to scan
+		EndBlock      uint64            `yaml:"END_BLOCK"`       // Last block to scan, 0 for the latest block
+		LogBlockRange uint64            `yaml:"LOG_BLOCK_RANGE"` // Max block range per eth_getLogs call
+	}
+	CacheConfig struct {
+		Directory     string `yaml:"DIRECTORY"`      // Response cache directory, caching is disabled when empty
+		TTLSeconds    int    `yaml:"TTL_SECONDS"`    // Expiry of cached responses, 0 never expires
+		FinalityDepth uint64 `yaml:"FINALITY_DEPTH"` // Blocks behind the head after which a block range is final
+		Offline       bool   `yaml:"OFFLINE"`        // Serve only from cache, set with --offline
+	}
+	FixtureConfig struct {
+		RecordDirectory string `yaml:"RECORD_DIRECTORY"` // Save every provider response as a fixture, set with --record
+		ReplayDirectory string `yaml:"REPLAY_DIRECTORY"` // Serve provider responses from fixtures, set with --replay
+	}
+	OutputConfig struct {
+		Directory        string `yaml:"DIRECTORY"`         // Reports directory, relative to the working directory, defaults to files/reports
+		FilenameTemplate string `yaml:"FILENAME_TEMPLATE"` // e.g. "{label}_{chain}_{type}_{range}.csv"
+		RunSubdirectory  bool   `yaml:"RUN_SUBDIRECTORY"`  // Write every run into its own subdirectory named after the run timestamp
+	}
+	ServerConfig struct {
+		Address       string `yaml:"ADDRESS"`        // Listen address of the serve command, defaults to :8080
+		Workers       int    `yaml:"WORKERS"`        // Report jobs run concurrently
+		QueueSize     int    `yaml:"QUEUE_SIZE"`     // Jobs waiting for a worker, further jobs are rejected
+		JobsDirectory string `yaml:"JOBS_DIRECTORY"` // Every job writes its reports into a subdirectory
+	}
+	WebhookConfig struct {
+		URL            string `yaml:"URL"`
+		Secret         string `yaml:"SECRET"`          // Signs the payloads with HMAC-SHA256
+		SecretEnv      string `yaml:"SECRET_ENV"`      // Environment variable holding the secret, overrides SECRET
+		MaxRetries     int    `yaml:"MAX_RETRIES"`     // Retries of a failed delivery, defaults to 5, -1 for none
+		BackoffSeconds int    `yaml:"BACKOFF_SECONDS"` // Delay before the first retry, doubled for every further retry
+		TimeoutSeconds int    `yaml:"TIMEOUT_SECONDS"` // Timeout of a single delivery attempt
+	}
+	WatchConfig struct {
+		IntervalSeconds int           `yaml:"INTERVAL_SECONDS"` // Poll interval, defaults to 30
+		StateFile       string        `yaml:"STATE_FILE"`       // Last polled block per wallet, chain and transfer type
+		DeadLetterFile  string        `yaml:"DEAD_LETTER_FILE"` // Undeliverable webhooks, one JSON object per line
+		Webhook         WebhookConfig `yaml:"WEBHOOK"`
+	}
+	SmtpConfig struct {
+		Address string   `yaml:"ADDRESS"` // Local relay without authentication, e.g. "localhost:25"
+		From    string   `yaml:"FROM"`
+		To      []string `yaml:"TO"`
+	}
+	AlertConfig struct {
+		Rules          []AlertRule   `yaml:"RULES"`
+		RulesFile      string        `yaml:"RULES_FILE"`       // YAML file with further RULES
+		Stdout         bool          `yaml:"STDOUT"`           // Print alerts, the default when no other destination is set
+		File           string        `yaml:"FILE"`             // Alerts are appended as JSON, one per line
+		Webhook        WebhookConfig `yaml:"WEBHOOK"`          // POST every alert as signed JSON
+		DeadLetterFile string        `yaml:"DEAD_LETTER_FILE"` // Alerts the webhook did not accept
+		Smtp           SmtpConfig    `yaml:"SMTP"`             // Mail every alert
+	}
+	LogConfig struct {
+		Level  string `yaml:"LEVEL"`  // debug, info, warn or error, defaults to info
+		Format string `yaml:"FORMAT"` // text or json, defaults to text
+	}
+	MetricsConfig struct {
+		Address  string `yaml:"ADDRESS"`  // Listen address of /metrics in watch mode, e.g. ":9464", serve uses its own address
+		Textfile string `yaml:"TEXTFILE"` // Metrics of one-shot runs are written here, e.g. for the node exporter textfile collector
+	}
+	EnsConfig struct {
+		Resolver     string `yaml:"RESOLVER"`      // rpc or file, ENS names in WALLET_ADDRESS and WALLETS need a resolver
+		File         string `yaml:"FILE"`          // YAML file mapping ENS names to addresses, read by the file resolver
+		Chain        string `yaml:"CHAIN"`         // Chain whose RPC endpoint resolves names, defaults to ethereum
+		ReverseNames bool   `yaml:"REVERSE_NAMES"` // Add the From/To ENS Name columns to the transfer reports
 	}
 	Config struct {
-		Etherscan     ThirdPartyApiConfig `yaml:"ETHERSCAN"`
-		Blockscout    ThirdPartyApiConfig `yaml:"BLOCKSCOUT"`
-		WalletAddress string              `yaml:"WALLET_ADDRESS"`
+		Etherscan         ThirdPartyApiConfig `yaml:"ETHERSCAN"`
+		Blockscout        ThirdPartyApiConfig `yaml:"BLOCKSCOUT"`
+		Rpc               RpcConfig           `yaml:"RPC"`
+		Provider          string              `yaml:"PROVIDER"`  // etherscan, blockscout or rpc, defaults to etherscan
+		Providers         []string            `yaml:"PROVIDERS"` // Ordered providers to fail over between, overrides PROVIDER
+		Failover          FailoverConfig      `yaml:"FAILOVER"`
+		Cache             CacheConfig         `yaml:"CACHE"`
+		Fixtures          FixtureConfig       `yaml:"FIXTURES"`
+		Output            OutputConfig        `yaml:"OUTPUT"`
+		Server            ServerConfig        `yaml:"SERVER"`
+		Watch             WatchConfig         `yaml:"WATCH"`
+		Alerts            AlertConfig         `yaml:"ALERTS"`
+		Metrics           MetricsConfig       `yaml:"METRICS"`
+		Log               LogConfig           `yaml:"LOG"`
+		RunTimeoutSeconds int                 `yaml:"RUN_TIMEOUT_SECONDS"` // Deadline of the whole run, 0 for none
+		FailFast          bool                `yaml:"FAIL_FAST"`           // Cancel all report tasks once one failed
+		StartBlock        uint64              `yaml:"START_BLOCK"`         // First block requested from explorer APIs
+		EndBlock          uint64              `yaml:"END_BLOCK"`           // Last block requested from explorer APIs, 0 for the latest block
+		WalletAddress     string              `yaml:"WALLET_ADDRESS"`
+		Wallets           []WalletConfig      `yaml:"WALLETS"`
+		AddressBookPath   string              `yaml:"ADDRESS_BOOK"` // Optional YAML or CSV file mapping addresses to labels
+		Ens               EnsConfig           `yaml:"ENS"`
+		Abi               AbiConfig           `yaml:"ABI"`
+		Chains            []string            `yaml:"CHAINS"`       // Chain names from the chain registry, defaults to ethereum
+		ReportTypes       []string            `yaml:"REPORT_TYPES"` // Report types to generate, defaults to all
 	}
 )
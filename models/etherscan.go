@@ -101,3 +101,28 @@ type (
 		// TokenType         string `json:"tokenType"` // e.g., "ERC-721", "ERC-1155"
 	}
 )
+
+type (
+	// Structure for a single event log result of the logs/getLogs endpoint.
+	// Unlike the account endpoints, numbers are hex encoded.
+	EventLog struct {
+		Address          string   `json:"address"`
+		Topics           []string `json:"topics"`
+		Data             string   `json:"data"`
+		BlockNumber      string   `json:"blockNumber"`
+		BlockHash        string   `json:"blockHash"`
+		TimeStamp        string   `json:"timeStamp"`
+		GasPrice         string   `json:"gasPrice"`
+		GasUsed          string   `json:"gasUsed"`
+		LogIndex         string   `json:"logIndex"`
+		TransactionHash  string   `json:"transactionHash"`
+		TransactionIndex string   `json:"transactionIndex"`
+	}
+)
+
+// Recorded provider response, used to replay provider calls in tests and offline runs
+type ProviderFixture struct {
+	Key      string `json:"key"` // Provider independent request, e.g. "fixture:?action=txlist&address=0x.."
+	Tag      string `json:"tag"`
+	Response string `json:"response"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type (
+	// Report job submitted to the HTTP API
+	ReportJobRequest struct {
+		Wallet      string   `json:"wallet"`
+		Chains      []string `json:"chains,omitempty"`      // Defaults to the chains of the config
+		ReportTypes []string `json:"reportTypes,omitempty"` // Defaults to the report types of the config
+		StartBlock  uint64   `json:"startBlock,omitempty"`
+		EndBlock    uint64   `json:"endBlock,omitempty"` // 0 for the latest block
+		Format      string   `json:"format,omitempty"`   // Download format, csv (default) or json
+	}
+
+	ReportJob struct {
+		ID          string           `json:"id"`
+		Status      string           `json:"status"` // queued, running, succeeded, failed or canceled
+		Request     ReportJobRequest `json:"request"`
+		SubmittedAt time.Time        `json:"submittedAt"`
+		StartedAt   time.Time        `json:"startedAt,omitempty"`
+		FinishedAt  time.Time        `json:"finishedAt,omitempty"`
+		Error       string           `json:"error,omitempty"`
+		Files       []ManifestEntry  `json:"files,omitempty"` // Reports of the job, paths relative to the job directory
+		Run         *RunResult       `json:"run,omitempty"`
+	}
+)
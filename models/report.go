@@ -1,14 +1,72 @@
 package models
 
 type ReportResponse struct {
+	Chain                string `json:"chain" csv:"Chain"`
 	TransactionHash      string `json:"transactionHash" csv:"Transaction Hash"`
 	DateTime             string `json:"dateTime" csv:"Date Time"`
 	FromAddress          string `json:"fromAddress" csv:"From Address"`
+	FromLabel            string `json:"fromLabel" csv:"From Label"`
 	ToAddress            string `json:"toAddress" csv:"To Address"`
+	ToLabel              string `json:"toLabel" csv:"To Label"`
 	TransactionType      string `json:"transactionType" csv:"Transaction Type"`
 	AssetContractAddress string `json:"assetContractAddress" csv:"Asset Contract Address"`
 	AssetSymbolName      string `json:"assetSymbolName" csv:"Asset Symbol Name"`
 	TokenID              string `json:"tokenID" csv:"Token ID"`
 	ValueAmount          string `json:"valueAmount" csv:"Value Amount"`
-	GasFeeEth            string `json:"gasFeeEth" csv:"Gas Fee (ETH)"`
+	GasFeeNative         string `json:"gasFeeNative" csv:"Gas Fee (Native)"`                // In the native currency of the chain
+	FromENSName          string `json:"fromEnsName,omitempty" csv:"From ENS Name,optional"` // Only written with ENS.REVERSE_NAMES
+	ToENSName            string `json:"toEnsName,omitempty" csv:"To ENS Name,optional"`
+}
+
+// External transaction with the calldata decoded through the contract ABI
+type DetailedReportResponse struct {
+	Chain            string `json:"chain" csv:"Chain"`
+	TransactionHash  string `json:"transactionHash" csv:"Transaction Hash"`
+	DateTime         string `json:"dateTime" csv:"Date Time"`
+	FromAddress      string `json:"fromAddress" csv:"From Address"`
+	FromLabel        string `json:"fromLabel" csv:"From Label"`
+	ToAddress        string `json:"toAddress" csv:"To Address"`
+	ToLabel          string `json:"toLabel" csv:"To Label"`
+	ValueAmount      string `json:"valueAmount" csv:"Value Amount"`
+	MethodID         string `json:"methodId" csv:"Method ID"`
+	MethodName       string `json:"methodName" csv:"Method Name"`
+	MethodSignature  string `json:"methodSignature" csv:"Method Signature"`
+	DecodedArguments string `json:"decodedArguments" csv:"Decoded Arguments"`
+	DecodeError      string `json:"decodeError" csv:"Decode Error"`
+	IsError          string `json:"isError" csv:"Is Error"`
+	ExplorerFunction string `json:"explorerFunction" csv:"Explorer Function Name"`
+}
+
+// Event log emitted by a contract that involves the wallet, decoded through the known ABIs
+type EventLogReportResponse struct {
+	Chain            string `json:"chain" csv:"Chain"`
+	TransactionHash  string `json:"transactionHash" csv:"Transaction Hash"`
+	DateTime         string `json:"dateTime" csv:"Date Time"`
+	BlockNumber      string `json:"blockNumber" csv:"Block Number"`
+	LogIndex         string `json:"logIndex" csv:"Log Index"`
+	ContractAddress  string `json:"contractAddress" csv:"Contract Address"`
+	ContractLabel    string `json:"contractLabel" csv:"Contract Label"`
+	EventName        string `json:"eventName" csv:"Event Name"`
+	EventSignature   string `json:"eventSignature" csv:"Event Signature"`
+	DecodedArguments string `json:"decodedArguments" csv:"Decoded Arguments"`
+	Topic0           string `json:"topic0" csv:"Topic 0"`
+	DecodeError      string `json:"decodeError" csv:"Decode Error"`
+}
+
+// Latest approval granted by the wallet to a spender for a token
+type ApprovalReportResponse struct {
+	Chain           string `json:"chain" csv:"Chain"`
+	TokenAddress    string `json:"tokenAddress" csv:"Token Address"`
+	TokenLabel      string `json:"tokenLabel" csv:"Token Label"`
+	TokenStandard   string `json:"tokenStandard" csv:"Token Standard"`
+	SpenderAddress  string `json:"spenderAddress" csv:"Spender Address"`
+	SpenderLabel    string `json:"spenderLabel" csv:"Spender Label"`
+	TokenID         string `json:"tokenID" csv:"Token ID"`
+	Allowance       string `json:"allowance" csv:"Allowance"`
+	Unlimited       bool   `json:"unlimited" csv:"Unlimited"`
+	Status          string `json:"status" csv:"Status"`
+	GrantedAt       string `json:"grantedAt" csv:"Granted At"`
+	BlockNumber     string `json:"blockNumber" csv:"Block Number"`
+	TransactionHash string `json:"transactionHash" csv:"Transaction Hash"`
+	Source          string `json:"source" csv:"Source"`
 }
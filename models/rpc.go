@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+type (
+	// Ethereum JSON-RPC envelope, see https://ethereum.org/en/developers/docs/apis/json-rpc/
+	RpcRequest struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      int64  `json:"id"`
+		Method  string `json:"method"`
+		Params  []any  `json:"params"`
+	}
+
+	RpcResponse struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      int64           `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *RpcError       `json:"error"`
+	}
+
+	RpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// All numbers returned by the node are hex encoded quantities
+	RpcBlock struct {
+		Number       string          `json:"number"`
+		Hash         string          `json:"hash"`
+		Timestamp    string          `json:"timestamp"`
+		Transactions json.RawMessage `json:"transactions"` // Hashes or full objects depending on the request
+	}
+
+	RpcTransaction struct {
+		Hash             string `json:"hash"`
+		Nonce            string `json:"nonce"`
+		BlockHash        string `json:"blockHash"`
+		BlockNumber      string `json:"blockNumber"`
+		TransactionIndex string `json:"transactionIndex"`
+		From             string `json:"from"`
+		To               string `json:"to"` // Empty for contract creations
+		Value            string `json:"value"`
+		Gas              string `json:"gas"`
+		GasPrice         string `json:"gasPrice"`
+		Input            string `json:"input"`
+	}
+
+	RpcReceipt struct {
+		TransactionHash   string `json:"transactionHash"`
+		GasUsed           string `json:"gasUsed"`
+		EffectiveGasPrice string `json:"effectiveGasPrice"`
+		CumulativeGasUsed string `json:"cumulativeGasUsed"`
+		ContractAddress   string `json:"contractAddress"`
+		Status            string `json:"status"` // 0x1 success, 0x0 failure
+	}
+
+	RpcLog struct {
+		Address          string   `json:"address"`
+		Topics           []string `json:"topics"`
+		Data             string   `json:"data"`
+		BlockNumber      string   `json:"blockNumber"`
+		BlockHash        string   `json:"blockHash"`
+		TransactionHash  string   `json:"transactionHash"`
+		TransactionIndex string   `json:"transactionIndex"`
+		LogIndex         string   `json:"logIndex"`
+		Removed          bool     `json:"removed"`
+	}
+
+	// Parity/Erigon style trace as returned by trace_filter
+	RpcTrace struct {
+		Action struct {
+			CallType string `json:"callType"`
+			From     string `json:"from"`
+			To       string `json:"to"`
+			Value    string `json:"value"`
+			Gas      string `json:"gas"`
+			Input    string `json:"input"`
+			Init     string `json:"init"` // Set for create traces
+		} `json:"action"`
+		Result *struct {
+			GasUsed string `json:"gasUsed"`
+			Address string `json:"address"` // Set for create traces
+		} `json:"result"`
+		BlockNumber         int64  `json:"blockNumber"`
+		BlockHash           string `json:"blockHash"`
+		TransactionHash     string `json:"transactionHash"`
+		TransactionPosition int64  `json:"transactionPosition"`
+		TraceAddress        []int  `json:"traceAddress"`
+		Type                string `json:"type"` // "call", "create", "suicide", "reward"
+		Error               string `json:"error"`
+	}
+)
+
+func (e *RpcError) Error() string {
+	return e.Message
+}
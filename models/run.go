@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+type (
+	// Outcome of a single report task, one report type for one wallet on one chain
+	ReportTaskResult struct {
+		Chain          string    `json:"chain"`
+		Wallet         string    `json:"wallet"`
+		ReportType     string    `json:"reportType"`
+		Status         string    `json:"status"` // succeeded, failed or canceled
+		StartedAt      time.Time `json:"startedAt"`
+		DurationMillis int64     `json:"durationMs"`
+		Rows           int       `json:"rows"`
+		ServedBy       string    `json:"servedBy,omitempty"` // Providers that served the requests, comma separated
+		Error          string    `json:"error,omitempty"`
+	}
+
+	// Outcome of a whole run, written as run_summary.json next to the reports
+	RunResult struct {
+		RunID          string             `json:"runId"`  // run_id of the log lines of the run
+		Status         string             `json:"status"` // succeeded, failed or canceled
+		StartedAt      time.Time          `json:"startedAt"`
+		FinishedAt     time.Time          `json:"finishedAt"`
+		DurationMillis int64              `json:"durationMs"`
+		FailFast       bool               `json:"failFast"`
+		Succeeded      int                `json:"succeeded"`
+		Failed         int                `json:"failed"`
+		Canceled       int                `json:"canceled"`
+		Tasks          []ReportTaskResult `json:"tasks"`
+	}
+)
+
+type (
+	// Output file of a run with the data needed to verify it was not altered
+	ManifestEntry struct {
+		File        string    `json:"file"` // Relative to the manifest
+		ReportType  string    `json:"reportType"`
+		Chain       string    `json:"chain"`
+		Wallet      string    `json:"wallet"`
+		Rows        int       `json:"rows"`
+		SHA256      string    `json:"sha256"`
+		ServedBy    string    `json:"servedBy,omitempty"` // Providers that served the requests, comma separated
+		StartBlock  uint64    `json:"startBlock"`
+		EndBlock    uint64    `json:"endBlock"` // Head of the chain when the data was requested, 0 when unknown (e.g. replayed runs)
+		GeneratedAt time.Time `json:"generatedAt"`
+	}
+
+	// Written as manifest.json next to the reports, lists every report written by the run
+	Manifest struct {
+		GeneratedAt time.Time       `json:"generatedAt"`
+		Provider    string          `json:"provider"`
+		StartBlock  uint64          `json:"startBlock"` // Requested block range, see the entries for the range each report covers
+		EndBlock    uint64          `json:"endBlock"`   // 0 for the latest block at generation time
+		Files       []ManifestEntry `json:"files"`
+	}
+)
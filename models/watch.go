@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Transfer of a watched wallet detected by the watch command, sent as webhook payload
+	WalletActivity struct {
+		ID                   string    `json:"id"` // Stable across retries and restarts, receivers drop duplicates by it
+		Chain                string    `json:"chain"`
+		Wallet               string    `json:"wallet"`
+		WalletLabel          string    `json:"walletLabel,omitempty"`
+		TransactionType      string    `json:"transactionType"` // <native symbol> Transfer (e.g. ETH Transfer), Internal, ERC-20 Transfer or ERC-721 Transfer
+		Direction            string    `json:"direction"`       // in, out or self
+		BlockNumber          uint64    `json:"blockNumber"`
+		DateTime             string    `json:"dateTime"`
+		TransactionHash      string    `json:"transactionHash"`
+		FromAddress          string    `json:"fromAddress"`
+		FromLabel            string    `json:"fromLabel,omitempty"`
+		ToAddress            string    `json:"toAddress"`
+		ToLabel              string    `json:"toLabel,omitempty"`
+		AssetContractAddress string    `json:"assetContractAddress,omitempty"` // Empty for the native currency
+		AssetSymbol          string    `json:"assetSymbol"`
+		TokenID              string    `json:"tokenId,omitempty"`
+		Value                string    `json:"value,omitempty"`  // Amount in the smallest unit of the asset
+		Amount               string    `json:"amount,omitempty"` // Value formatted with the decimals of the asset
+		Status               string    `json:"status"`           // success or failed
+		GasFee               string    `json:"gasFee,omitempty"` // In the native currency, paid by the sender
+		DetectedAt           time.Time `json:"detectedAt"`
+	}
+
+	// Webhook payload that could not be delivered, appended to the dead-letter file
+	DeadLetter struct {
+		URL      string          `json:"url"`
+		Payload  json.RawMessage `json:"payload"`
+		Attempts int             `json:"attempts"`
+		Error    string          `json:"error"`
+		FailedAt time.Time       `json:"failedAt"`
+	}
+
+	// Position of the watch command for one wallet, chain and transfer type
+	WatchCursor struct {
+		Block uint64   `json:"block"` // Highest block seen, it is polled again in case the explorer indexed it partially
+		Seen  []string `json:"seen"`  // IDs of the activities of that block that were already sent
+	}
+
+	// State of the watch command, kept across restarts
+	WatchState struct {
+		Cursors map[string]WatchCursor `json:"cursors"` // Keyed by chain/wallet/report type
+	}
+)
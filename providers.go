@@ -1,65 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"net/http"
-)
-
-type BlockscoutProvider struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
-}
-
-// NewBlockscoutProvider creates a new Blockscout provider instance.
-func NewBlockscoutProvider(apiKey, baseURL string, client *http.Client) *BlockscoutProvider {
-	if baseURL == "" {
-		// Blockscout instances have different URLs, MUST be provided in config
-		return nil // Or handle error: Base URL is required for Blockscout
-	}
-	return &BlockscoutProvider{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		client:  client,
-	}
-}
-
-// FetchTransactionData implements the BlockchainDataProvider interface for Blockscout.
-func (p *BlockscoutProvider) FetchTransactionData(txHash string) (string, error) {
-	// Construct the specific API URL for Blockscout (might be Etherscan compatible)
-	// Example: module=transaction, action=gettxinfo
-	// url := fmt.Sprintf("%s?module=transaction&action=gettxinfo&txhash=%s", p.baseURL, txHash)
-	// Note: Blockscout API key usage varies depending on instance setup
-
-	// --- Placeholder Implementation ---
-	fmt.Printf("BlockscoutProvider: Fetching tx %s from %s\n", txHash, p.baseURL)
-	// TODO: Implement actual HTTP GET request using p.client and the constructed URL
-	// Handle response, check for errors, parse JSON etc.
-
-	// Returning mock data for now
-	mockData := fmt.Sprintf(`{"blockscout_tx": "%s", "status": "mock_success"}`, txHash)
-	return mockData, nil
-}
-
-// --- Factory ---
-
-// ErrUnknownProvider is returned when the factory is asked to create an unknown provider type.
-var ErrUnknownProvider = fmt.Errorf("unknown data provider requested")
-
-// NewDataProvider acts as a factory to create specific BlockchainDataProvider instances.
-
-// --- Configuration Struct Slice ---
-// We define a simplified version of the necessary config parts here
-// The actual Config struct in main.go will need to be updated to include this.
-
-type ProviderConfig struct {
-	Key     string `yaml:"key"`
-	BaseURL string `yaml:"baseURL"` // Optional for Etherscan, Required for Blockscout
-}
-
-type APIConfig struct {
-	Etherscan  ProviderConfig `yaml:"etherscan"`
-	Blockscout ProviderConfig `yaml:"blockscout"`
-	// Add other general API settings if needed (like Retries from original config)
-	// Retries    int            `yaml:"retries"`
-}
@@ -0,0 +1,357 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	"github.com/coin-tracker/transaction-tracker/usecase"
+)
+
+var (
+	// ErrQueueFull is returned when every worker is busy and the job queue is full.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrJobNotFound is returned for unknown job IDs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when canceling a job that already finished.
+	ErrJobFinished = errors.New("job already finished")
+)
+
+/*
+JobManager runs report jobs on a fixed number of workers. Jobs wait in a bounded queue, a job
+submitted while the queue is full is rejected. Every job writes its reports, run summary and
+manifest into its own directory, the job itself is saved as job.json so past jobs are listed
+after a restart.
+*/
+type JobManager struct {
+	config       models.Config
+	providerType string
+	directory    string
+
+	queue chan string
+
+	mu      sync.Mutex
+	jobs    map[string]*models.ReportJob
+	cancels map[string]context.CancelCauseFunc // Jobs that are queued or running
+}
+
+// NewJobManager loads the jobs of earlier runs from the jobs directory.
+func NewJobManager(providerType string, config models.Config) (*JobManager, error) {
+	directory := config.Server.JobsDirectory
+	if directory == "" {
+		directory = constants.SERVER_DEFAULT_JOBS_DIRECTORY
+	}
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory %s: %w", directory, err)
+	}
+
+	queueSize := config.Server.QueueSize
+	if queueSize <= 0 {
+		queueSize = constants.SERVER_DEFAULT_QUEUE_SIZE
+	}
+
+	m := &JobManager{
+		config:       config,
+		providerType: providerType,
+		directory:    directory,
+		queue:        make(chan string, queueSize),
+		jobs:         map[string]*models.ReportJob{},
+		cancels:      map[string]context.CancelCauseFunc{},
+	}
+	if err := m.loadJobs(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+/*
+Run starts the workers and blocks until the context is canceled, running jobs are canceled
+with it and the workers have stopped when Run returns.
+*/
+func (m *JobManager) Run(ctx context.Context) {
+	workers := m.config.Server.Workers
+	if workers <= 0 {
+		workers = constants.SERVER_DEFAULT_WORKERS
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					m.runJob(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Submit validates the request and queues the job.
+func (m *JobManager) Submit(request models.ReportJobRequest) (models.ReportJob, error) {
+	request, err := m.validate(request)
+	if err != nil {
+		return models.ReportJob{}, err
+	}
+
+	job := &models.ReportJob{
+		ID:          newJobID(),
+		Status:      constants.JOB_STATUS_QUEUED,
+		Request:     request,
+		SubmittedAt: time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case m.queue <- job.ID:
+	default:
+		return models.ReportJob{}, ErrQueueFull
+	}
+	m.jobs[job.ID] = job
+	m.cancels[job.ID] = func(error) {} // Replaced by the cancel of the job context once it runs
+	return *job, nil
+}
+
+// Get returns a snapshot of the job.
+func (m *JobManager) Get(id string) (models.ReportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return models.ReportJob{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// List returns all jobs, the latest first.
+func (m *JobManager) List() []models.ReportJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]models.ReportJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].SubmittedAt.Equal(jobs[j].SubmittedAt) {
+			return jobs[i].ID > jobs[j].ID
+		}
+		return jobs[i].SubmittedAt.After(jobs[j].SubmittedAt)
+	})
+	return jobs
+}
+
+// Cancel cancels a queued or running job, a queued job never starts.
+func (m *JobManager) Cancel(id string) (models.ReportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return models.ReportJob{}, ErrJobNotFound
+	}
+	cancel, ok := m.cancels[id]
+	if !ok {
+		return *job, ErrJobFinished
+	}
+
+	if job.Status == constants.JOB_STATUS_QUEUED {
+		job.Status = constants.RUN_STATUS_CANCELED
+		job.FinishedAt = time.Now().UTC()
+		delete(m.cancels, id)
+		m.saveJob(job)
+	}
+	cancel(fmt.Errorf("job %s canceled", id))
+	return *job, nil
+}
+
+// FilePath returns the path of a report of the job, only files listed in the job's manifest are served.
+func (m *JobManager) FilePath(id, name string) (string, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range job.Files {
+		if file.File == name {
+			return filepath.Join(m.directory, id, filepath.FromSlash(file.File)), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no file %s in job %s", ErrJobNotFound, name, id)
+}
+
+func (m *JobManager) runJob(ctx context.Context, id string) {
+	jobCtx, cancel := context.WithCancelCause(logging.With(ctx, "job_id", id))
+	defer cancel(nil)
+
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	if !ok || job.Status != constants.JOB_STATUS_QUEUED {
+		// Canceled while it was queued
+		m.mu.Unlock()
+		return
+	}
+	job.Status = constants.JOB_STATUS_RUNNING
+	job.StartedAt = time.Now().UTC()
+	m.cancels[id] = cancel
+	request := job.Request
+	m.saveJob(job)
+	m.mu.Unlock()
+
+	if m.config.RunTimeoutSeconds > 0 {
+		var cancelTimeout context.CancelFunc
+		jobCtx, cancelTimeout = context.WithTimeout(jobCtx, time.Duration(m.config.RunTimeoutSeconds)*time.Second)
+		defer cancelTimeout()
+	}
+
+	jobDirectory := filepath.Join(m.directory, id)
+	result, err := usecase.GenerateTransactionReports(jobCtx, m.providerType, m.jobConfig(request, jobDirectory))
+	manifest, manifestErr := usecase.VerifyManifest(filepath.Join(jobDirectory, constants.MANIFEST_FILE))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cancels, id)
+	job.FinishedAt = time.Now().UTC()
+	job.Run = &result
+	if manifestErr == nil {
+		job.Files = manifest.Files
+	}
+	switch {
+	case err == nil:
+		job.Status = constants.RUN_STATUS_SUCCEEDED
+	case jobCtx.Err() != nil:
+		job.Status = constants.RUN_STATUS_CANCELED
+		job.Error = context.Cause(jobCtx).Error()
+	default:
+		job.Status = constants.RUN_STATUS_FAILED
+		job.Error = err.Error()
+	}
+	m.saveJob(job)
+}
+
+// jobConfig is the config of the run of a job, reports are written into the job directory
+func (m *JobManager) jobConfig(request models.ReportJobRequest, directory string) models.Config {
+	config := m.config
+	config.WalletAddress = request.Wallet
+	config.Wallets = nil
+	for _, wallet := range m.config.Wallets {
+		// Keep the label of a configured wallet
+		if strings.EqualFold(wallet.Address, request.Wallet) {
+			config.Wallets = []models.WalletConfig{wallet}
+		}
+	}
+	if len(request.Chains) > 0 {
+		config.Chains = request.Chains
+	}
+	if len(request.ReportTypes) > 0 {
+		config.ReportTypes = request.ReportTypes
+	}
+	config.StartBlock, config.EndBlock = request.StartBlock, request.EndBlock
+	config.Rpc.StartBlock, config.Rpc.EndBlock = request.StartBlock, request.EndBlock
+	config.Output.Directory = directory
+	config.Output.RunSubdirectory = false
+	return config
+}
+
+func (m *JobManager) validate(request models.ReportJobRequest) (models.ReportJobRequest, error) {
+	request.Wallet = strings.TrimSpace(request.Wallet)
+	// ENS names are resolved by the job, which needs a resolver
+	isName := m.config.Ens.Resolver != "" && util.IsENSName(request.Wallet)
+	if !isName && !util.IsChecksumAddress(request.Wallet) {
+		return request, fmt.Errorf("invalid wallet address '%s'", request.Wallet)
+	}
+	request.Wallet = util.ChecksumAddress(request.Wallet)
+	if _, err := chains.Resolve(request.Chains); err != nil {
+		return request, err
+	}
+	if _, err := usecase.ResolveReportTypes(request.ReportTypes); err != nil {
+		return request, err
+	}
+	if request.EndBlock != 0 && request.EndBlock < request.StartBlock {
+		return request, fmt.Errorf("end block %d is before start block %d", request.EndBlock, request.StartBlock)
+	}
+
+	request.Format = strings.ToLower(request.Format)
+	if request.Format == "" {
+		request.Format = constants.REPORT_FORMAT_CSV
+	}
+	if request.Format != constants.REPORT_FORMAT_CSV && request.Format != constants.REPORT_FORMAT_JSON {
+		return request, fmt.Errorf("unknown format '%s', supported formats are csv and json", request.Format)
+	}
+	return request, nil
+}
+
+// saveJob writes the job next to its reports, the caller holds the lock
+func (m *JobManager) saveJob(job *models.ReportJob) {
+	dir := filepath.Join(m.directory, job.ID)
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(dir, os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, constants.JOB_FILE), data, 0o644)
+	}
+	if err != nil {
+		slog.Error("saving job failed", "job_id", job.ID, logging.KeyError, err)
+	}
+}
+
+// loadJobs lists the jobs of earlier runs, jobs that did not finish were interrupted by a shutdown
+func (m *JobManager) loadJobs() error {
+	entries, err := os.ReadDir(m.directory)
+	if err != nil {
+		return fmt.Errorf("failed to read jobs directory %s: %w", m.directory, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.directory, entry.Name(), constants.JOB_FILE))
+		if err != nil {
+			continue
+		}
+		job := &models.ReportJob{}
+		if err := json.Unmarshal(data, job); err != nil || job.ID != entry.Name() {
+			slog.Warn("skipping invalid job", "job_id", entry.Name(), logging.KeyError, err)
+			continue
+		}
+		if job.Status == constants.JOB_STATUS_QUEUED || job.Status == constants.JOB_STATUS_RUNNING {
+			job.Status = constants.RUN_STATUS_FAILED
+			job.Error = "interrupted by a server shutdown"
+			m.saveJob(job)
+		}
+		m.jobs[job.ID] = job
+	}
+	return nil
+}
+
+// newJobID returns a unique ID that sorts by submission time
+func newJobID() string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return time.Now().UTC().Format(constants.RUN_TIMESTAMP_LAYOUT) + "-" + hex.EncodeToString(random)
+}
@@ -0,0 +1,235 @@
+/*
+Package server exposes the report pipeline over HTTP. Report jobs are submitted, polled, canceled
+and their reports downloaded as CSV or JSON:
+
+	POST   /jobs                     submit a job, 202 with the queued job, 503 when the queue is full
+	GET    /jobs                     list all jobs, the latest first
+	GET    /jobs/{id}                status of a job
+	DELETE /jobs/{id}                cancel a queued or running job
+	GET    /jobs/{id}/files/{name}   download a report, ?format=csv|json overrides the format of the job
+	GET    /metrics                  Prometheus metrics of the provider requests and reports
+*/
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+)
+
+// Server serves the HTTP API of the job manager.
+type Server struct {
+	jobs *JobManager
+}
+
+// New creates the HTTP API of the job manager.
+func New(jobs *JobManager) *Server {
+	return &Server{jobs: jobs}
+}
+
+// Handler returns the routes of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.submitJob)
+	mux.HandleFunc("GET /jobs", s.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	mux.HandleFunc("DELETE /jobs/{id}", s.cancelJob)
+	mux.HandleFunc("GET /jobs/{id}/files/{name}", s.downloadFile)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	return mux
+}
+
+/*
+ListenAndServe serves the API and runs the jobs until the context is canceled. On shutdown
+running jobs are canceled and in-flight requests are given a few seconds to complete.
+*/
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		s.jobs.Run(ctx)
+		close(workersDone)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logging.FromContext(ctx).Info("serving the report API", "address", address)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = httpServer.Shutdown(shutdownCtx)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	if ctx.Err() != nil {
+		<-workersDone
+	}
+	return err
+}
+
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	request := models.ReportJobRequest{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job request: %w", err))
+		return
+	}
+
+	job, err := s.jobs.Submit(request)
+	if errors.Is(err, ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.List())
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrJobFinished):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+func (s *Server) downloadFile(w http.ResponseWriter, r *http.Request) {
+	id, name := r.PathValue("id"), r.PathValue("name")
+	job, err := s.jobs.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	filePath, err := s.jobs.FilePath(id, name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	format := job.Request.Format
+	if query := r.URL.Query().Get("format"); query != "" {
+		format = strings.ToLower(query)
+	}
+
+	switch format {
+	case constants.REPORT_FORMAT_JSON:
+		file, err := os.Open(filePath)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer file.Close()
+		reader := csv.NewReader(file)
+		header, err := reader.Read()
+		if err != nil && err != io.EOF {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("error reading report: %w", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := writeRecords(w, reader, header); err != nil {
+			// The status is sent already, abort the response so the client does not take it as complete
+			logging.FromContext(r.Context()).Error("streaming report as JSON failed", "file", name, logging.KeyError, err)
+			panic(http.ErrAbortHandler)
+		}
+	case constants.REPORT_FORMAT_CSV, "":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		http.ServeFile(w, r, filePath)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format '%s', supported formats are csv and json", format))
+	}
+}
+
+/*
+writeRecords streams the rows of a report as a JSON array of objects keyed by the column headers,
+one row is held in memory at a time. A report without header is an empty array.
+*/
+func writeRecords(w io.Writer, reader *csv.Reader, header []string) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for rows := 0; header != nil; rows++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading report: %w", err)
+		}
+		record := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(row) {
+				record[column] = row[i]
+			}
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if rows > 0 {
+			data = append([]byte(","), data...)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+const wallet = "0x1111111111111111111111111111111111111111"
+
+// newTestServer serves the API with replayed provider fixtures, the workers only run when start is set
+func newTestServer(t *testing.T, queueSize int, start bool) (*httptest.Server, *JobManager) {
+	t.Helper()
+	fixtures, err := filepath.Abs(filepath.Join("..", "usecase", "testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := models.Config{
+		Chains:   []string{constants.CHAIN_ETHEREUM},
+		Fixtures: models.FixtureConfig{ReplayDirectory: fixtures},
+		Server:   models.ServerConfig{Workers: 1, QueueSize: queueSize, JobsDirectory: t.TempDir()},
+	}
+	jobs, err := NewJobManager(constants.PROVIDER_ETHERSCAN, config)
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		if start {
+			jobs.Run(ctx)
+		}
+		close(done)
+	}()
+	server := httptest.NewServer(New(jobs).Handler())
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+		<-done
+	})
+	return server, jobs
+}
+
+func doRequest(t *testing.T, method, url, body string, value any) int {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	defer response.Body.Close()
+	if value != nil {
+		if err := json.NewDecoder(response.Body).Decode(value); err != nil {
+			t.Fatalf("%s %s invalid response: %v", method, url, err)
+		}
+	}
+	return response.StatusCode
+}
+
+func TestServerJobLifecycle(t *testing.T) {
+	server, _ := newTestServer(t, 4, true)
+
+	job := models.ReportJob{}
+	body := `{"wallet":"` + wallet + `","reportTypes":["EXTERNAL_REPORT"],"format":"json"}`
+	if status := doRequest(t, http.MethodPost, server.URL+"/jobs", body, &job); status != http.StatusAccepted {
+		t.Fatalf("POST /jobs status = %d; want %d", status, http.StatusAccepted)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status == constants.JOB_STATUS_QUEUED || job.Status == constants.JOB_STATUS_RUNNING {
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", job.ID, job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+		doRequest(t, http.MethodGet, server.URL+"/jobs/"+job.ID, "", &job)
+	}
+	if job.Status != constants.RUN_STATUS_SUCCEEDED || len(job.Files) != 1 {
+		t.Fatalf("job = %s with %d files, error %q; want %s with 1 file", job.Status, len(job.Files), job.Error, constants.RUN_STATUS_SUCCEEDED)
+	}
+
+	jobs := []models.ReportJob{}
+	if status := doRequest(t, http.MethodGet, server.URL+"/jobs", "", &jobs); status != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("GET /jobs = %d with %d jobs; want %d with 1", status, len(jobs), http.StatusOK)
+	}
+
+	records := []map[string]string{}
+	fileURL := server.URL + "/jobs/" + job.ID + "/files/" + job.Files[0].File
+	if status := doRequest(t, http.MethodGet, fileURL, "", &records); status != http.StatusOK || len(records) != 2 {
+		t.Errorf("GET %s = %d with %d records; want %d with 2", fileURL, status, len(records), http.StatusOK)
+	}
+
+	response, err := http.Get(fileURL + "?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/csv" {
+		t.Errorf("CSV download = %d %s; want %d text/csv", response.StatusCode, response.Header.Get("Content-Type"), http.StatusOK)
+	}
+
+	if status := doRequest(t, http.MethodGet, server.URL+"/jobs/"+job.ID+"/files/config.yml", "", nil); status != http.StatusNotFound {
+		t.Errorf("download of an unlisted file status = %d; want %d", status, http.StatusNotFound)
+	}
+	if status := doRequest(t, http.MethodDelete, server.URL+"/jobs/"+job.ID, "", nil); status != http.StatusConflict {
+		t.Errorf("cancel of a finished job status = %d; want %d", status, http.StatusConflict)
+	}
+
+	response, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsBody, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if want := `tracker_report_rows_total{chain="ethereum",report_type="EXTERNAL_REPORT"} 2`; !strings.Contains(string(metricsBody), want) {
+		t.Errorf("GET /metrics is missing %s", want)
+	}
+}
+
+func TestServerQueue(t *testing.T) {
+	server, jobs := newTestServer(t, 1, false)
+	body := `{"wallet":"` + wallet + `"}`
+
+	job := models.ReportJob{}
+	if status := doRequest(t, http.MethodPost, server.URL+"/jobs", body, &job); status != http.StatusAccepted {
+		t.Fatalf("POST /jobs status = %d; want %d", status, http.StatusAccepted)
+	}
+	if status := doRequest(t, http.MethodPost, server.URL+"/jobs", body, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("POST /jobs with a full queue status = %d; want %d", status, http.StatusServiceUnavailable)
+	}
+
+	if status := doRequest(t, http.MethodDelete, server.URL+"/jobs/"+job.ID, "", &job); status != http.StatusAccepted || job.Status != constants.RUN_STATUS_CANCELED {
+		t.Errorf("cancel of a queued job = %d %s; want %d %s", status, job.Status, http.StatusAccepted, constants.RUN_STATUS_CANCELED)
+	}
+
+	// The canceled job is skipped and kept after a restart
+	reloaded, err := NewJobManager(constants.PROVIDER_ETHERSCAN, jobs.config)
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	if saved, err := reloaded.Get(job.ID); err != nil || saved.Status != constants.RUN_STATUS_CANCELED {
+		t.Errorf("reloaded job = %s, %v; want %s", saved.Status, err, constants.RUN_STATUS_CANCELED)
+	}
+}
+
+func TestServerInvalidRequest(t *testing.T) {
+	server, _ := newTestServer(t, 1, false)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Invalid Wallet", body: `{"wallet":"0x1234"}`},
+		{name: "Unknown Chain", body: `{"wallet":"` + wallet + `","chains":["dogechain"]}`},
+		{name: "Unknown Report Type", body: `{"wallet":"` + wallet + `","reportTypes":["TAX_REPORT"]}`},
+		{name: "Inverted Block Range", body: `{"wallet":"` + wallet + `","startBlock":200,"endBlock":100}`},
+		{name: "Unknown Format", body: `{"wallet":"` + wallet + `","format":"xlsx"}`},
+		{name: "Unknown Field", body: `{"wallet":"` + wallet + `","walet":"x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := map[string]string{}
+			if status := doRequest(t, http.MethodPost, server.URL+"/jobs", tt.body, &response); status != http.StatusBadRequest || response["error"] == "" {
+				t.Errorf("POST /jobs = %d %v; want %d with an error", status, response, http.StatusBadRequest)
+			}
+		})
+	}
+
+	if status := doRequest(t, http.MethodGet, server.URL+"/jobs/unknown", "", nil); status != http.StatusNotFound {
+		t.Errorf("GET of an unknown job status = %d; want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   string
+	}{
+		{name: "Empty Report", report: "", want: "[]\n"},
+		{name: "Header Only", report: "Hash,Value\n", want: "[]\n"},
+		{name: "Rows", report: "Hash,Value\n0x01,1\n0x02,\"2,5\"\n", want: `[{"Hash":"0x01","Value":"1"},{"Hash":"0x02","Value":"2,5"}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := csv.NewReader(strings.NewReader(tt.report))
+			header, err := reader.Read()
+			if err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+			out := strings.Builder{}
+			if err := writeRecords(&out, reader, header); err != nil {
+				t.Fatalf("writeRecords() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeRecords() = %s; want %s", out.String(), tt.want)
+			}
+		})
+	}
+}
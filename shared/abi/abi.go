@@ -0,0 +1,280 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+type (
+	// Argument is a single input of a function or event as described in the JSON ABI
+	Argument struct {
+		Name       string     `json:"name"`
+		Type       string     `json:"type"`
+		Indexed    bool       `json:"indexed"`    // Only used by events
+		Components []Argument `json:"components"` // Only used by tuples
+	}
+
+	// Entry is a raw item of the JSON ABI array
+	Entry struct {
+		Type      string     `json:"type"` // "function", "event", "constructor", "fallback", ...
+		Name      string     `json:"name"`
+		Inputs    []Argument `json:"inputs"`
+		Anonymous bool       `json:"anonymous"`
+	}
+
+	// Method is a contract function that can be matched by its 4 byte selector
+	Method struct {
+		Name     string
+		Inputs   []Argument
+		Selector string // 0x prefixed, lower case
+	}
+
+	// Event is a contract event that can be matched by its topic0
+	Event struct {
+		Name   string
+		Inputs []Argument
+		Topic  string // 0x prefixed, lower case
+	}
+
+	// ABI holds the functions and events of a contract indexed by selector/topic
+	ABI struct {
+		Methods map[string]Method
+		Events  map[string]Event
+	}
+
+	// DecodedArgument is a decoded value rendered as a string
+	DecodedArgument struct {
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+
+	// DecodedCall is the result of decoding transaction calldata
+	DecodedCall struct {
+		MethodID   string
+		MethodName string
+		Signature  string
+		Arguments  []DecodedArgument
+	}
+
+	// DecodedLog is the result of decoding an event log
+	DecodedLog struct {
+		Topic     string
+		EventName string
+		Signature string
+		Arguments []DecodedArgument
+	}
+)
+
+/*
+Parse a JSON ABI (the array format produced by solc and returned by Etherscan) and
+index its functions by selector and its events by topic.
+*/
+func ParseABI(data []byte) (*ABI, error) {
+	entries := []Entry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid ABI json: %w", err)
+	}
+
+	contractABI := &ABI{
+		Methods: map[string]Method{},
+		Events:  map[string]Event{},
+	}
+	contractABI.add(entries)
+	return contractABI, nil
+}
+
+func (a *ABI) add(entries []Entry) {
+	for _, entry := range entries {
+		switch entry.Type {
+		case "function", "":
+			// Older ABIs omit the type for functions
+			signature := Signature(entry.Name, entry.Inputs)
+			selector := "0x" + hex.EncodeToString(util.Keccak256([]byte(signature))[:4])
+			a.Methods[selector] = Method{Name: entry.Name, Inputs: entry.Inputs, Selector: selector}
+		case "event":
+			if entry.Anonymous {
+				// Anonymous events have no topic0 and can not be matched
+				continue
+			}
+			signature := Signature(entry.Name, entry.Inputs)
+			topic := "0x" + hex.EncodeToString(util.Keccak256([]byte(signature)))
+			a.Events[topic] = Event{Name: entry.Name, Inputs: entry.Inputs, Topic: topic}
+		}
+	}
+}
+
+/*
+Build the canonical signature used for hashing, e.g. "transfer(address,uint256)".
+Tuples are expanded into their component types.
+*/
+func Signature(name string, inputs []Argument) string {
+	types := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		types = append(types, canonicalType(input))
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(types, ","))
+}
+
+func canonicalType(arg Argument) string {
+	if strings.HasPrefix(arg.Type, "tuple") {
+		components := make([]string, 0, len(arg.Components))
+		for _, component := range arg.Components {
+			components = append(components, canonicalType(component))
+		}
+		return "(" + strings.Join(components, ",") + ")" + strings.TrimPrefix(arg.Type, "tuple")
+	}
+	switch arg.Type {
+	case "uint":
+		return "uint256"
+	case "int":
+		return "int256"
+	}
+	return arg.Type
+}
+
+/*
+Decode the calldata of a transaction (0x prefixed hex) using the methods of the ABI.
+Returns an error if the selector is unknown or the arguments can not be decoded.
+*/
+func (a *ABI) DecodeInput(input string) (*DecodedCall, error) {
+	data, err := decodeHex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("calldata too short to contain a method selector")
+	}
+
+	selector := "0x" + hex.EncodeToString(data[:4])
+	method, ok := a.Methods[selector]
+	if !ok {
+		return nil, fmt.Errorf("unknown method selector %s", selector)
+	}
+
+	args, err := decodeArguments(method.Inputs, data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode arguments of %s: %w", method.Name, err)
+	}
+
+	return &DecodedCall{
+		MethodID:   selector,
+		MethodName: method.Name,
+		Signature:  Signature(method.Name, method.Inputs),
+		Arguments:  args,
+	}, nil
+}
+
+/*
+Decode an event log using the events of the ABI. Indexed arguments are read from the topics,
+the remaining arguments from the data. Indexed dynamic values (strings, arrays) are only
+available as their keccak hash and are rendered as such.
+*/
+func (a *ABI) DecodeLog(topics []string, data string) (*DecodedLog, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("anonymous logs without topics can not be decoded")
+	}
+
+	topic := strings.ToLower(topics[0])
+	event, ok := a.Events[topic]
+	if !ok {
+		return nil, fmt.Errorf("unknown event topic %s", topic)
+	}
+
+	indexed := []Argument{}
+	nonIndexed := []Argument{}
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		} else {
+			nonIndexed = append(nonIndexed, input)
+		}
+	}
+	// ERC-20 and ERC-721 Transfer share the same topic, the topic count tells them apart
+	if len(indexed) != len(topics)-1 {
+		return nil, fmt.Errorf("event %s expects %d indexed arguments, log has %d", event.Name, len(indexed), len(topics)-1)
+	}
+
+	indexedValues := make([]string, 0, len(indexed))
+	for i, input := range indexed {
+		typ, err := parseType(input)
+		if err != nil {
+			return nil, err
+		}
+		word, err := decodeHex(topics[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if typ.isDynamic() || typ.kind == "tuple" || typ.kind == "array" {
+			indexedValues = append(indexedValues, "0x"+hex.EncodeToString(word))
+			continue
+		}
+		if len(word) != wordSize {
+			return nil, fmt.Errorf("topic %d of %s is not 32 bytes", i+1, event.Name)
+		}
+		value, err := decodeWord(typ, word)
+		if err != nil {
+			return nil, err
+		}
+		indexedValues = append(indexedValues, value)
+	}
+
+	dataBytes, err := decodeHex(data)
+	if err != nil {
+		return nil, err
+	}
+	nonIndexedArgs, err := decodeArguments(nonIndexed, dataBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data of %s: %w", event.Name, err)
+	}
+
+	// Keep the declaration order of the event inputs
+	args := make([]DecodedArgument, 0, len(event.Inputs))
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			args = append(args, DecodedArgument{Name: input.Name, Type: canonicalType(input), Value: indexedValues[0]})
+			indexedValues = indexedValues[1:]
+		} else {
+			args = append(args, nonIndexedArgs[0])
+			nonIndexedArgs = nonIndexedArgs[1:]
+		}
+	}
+
+	return &DecodedLog{
+		Topic:     topic,
+		EventName: event.Name,
+		Signature: Signature(event.Name, event.Inputs),
+		Arguments: args,
+	}, nil
+}
+
+// DecodeArguments decodes ABI encoded values, e.g. the return data of an eth_call.
+func DecodeArguments(args []Argument, data []byte) ([]DecodedArgument, error) {
+	return decodeArguments(args, data)
+}
+
+// FormatArguments renders the decoded arguments as "name=value; name=value" for CSV output.
+func FormatArguments(args []DecodedArgument) string {
+	parts := make([]string, 0, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		parts = append(parts, name+"="+arg.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func decodeHex(input string) ([]byte, error) {
+	input = strings.TrimPrefix(strings.TrimPrefix(input, "0x"), "0X")
+	data, err := hex.DecodeString(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,202 @@
+package abi
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"submit","inputs":[{"name":"note","type":"string"},{"name":"ids","type":"uint256[]"},{"name":"delta","type":"int8"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+]`
+
+func word(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+func TestDecodeInput(t *testing.T) {
+	contractABI, err := ParseABI([]byte(testABI))
+	if err != nil {
+		t.Fatalf("ParseABI unexpected error: %v", err)
+	}
+
+	submitSelector := ""
+	for selector, method := range contractABI.Methods {
+		if method.Name == "submit" {
+			submitSelector = selector
+		}
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		wantName  string
+		wantArgs  string
+		expectErr bool
+	}{
+		{
+			name:     "Static Arguments",
+			input:    "0xa9059cbb" + word("dead") + word("3e8"),
+			wantName: "transfer",
+			wantArgs: "to=0x000000000000000000000000000000000000dEaD; amount=1000",
+		},
+		{
+			name: "Dynamic Arguments",
+			input: submitSelector +
+				word("60") + word("a0") + strings.Repeat("f", 64) + // offsets of note and ids, delta = -1
+				word("2") + "6869" + strings.Repeat("0", 60) + // note = "hi"
+				word("2") + word("1") + word("2"), // ids = [1,2]
+			wantName: "submit",
+			wantArgs: "note=hi; ids=[1,2]; delta=-1",
+		},
+		{
+			name:      "Unknown Selector",
+			input:     "0xdeadbeef",
+			expectErr: true,
+		},
+		{
+			name:      "Truncated Arguments",
+			input:     "0xa9059cbb" + word("dead"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			call, err := contractABI.DecodeInput(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("DecodeInput(%q) expected an error, but got nil", tc.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeInput(%q) unexpected error: %v", tc.input, err)
+			}
+			if call.MethodName != tc.wantName {
+				t.Errorf("DecodeInput(%q).MethodName = %q; want %q", tc.input, call.MethodName, tc.wantName)
+			}
+			if got := FormatArguments(call.Arguments); got != tc.wantArgs {
+				t.Errorf("DecodeInput(%q) arguments = %q; want %q", tc.input, got, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func TestEventTopic(t *testing.T) {
+	contractABI, err := ParseABI([]byte(testABI))
+	if err != nil {
+		t.Fatalf("ParseABI unexpected error: %v", err)
+	}
+
+	transferTopic := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	if event, ok := contractABI.Events[transferTopic]; !ok || event.Name != "Transfer" {
+		t.Errorf("Events[%q] = %+v; want Transfer event", transferTopic, event)
+	}
+}
+
+func TestRegistryDecodeLogStandards(t *testing.T) {
+	registry := NewRegistry("", "", nil)
+	for _, standard := range StandardABIs() {
+		registry.AddFallback(standard)
+	}
+
+	transferTopic := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	approvalForAllTopic := "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
+	owner := "0x" + word("aa")
+	operator := "0x" + word("bb")
+
+	tests := []struct {
+		name      string
+		topics    []string
+		data      string
+		wantEvent string
+		wantArgs  string
+	}{
+		{
+			name:      "ERC-20 Transfer",
+			topics:    []string{transferTopic, owner, operator},
+			data:      "0x" + word("64"),
+			wantEvent: "Transfer",
+			wantArgs:  "from=0x00000000000000000000000000000000000000AA; to=0x00000000000000000000000000000000000000bb; value=100",
+		},
+		{
+			name:      "ERC-721 Transfer",
+			topics:    []string{transferTopic, owner, operator, "0x" + word("7")},
+			data:      "0x",
+			wantEvent: "Transfer",
+			wantArgs:  "from=0x00000000000000000000000000000000000000AA; to=0x00000000000000000000000000000000000000bb; tokenId=7",
+		},
+		{
+			name:      "ERC-721 ApprovalForAll",
+			topics:    []string{approvalForAllTopic, owner, operator},
+			data:      "0x" + word("1"),
+			wantEvent: "ApprovalForAll",
+			wantArgs:  "owner=0x00000000000000000000000000000000000000AA; operator=0x00000000000000000000000000000000000000bb; approved=true",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := registry.DecodeLog("0x0000000000000000000000000000000000000001", tc.topics, tc.data)
+			if err != nil {
+				t.Fatalf("DecodeLog unexpected error: %v", err)
+			}
+			if decoded.EventName != tc.wantEvent {
+				t.Errorf("DecodeLog().EventName = %q; want %q", decoded.EventName, tc.wantEvent)
+			}
+			if got := FormatArguments(decoded.Arguments); got != tc.wantArgs {
+				t.Errorf("DecodeLog() arguments = %q; want %q", got, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRegistryConcurrentLookup(t *testing.T) {
+	slow := "0x00000000000000000000000000000000000000aa"
+	release := make(chan struct{})
+	fetches := atomic.Int32{}
+	registry := NewRegistry("", "", func(address string) ([]byte, error) {
+		fetches.Add(1)
+		if address == slow {
+			<-release
+		}
+		return []byte(testABI), nil
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := registry.Lookup(slow); err != nil {
+				t.Errorf("Lookup(%s) unexpected error: %v", slow, err)
+			}
+		}()
+	}
+
+	// A fetch in progress must not block the lookups of other contracts
+	done := make(chan error, 1)
+	go func() {
+		_, err := registry.Lookup("0x00000000000000000000000000000000000000bb")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Lookup() unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lookup() waited for the fetch of another contract")
+	}
+
+	close(release)
+	wg.Wait()
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetcher called %d times; want one fetch per contract", got)
+	}
+}
@@ -0,0 +1,293 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+const wordSize = 32
+
+// abiType is a parsed solidity type, e.g. "uint256[2][]" or a tuple
+type abiType struct {
+	kind       string // "uint", "int", "address", "bool", "bytes", "fixedbytes", "string", "slice", "array", "tuple"
+	size       int    // bit size for ints, byte size for fixed bytes
+	length     int    // length of fixed size arrays
+	elem       *abiType
+	components []Argument
+	raw        string
+}
+
+func parseType(arg Argument) (*abiType, error) {
+	typ := arg.Type
+
+	// Arrays are parsed from the outermost (right most) dimension
+	if strings.HasSuffix(typ, "]") {
+		open := strings.LastIndex(typ, "[")
+		if open < 0 {
+			return nil, fmt.Errorf("invalid array type %s", typ)
+		}
+		elem, err := parseType(Argument{Type: typ[:open], Components: arg.Components})
+		if err != nil {
+			return nil, err
+		}
+		dimension := typ[open+1 : len(typ)-1]
+		if dimension == "" {
+			return &abiType{kind: "slice", elem: elem, raw: typ}, nil
+		}
+		length, err := strconv.Atoi(dimension)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length in type %s", typ)
+		}
+		return &abiType{kind: "array", length: length, elem: elem, raw: typ}, nil
+	}
+
+	switch {
+	case typ == "tuple":
+		return &abiType{kind: "tuple", components: arg.Components, raw: typ}, nil
+	case typ == "address":
+		return &abiType{kind: "address", raw: typ}, nil
+	case typ == "bool":
+		return &abiType{kind: "bool", raw: typ}, nil
+	case typ == "string":
+		return &abiType{kind: "string", raw: typ}, nil
+	case typ == "bytes":
+		return &abiType{kind: "bytes", raw: typ}, nil
+	case typ == "function":
+		// function pointers are encoded as bytes24
+		return &abiType{kind: "fixedbytes", size: 24, raw: typ}, nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid fixed bytes type %s", typ)
+		}
+		return &abiType{kind: "fixedbytes", size: size, raw: typ}, nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		kind := "int"
+		if strings.HasPrefix(typ, "uint") {
+			kind = "uint"
+		}
+		size := 256
+		if bitSize := strings.TrimPrefix(typ, kind); bitSize != "" {
+			var err error
+			size, err = strconv.Atoi(bitSize)
+			if err != nil || size < 8 || size > 256 || size%8 != 0 {
+				return nil, fmt.Errorf("invalid integer type %s", typ)
+			}
+		}
+		return &abiType{kind: kind, size: size, raw: typ}, nil
+	}
+	return nil, fmt.Errorf("unsupported ABI type %s", typ)
+}
+
+// isDynamic reports whether the type is encoded in the tail section
+func (t *abiType) isDynamic() bool {
+	switch t.kind {
+	case "bytes", "string", "slice":
+		return true
+	case "array":
+		return t.elem.isDynamic()
+	case "tuple":
+		for _, component := range t.components {
+			componentType, err := parseType(component)
+			if err != nil || componentType.isDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize is the number of bytes the type takes in the head section
+func (t *abiType) headSize() int {
+	if t.isDynamic() {
+		return wordSize
+	}
+	switch t.kind {
+	case "array":
+		return t.length * t.elem.headSize()
+	case "tuple":
+		size := 0
+		for _, component := range t.components {
+			componentType, _ := parseType(component)
+			size += componentType.headSize()
+		}
+		return size
+	}
+	return wordSize
+}
+
+/*
+Decode ABI encoded values (head/tail encoding) for the given arguments.
+Values are rendered as strings: integers in decimal, addresses and bytes as 0x hex,
+arrays as [a,b] and tuples as (a,b).
+*/
+func decodeArguments(args []Argument, data []byte) ([]DecodedArgument, error) {
+	types := make([]*abiType, 0, len(args))
+	for _, arg := range args {
+		typ, err := parseType(arg)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+	}
+
+	values, err := decodeSequence(types, data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]DecodedArgument, 0, len(args))
+	for i, arg := range args {
+		decoded = append(decoded, DecodedArgument{Name: arg.Name, Type: canonicalType(arg), Value: values[i]})
+	}
+	return decoded, nil
+}
+
+// decodeSequence decodes a list of values that share a head/tail section (arguments, tuples, arrays)
+func decodeSequence(types []*abiType, data []byte) ([]string, error) {
+	values := make([]string, 0, len(types))
+	offset := 0
+	for _, typ := range types {
+		if typ.isDynamic() {
+			word, err := readWord(data, offset)
+			if err != nil {
+				return nil, err
+			}
+			tailOffset, err := wordToInt(word)
+			if err != nil {
+				return nil, err
+			}
+			if tailOffset > len(data) {
+				return nil, fmt.Errorf("offset %d out of bounds for %s", tailOffset, typ.raw)
+			}
+			value, err := decodeValue(typ, data[tailOffset:])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		} else {
+			if offset > len(data) {
+				return nil, fmt.Errorf("data too short for %s", typ.raw)
+			}
+			value, err := decodeValue(typ, data[offset:])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		offset += typ.headSize()
+	}
+	return values, nil
+}
+
+// decodeValue decodes a single value whose encoding starts at the beginning of data
+func decodeValue(typ *abiType, data []byte) (string, error) {
+	switch typ.kind {
+	case "tuple":
+		types := make([]*abiType, 0, len(typ.components))
+		for _, component := range typ.components {
+			componentType, err := parseType(component)
+			if err != nil {
+				return "", err
+			}
+			types = append(types, componentType)
+		}
+		values, err := decodeSequence(types, data)
+		if err != nil {
+			return "", err
+		}
+		return "(" + strings.Join(values, ",") + ")", nil
+
+	case "array", "slice":
+		length := typ.length
+		if typ.kind == "slice" {
+			word, err := readWord(data, 0)
+			if err != nil {
+				return "", err
+			}
+			length, err = wordToInt(word)
+			if err != nil {
+				return "", err
+			}
+			data = data[wordSize:]
+		}
+		if length > len(data) {
+			return "", fmt.Errorf("array length %d out of bounds for %s", length, typ.raw)
+		}
+		types := make([]*abiType, length)
+		for i := range types {
+			types[i] = typ.elem
+		}
+		values, err := decodeSequence(types, data)
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(values, ",") + "]", nil
+
+	case "bytes", "string":
+		word, err := readWord(data, 0)
+		if err != nil {
+			return "", err
+		}
+		length, err := wordToInt(word)
+		if err != nil {
+			return "", err
+		}
+		if wordSize+length > len(data) {
+			return "", fmt.Errorf("%s length %d out of bounds", typ.raw, length)
+		}
+		content := data[wordSize : wordSize+length]
+		if typ.kind == "string" {
+			return string(content), nil
+		}
+		return "0x" + hex.EncodeToString(content), nil
+	}
+
+	word, err := readWord(data, 0)
+	if err != nil {
+		return "", err
+	}
+	return decodeWord(typ, word)
+}
+
+// decodeWord decodes a static value that fits into a single 32 byte word
+func decodeWord(typ *abiType, word []byte) (string, error) {
+	switch typ.kind {
+	case "address":
+		return util.ChecksumAddress("0x" + hex.EncodeToString(word[12:])), nil
+	case "bool":
+		return strconv.FormatBool(word[wordSize-1] == 1), nil
+	case "fixedbytes":
+		return "0x" + hex.EncodeToString(word[:typ.size]), nil
+	case "uint":
+		return new(big.Int).SetBytes(word).String(), nil
+	case "int":
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			// Two's complement for negative numbers
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return value.String(), nil
+	}
+	return "", fmt.Errorf("unsupported ABI type %s", typ.raw)
+}
+
+func readWord(data []byte, offset int) ([]byte, error) {
+	if offset < 0 || offset+wordSize > len(data) {
+		return nil, fmt.Errorf("data too short, need %d bytes at offset %d", wordSize, offset)
+	}
+	return data[offset : offset+wordSize], nil
+}
+
+func wordToInt(word []byte) (int, error) {
+	value := new(big.Int).SetBytes(word)
+	if !value.IsInt64() || value.Int64() > int64(^uint32(0)) {
+		return 0, fmt.Errorf("value %s is too large to be an offset or length", value.String())
+	}
+	return int(value.Int64()), nil
+}
@@ -0,0 +1,206 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrABINotFound is returned when no ABI is known for a contract.
+var ErrABINotFound = errors.New("contract ABI not found")
+
+// Fetcher loads the JSON ABI of a contract from a remote source, e.g. Etherscan's contract/getabi.
+type Fetcher func(address string) ([]byte, error)
+
+/*
+Registry resolves contract ABIs by address. ABIs are looked up in the following order:
+ 1. in memory
+ 2. <directory>/<address>.json (ABIs maintained by hand)
+ 3. <cacheDirectory>/<address>.json (ABIs fetched earlier)
+ 4. the remote fetcher, successful results are written to the cache directory
+
+Fallback ABIs (e.g. the ERC-20 standard) are used when a contract has no ABI of its own
+or the selector/topic is not part of it. They are tried in the order they were added.
+*/
+type Registry struct {
+	directory      string
+	cacheDirectory string
+	fetcher        Fetcher
+
+	mu        sync.Mutex
+	contracts map[string]*ABI // nil value marks a contract without ABI so it is not fetched again
+	loading   map[string]*pendingLoad
+	fallbacks []*ABI
+}
+
+// pendingLoad is an ABI load in progress, concurrent lookups of the same contract wait for it
+type pendingLoad struct {
+	done        chan struct{}
+	contractABI *ABI
+	err         error
+}
+
+// NewRegistry creates a registry, the directories and fetcher are optional.
+func NewRegistry(directory, cacheDirectory string, fetcher Fetcher) *Registry {
+	return &Registry{
+		directory:      directory,
+		cacheDirectory: cacheDirectory,
+		fetcher:        fetcher,
+		contracts:      map[string]*ABI{},
+		loading:        map[string]*pendingLoad{},
+	}
+}
+
+// AddFallback registers an ABI used for every contract, e.g. token standards.
+func (r *Registry) AddFallback(fallback *ABI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallbacks = append(r.fallbacks, fallback)
+}
+
+// Lookup returns the ABI of the contract at the given address.
+func (r *Registry) Lookup(address string) (*ABI, error) {
+	key := strings.ToLower(strings.TrimSpace(address))
+	if key == "" {
+		return nil, ErrABINotFound
+	}
+
+	r.mu.Lock()
+	if contractABI, ok := r.contracts[key]; ok {
+		r.mu.Unlock()
+		if contractABI == nil {
+			return nil, ErrABINotFound
+		}
+		return contractABI, nil
+	}
+	// The ABI may be fetched over the network, the lock is not held meanwhile and
+	// lookups of the same contract wait for the load in progress
+	if pending, ok := r.loading[key]; ok {
+		r.mu.Unlock()
+		<-pending.done
+		return pending.contractABI, pending.err
+	}
+	pending := &pendingLoad{done: make(chan struct{})}
+	r.loading[key] = pending
+	r.mu.Unlock()
+
+	contractABI, err := r.load(key)
+
+	r.mu.Lock()
+	delete(r.loading, key)
+	// Failed loads are not remembered so they are retried by the next lookup
+	if err == nil || errors.Is(err, ErrABINotFound) {
+		r.contracts[key] = contractABI
+		err = nil
+		if contractABI == nil {
+			err = ErrABINotFound
+		}
+	} else {
+		contractABI = nil
+	}
+	r.mu.Unlock()
+
+	pending.contractABI, pending.err = contractABI, err
+	close(pending.done)
+	return contractABI, err
+}
+
+/*
+Decode the calldata sent to the contract at the given address. The contract ABI is tried first,
+then the fallback ABIs.
+*/
+func (r *Registry) DecodeInput(address, input string) (*DecodedCall, error) {
+	contractABI, err := r.Lookup(address)
+	if err != nil && !errors.Is(err, ErrABINotFound) {
+		return nil, err
+	}
+	if contractABI != nil {
+		if call, err := contractABI.DecodeInput(input); err == nil {
+			return call, nil
+		}
+	}
+
+	var lastErr error = ErrABINotFound
+	for _, fallback := range r.fallbackABIs() {
+		call, err := fallback.DecodeInput(input)
+		if err == nil {
+			return call, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+/*
+Decode an event log emitted by the contract at the given address. The contract ABI is tried
+first, then the fallback ABIs.
+*/
+func (r *Registry) DecodeLog(address string, topics []string, data string) (*DecodedLog, error) {
+	contractABI, err := r.Lookup(address)
+	if err != nil && !errors.Is(err, ErrABINotFound) {
+		return nil, err
+	}
+	if contractABI != nil {
+		if decoded, err := contractABI.DecodeLog(topics, data); err == nil {
+			return decoded, nil
+		}
+	}
+
+	var lastErr error = ErrABINotFound
+	for _, fallback := range r.fallbackABIs() {
+		decoded, err := fallback.DecodeLog(topics, data)
+		if err == nil {
+			return decoded, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (r *Registry) fallbackABIs() []*ABI {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*ABI{}, r.fallbacks...)
+}
+
+func (r *Registry) load(address string) (*ABI, error) {
+	for _, dir := range []string{r.directory, r.cacheDirectory} {
+		if dir == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, address+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading ABI for %s: %w", address, err)
+		}
+		return ParseABI(data)
+	}
+
+	if r.fetcher == nil {
+		return nil, ErrABINotFound
+	}
+
+	data, err := r.fetcher(address)
+	if err != nil {
+		return nil, err
+	}
+	contractABI, err := ParseABI(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cacheDirectory != "" {
+		if err := os.MkdirAll(r.cacheDirectory, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create ABI cache directory %s: %w", r.cacheDirectory, err)
+		}
+		if err := os.WriteFile(filepath.Join(r.cacheDirectory, address+".json"), data, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to cache ABI for %s: %w", address, err)
+		}
+	}
+	return contractABI, nil
+}
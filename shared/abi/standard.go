@@ -0,0 +1,45 @@
+package abi
+
+// Token standard ABIs used to decode calls and events of contracts without a known ABI.
+// ERC-20 and ERC-721 share the Transfer/Approval topics, they differ in the number of indexed inputs.
+const (
+	erc20ABI = `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"increaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"addedValue","type":"uint256"}]},
+		{"type":"function","name":"decreaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"subtractedValue","type":"uint256"}]}
+	]`
+
+	erc721ABI = `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"approved","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+		{"type":"event","name":"ApprovalForAll","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool","indexed":false}]},
+		{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+		{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}]},
+		{"type":"function","name":"setApprovalForAll","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]}
+	]`
+)
+
+var (
+	// ERC20 holds the events and functions of the ERC-20 token standard
+	ERC20 = mustParseABI(erc20ABI)
+
+	// ERC721 holds the events and functions of the ERC-721 token standard
+	ERC721 = mustParseABI(erc721ABI)
+)
+
+// StandardABIs returns the token standard ABIs in the order they should be tried.
+func StandardABIs() []*ABI {
+	return []*ABI{ERC20, ERC721}
+}
+
+func mustParseABI(data string) *ABI {
+	contractABI, err := ParseABI([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return contractABI
+}
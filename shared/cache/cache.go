@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCacheMiss is returned in offline mode when a response is not cached.
+var ErrCacheMiss = errors.New("response not found in cache")
+
+// entry is the file format of a cached response
+type entry struct {
+	Key       string    `json:"key"`
+	StoredAt  time.Time `json:"storedAt"`
+	Permanent bool      `json:"permanent"` // Finalized block ranges never change and never expire
+	Response  string    `json:"response"`
+}
+
+/*
+ResponseCache is a content-addressed on-disk cache of provider responses. Entries are stored under
+<directory>/<first 2 chars of the hash>/<sha256 of the key>.json. The key must not contain secrets,
+use util.StripQueryParams to drop the API key from request URLs.
+*/
+type ResponseCache struct {
+	directory string
+	ttl       time.Duration // 0 never expires
+}
+
+// NewResponseCache creates a cache in the given directory with the given TTL for non permanent entries.
+func NewResponseCache(directory string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{directory: directory, ttl: ttl}
+}
+
+// Get returns the cached response for the key if present and not expired.
+func (c *ResponseCache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+
+	cached := entry{}
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Key != key {
+		return "", false
+	}
+	if !cached.Permanent && c.ttl > 0 && time.Since(cached.StoredAt) > c.ttl {
+		return "", false
+	}
+	return cached.Response, true
+}
+
+// Put stores the response for the key, permanent entries ignore the TTL.
+func (c *ResponseCache) Put(key, response string, permanent bool) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.Marshal(entry{Key: key, StoredAt: time.Now().UTC(), Permanent: permanent, Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	// Write to a temp file first so concurrent readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c *ResponseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	return filepath.Join(c.directory, hash[:2], hash+".json")
+}
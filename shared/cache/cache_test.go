@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		permanent bool
+		wait      time.Duration
+		wantHit   bool
+	}{
+		{name: "Fresh Entry", ttl: time.Hour, wantHit: true},
+		{name: "Expired Entry", ttl: time.Millisecond, wait: 5 * time.Millisecond, wantHit: false},
+		{name: "Permanent Entry Ignores TTL", ttl: time.Millisecond, permanent: true, wait: 5 * time.Millisecond, wantHit: true},
+		{name: "No TTL", ttl: 0, wait: 5 * time.Millisecond, wantHit: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			responseCache := NewResponseCache(dir, tc.ttl)
+			key := "https://api.etherscan.io/v2/api?action=txlist&test=" + tc.name
+
+			if err := responseCache.Put(key, `{"status":"1"}`, tc.permanent); err != nil {
+				t.Fatalf("Put unexpected error: %v", err)
+			}
+			time.Sleep(tc.wait)
+
+			got, hit := responseCache.Get(key)
+			if hit != tc.wantHit {
+				t.Fatalf("Get hit = %v; want %v", hit, tc.wantHit)
+			}
+			if hit && got != `{"status":"1"}` {
+				t.Errorf("Get = %q; want %q", got, `{"status":"1"}`)
+			}
+		})
+	}
+
+	if _, hit := NewResponseCache(dir, 0).Get("unknown"); hit {
+		t.Errorf("Get(%q) hit = true; want false", "unknown")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	lru := NewLRU[string, int](2)
+	lru.Put("a", 1)
+	lru.Put("b", 2)
+	lru.Get("a") // b is now the least recently used entry
+	lru.Put("c", 3)
+
+	if _, hit := lru.Get("b"); hit {
+		t.Errorf("Get(%q) hit = true; want it evicted", "b")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, hit := lru.Get(key); !hit || got != want {
+			t.Errorf("Get(%q) = %d, %v; want %d, true", key, got, hit, want)
+		}
+	}
+	lru.Put("c", 4)
+	if got, _ := lru.Get("c"); got != 4 || lru.Len() != 2 {
+		t.Errorf("Get(%q) = %d with %d entries; want 4 with 2 entries", "c", got, lru.Len())
+	}
+}
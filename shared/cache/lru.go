@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+/*
+LRU is an in-memory cache of at most size entries, the least recently used entry is evicted
+when a new one is added to a full cache. It is safe for concurrent use.
+*/
+type LRU[K comparable, V any] struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // Front is the most recently used entry
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewLRU creates a cache of at most size entries, a non-positive size keeps one entry.
+func NewLRU[K comparable, V any](size int) *LRU[K, V] {
+	return &LRU[K, V]{size: max(size, 1), order: list.New(), entries: map[K]*list.Element{}}
+}
+
+// Get returns the value of the key and marks it as recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// Put stores the value of the key, evicting the least recently used entry when the cache is full.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,80 @@
+package chains
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// registry of the supported chains, keyed by name
+var registry = map[string]models.Chain{
+	constants.CHAIN_ETHEREUM: {Name: constants.CHAIN_ETHEREUM, ChainID: 1, NativeSymbol: constants.TOKEN_SYMBOL_ETH, ExplorerBaseURL: "https://etherscan.io", Decimals: 18},
+	constants.CHAIN_POLYGON:  {Name: constants.CHAIN_POLYGON, ChainID: 137, NativeSymbol: constants.TOKEN_SYMBOL_POL, ExplorerBaseURL: "https://polygonscan.com", Decimals: 18},
+	constants.CHAIN_ARBITRUM: {Name: constants.CHAIN_ARBITRUM, ChainID: 42161, NativeSymbol: constants.TOKEN_SYMBOL_ETH, ExplorerBaseURL: "https://arbiscan.io", Decimals: 18},
+	constants.CHAIN_OPTIMISM: {Name: constants.CHAIN_OPTIMISM, ChainID: 10, NativeSymbol: constants.TOKEN_SYMBOL_ETH, ExplorerBaseURL: "https://optimistic.etherscan.io", Decimals: 18},
+	constants.CHAIN_BASE:     {Name: constants.CHAIN_BASE, ChainID: 8453, NativeSymbol: constants.TOKEN_SYMBOL_ETH, ExplorerBaseURL: "https://basescan.org", Decimals: 18},
+	constants.CHAIN_BSC:      {Name: constants.CHAIN_BSC, ChainID: 56, NativeSymbol: constants.TOKEN_SYMBOL_BNB, ExplorerBaseURL: "https://bscscan.com", Decimals: 18},
+	constants.CHAIN_SEPOLIA:  {Name: constants.CHAIN_SEPOLIA, ChainID: 11155111, NativeSymbol: constants.TOKEN_SYMBOL_ETH, ExplorerBaseURL: "https://sepolia.etherscan.io", Decimals: 18},
+}
+
+// Default returns Ethereum mainnet, used when no chain is configured.
+func Default() models.Chain {
+	return registry[constants.CHAIN_ETHEREUM]
+}
+
+// Lookup returns the chain registered under the given name (case-insensitive).
+func Lookup(name string) (models.Chain, error) {
+	chain, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return models.Chain{}, fmt.Errorf("unknown chain '%s', supported chains are: %s", name, strings.Join(Names(), ", "))
+	}
+	return chain, nil
+}
+
+// LookupByID returns the chain with the given chain ID.
+func LookupByID(chainID int64) (models.Chain, error) {
+	for _, chain := range registry {
+		if chain.ChainID == chainID {
+			return chain, nil
+		}
+	}
+	return models.Chain{}, fmt.Errorf("unknown chain id %d", chainID)
+}
+
+// Names returns the names of all supported chains, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Resolve the configured chain names. An empty list results in Ethereum mainnet,
+duplicates are dropped.
+*/
+func Resolve(names []string) ([]models.Chain, error) {
+	if len(names) == 0 {
+		return []models.Chain{Default()}, nil
+	}
+
+	resolved := []models.Chain{}
+	seen := map[int64]bool{}
+	for _, name := range names {
+		chain, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[chain.ChainID] {
+			continue
+		}
+		seen[chain.ChainID] = true
+		resolved = append(resolved, chain)
+	}
+	return resolved, nil
+}
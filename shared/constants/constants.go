@@ -3,23 +3,144 @@ package constants
 const (
 	PROVIDER_ETHERSCAN  = "etherscan"
 	PROVIDER_BLOCKSCOUT = "blockscout"
+	PROVIDER_RPC        = "rpc"
+	PROVIDER_FAILOVER   = "failover"
 
-	EXTERNAL_REPORT = "EXTERNAL_REPORT"
-	INTERNAL_REPORT = "INTERNAL_REPORT"
-	ERC20_REPORT    = "ERC20_REPORT"
-	ERC721_REPORT   = "ERC721_REPORT"
+	FAILOVER_DEFAULT_FAILURE_THRESHOLD = 3
+	FAILOVER_DEFAULT_COOLDOWN_SECONDS  = 60
+	// Streamed responses up to this size are read to check for an error response before they are returned
+	FAILOVER_STREAM_CHECK_BYTES = 4096
 
-	EXTERNAL_REPORT_ACTION = "txlist"
-	INTERNAL_REPORT_ACTION = "txlistinternal"
-	ERC20_REPORT_ACTION    = "tokentx"
-	ERC721_REPORT_ACTION   = "tokennfttx"
+	// Failed explorer requests are retried after this backoff, doubled for every further retry
+	PROVIDER_RETRY_BACKOFF_MILLISECONDS = 500
+	PROVIDER_RETRY_MAX_BACKOFF_SECONDS  = 8
 
-	TRANSACTION_TYPE_ETH_TRANSFER      = "ETH Transfer"
+	CACHE_DEFAULT_FINALITY_DEPTH   = 64
+	CACHE_LATEST_BLOCK_TTL_SECONDS = 30 // How long the head of the chain is reused to decide finality
+	BLOCK_NUMBER_ACTION            = "eth_blockNumber"
+
+	// Topic of Transfer(address,address,uint256), shared by ERC-20 and ERC-721
+	TRANSFER_EVENT_TOPIC = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	RPC_DEFAULT_LOG_BLOCK_RANGE = 10000
+	RPC_CACHE_ENTRIES           = 10000 // Entries kept per node lookup cache (blocks, receipts, transactions, tokens)
+
+	ENS_RESOLVER_RPC      = "rpc"
+	ENS_RESOLVER_FILE     = "file"
+	ENS_REGISTRY_ADDRESS  = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e" // Same address on mainnet and the testnets
+	ENS_RESOLVER_SELECTOR = "0x0178b8bf"                                 // resolver(bytes32) of the registry
+	ENS_ADDR_SELECTOR     = "0x3b3b57de"                                 // addr(bytes32) of a resolver
+	ENS_NAME_SELECTOR     = "0x691f3431"                                 // name(bytes32) of a reverse resolver
+	ENS_REVERSE_SUFFIX    = ".addr.reverse"
+	ENS_FROM_NAME_COLUMN  = "From ENS Name"
+	ENS_TO_NAME_COLUMN    = "To ENS Name"
+
+	EXTERNAL_REPORT  = "EXTERNAL_REPORT"
+	INTERNAL_REPORT  = "INTERNAL_REPORT"
+	ERC20_REPORT     = "ERC20_REPORT"
+	ERC721_REPORT    = "ERC721_REPORT"
+	EVENT_LOG_REPORT = "EVENT_LOG_REPORT"
+	APPROVAL_REPORT  = "APPROVAL_REPORT"
+
+	EXTERNAL_REPORT_ACTION  = "txlist"
+	INTERNAL_REPORT_ACTION  = "txlistinternal"
+	ERC20_REPORT_ACTION     = "tokentx"
+	ERC721_REPORT_ACTION    = "tokennfttx"
+	EVENT_LOG_REPORT_ACTION = "getLogs"
+	APPROVAL_REPORT_ACTION  = "approvals" // Built from getLogs and txlist, never sent to the provider
+
+	CONTRACT_ABI_ACTION = "getabi"
+
+	// Etherscan returns at most 1000 logs per page and page * offset must stay below 10000
+	LOGS_PAGE_SIZE         = 1000
+	LOGS_MAX_RESULT_WINDOW = 10000
+
+	TRANSACTION_TYPE_NATIVE_TRANSFER   = "%s Transfer" // e.g. ETH Transfer, POL Transfer
 	TRANSACTION_TYPE_INTERNAL_TRANSFER = "Internal"
 	TRANSACTION_TYPE_ERC20_TRANSFER    = "ERC-20 Transfer"
 	TRANSACTION_TYPE_ERC721_TRANSFER   = "ERC-721 Transfer"
 
 	TOKEN_SYMBOL_ETH = "ETH"
+	TOKEN_SYMBOL_POL = "POL"
+	TOKEN_SYMBOL_BNB = "BNB"
+
+	CHAIN_ETHEREUM = "ethereum"
+	CHAIN_POLYGON  = "polygon"
+	CHAIN_ARBITRUM = "arbitrum"
+	CHAIN_OPTIMISM = "optimism"
+	CHAIN_BASE     = "base"
+	CHAIN_BSC      = "bsc"
+	CHAIN_SEPOLIA  = "sepolia"
+
+	ADDRESS_CATEGORY_OWN_WALLET = "Own Wallet"
+
+	TOKEN_STANDARD_ERC20  = "ERC-20"
+	TOKEN_STANDARD_ERC721 = "ERC-721"
+
+	APPROVAL_STATUS_ACTIVE  = "Active"
+	APPROVAL_STATUS_REVOKED = "Revoked"
+	APPROVAL_ALLOWANCE_ALL  = "ALL" // ApprovalForAll covers every token of the collection
+
+	APPROVAL_SOURCE_EVENT_LOG = "Event Log"
+	APPROVAL_SOURCE_CALLDATA  = "Calldata"
 
 	DATE_FORMAT_YYYY_MM_DD_HH_MM_SS = "2006-01-02 15:04:05"
+
+	RUN_STATUS_SUCCEEDED = "succeeded"
+	RUN_STATUS_FAILED    = "failed"
+	RUN_STATUS_CANCELED  = "canceled"
+	RUN_SUMMARY_FILE     = "run_summary.json"
+	MANIFEST_FILE        = "manifest.json"
+
+	JOB_STATUS_QUEUED  = "queued"
+	JOB_STATUS_RUNNING = "running"
+	JOB_FILE           = "job.json"
+
+	SERVER_DEFAULT_ADDRESS        = ":8080"
+	SERVER_DEFAULT_WORKERS        = 2
+	SERVER_DEFAULT_QUEUE_SIZE     = 16
+	SERVER_DEFAULT_JOBS_DIRECTORY = "files/jobs"
+
+	WATCH_DEFAULT_INTERVAL_SECONDS = 30
+	WATCH_DEFAULT_STATE_FILE       = "files/watch/state.json"
+	WATCH_DEFAULT_DEAD_LETTER_FILE = "files/watch/dead_letter.jsonl"
+
+	DIRECTION_IN   = "in"
+	DIRECTION_OUT  = "out"
+	DIRECTION_SELF = "self"
+
+	TRANSACTION_STATUS_SUCCESS = "success"
+	TRANSACTION_STATUS_FAILED  = "failed"
+
+	ALERT_EVENT_TRANSFER            = "transfer"
+	ALERT_EVENT_APPROVAL            = "approval"
+	ALERTS_DEFAULT_DEAD_LETTER_FILE = "files/alerts/dead_letter.jsonl"
+	ALERT_MAIL_SUBJECT_PREFIX       = "[transaction-tracker]"
+
+	CONFIG_DEFAULT_PATH = "config.yml"
+	CONFIG_ENV_PREFIX   = "TRACKER_"        // TRACKER_<KEY PATH> overrides a config key, e.g. TRACKER_ETHERSCAN_API_KEY
+	CONFIG_PROFILE_ENV  = "TRACKER_PROFILE" // Profile used when --profile is not set
+
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+
+	WEBHOOK_DEFAULT_MAX_RETRIES     = 5
+	WEBHOOK_DEFAULT_BACKOFF_SECONDS = 1
+	WEBHOOK_MAX_BACKOFF_SECONDS     = 300 // Retries never wait longer
+	WEBHOOK_DEFAULT_TIMEOUT_SECONDS = 10
+	WEBHOOK_SIGNATURE_HEADER        = "X-Tracker-Signature" // sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+	WEBHOOK_TIMESTAMP_HEADER        = "X-Tracker-Timestamp" // Unix seconds of the delivery attempt
+
+	REPORT_FORMAT_CSV  = "csv"
+	REPORT_FORMAT_JSON = "json"
+
+	DEFAULT_OUTPUT_DIRECTORY  = "files/reports"
+	DEFAULT_FILENAME_TEMPLATE = "{wallet}_{chain}_{type}_report.csv"
+	RUN_TIMESTAMP_LAYOUT      = "20060102T150405Z"
+
+	// Report type of the detailed external report, written together with the external report
+	EXTERNAL_DETAILED_REPORT = "EXTERNAL_DETAILED_REPORT"
+
+	// Streamed reports are flushed to disk every CSV_FLUSH_ROWS rows
+	CSV_FLUSH_ROWS = 1000
 )
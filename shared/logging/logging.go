@@ -0,0 +1,85 @@
+/*
+Package logging creates the structured logger of the tracker and carries it in the context, so
+the fields of a run (run ID, chain, wallet, report type, provider) are added once and attached
+to every line logged below, also from concurrent report tasks.
+*/
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// Field names shared by all log lines
+const (
+	KeyRunID      = "run_id"
+	KeyChain      = "chain"
+	KeyWallet     = "wallet"
+	KeyReportType = "report_type"
+	KeyProvider   = "provider"
+	KeyError      = "error"
+)
+
+type contextKey struct{}
+
+// New creates a text or JSON logger writing to w with the level of the config, defaults to info and text.
+func New(config models.LogConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(config.Format) {
+	case "", constants.LOG_FORMAT_TEXT:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case constants.LOG_FORMAT_JSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format '%s', expected text or json", config.Format)
+}
+
+// ParseLevel parses debug, info, warn or error, an empty level is info.
+func ParseLevel(level string) (slog.Level, error) {
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	parsed := slog.Level(0)
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level '%s', expected debug, info, warn or error", level)
+	}
+	return parsed, nil
+}
+
+// NewContext returns a context carrying the logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of the context, slog.Default() if it carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a context whose logger adds the given fields, e.g. With(ctx, KeyChain, "polygon").
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// NewRunID returns a random ID that tells the lines of concurrent runs apart.
+func NewRunID() string {
+	random := make([]byte, 6)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
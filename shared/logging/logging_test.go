@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  models.LogConfig
+		level   slog.Level
+		wantErr bool
+	}{
+		{name: "Defaults", config: models.LogConfig{}, level: slog.LevelInfo},
+		{name: "Debug JSON", config: models.LogConfig{Level: "debug", Format: "json"}, level: slog.LevelDebug},
+		{name: "Upper Case Level", config: models.LogConfig{Level: "WARN", Format: "TEXT"}, level: slog.LevelWarn},
+		{name: "Unknown Level", config: models.LogConfig{Level: "verbose"}, wantErr: true},
+		{name: "Unknown Format", config: models.LogConfig{Format: "xml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := New(tt.config, &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v; want error %v", err, tt.wantErr)
+			}
+			if err == nil && (!logger.Enabled(context.Background(), tt.level) || logger.Enabled(context.Background(), tt.level-1)) {
+				t.Errorf("New() logger is not enabled from level %s", tt.level)
+			}
+		})
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Errorf("FromContext() without a logger; want slog.Default()")
+	}
+
+	out := bytes.Buffer{}
+	logger, _ := New(models.LogConfig{Format: "json"}, &out)
+	ctx := With(NewContext(context.Background(), logger), KeyRunID, "run-1", KeyChain, "polygon")
+	_ = With(ctx, KeyWallet, "0x1") // Fields of a derived context do not leak into ctx
+	FromContext(With(ctx, KeyReportType, "ERC20_REPORT")).Info("report written")
+
+	entry := map[string]any{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %s: %v", out.String(), err)
+	}
+	if entry[KeyRunID] != "run-1" || entry[KeyChain] != "polygon" || entry[KeyReportType] != "ERC20_REPORT" || entry[KeyWallet] != nil {
+		t.Errorf("log line = %v; want the fields of its context only", entry)
+	}
+}
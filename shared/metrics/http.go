@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Transport counts and times the requests sent through it as requests of one provider on one chain.
+type Transport struct {
+	Base     http.RoundTripper // Transport sending the requests, http.DefaultTransport when nil
+	Provider string
+	Chain    string
+}
+
+// InstrumentClient returns a copy of the client whose requests are recorded as requests of the provider.
+func InstrumentClient(client *http.Client, provider, chain string) *http.Client {
+	instrumented := *client
+	instrumented.Transport = &Transport{Base: client.Transport, Provider: provider, Chain: chain}
+	return &instrumented
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	action := requestAction(req)
+
+	startedAt := time.Now()
+	resp, err := base.RoundTrip(req)
+	ProviderRequestDuration.Observe(time.Since(startedAt).Seconds(), t.Provider, t.Chain, action)
+	ProviderRequests.Inc(t.Provider, t.Chain, action, requestResult(resp, err))
+	return resp, err
+}
+
+/*
+requestAction returns the action of an explorer request (txlist, getLogs, ...) or the method of
+a JSON-RPC request (eth_getLogs, ...). Request bodies are read from a copy.
+*/
+func requestAction(req *http.Request) string {
+	if action := req.URL.Query().Get("action"); action != "" {
+		return action
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			defer body.Close()
+			rpc := struct {
+				Method string `json:"method"`
+			}{}
+			if json.NewDecoder(io.LimitReader(body, 1<<16)).Decode(&rpc) == nil && rpc.Method != "" {
+				return rpc.Method
+			}
+		}
+	}
+	return "unknown"
+}
+
+// requestResult classifies the outcome of a request for the result label
+func requestResult(resp *http.Response, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			return ResultCanceled
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return ResultTimeout
+		}
+		return ResultNetwork
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ResultRateLimited
+	case resp.StatusCode >= 500:
+		return ResultServerError
+	case resp.StatusCode >= 400:
+		return ResultClientError
+	}
+	return ResultOK
+}
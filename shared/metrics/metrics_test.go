@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("test_requests_total", "Requests.", "action", "result")
+	latency := registry.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "action")
+	block := registry.NewGauge("test_block", "Block.", "wallet")
+
+	requests.Inc("txlist", "ok")
+	requests.Add(2, "txlist", "ok")
+	requests.Inc("tokentx", "rate_limited")
+	latency.Observe(0.05, "txlist")
+	latency.Observe(0.5, "txlist")
+	latency.Observe(3, "txlist")
+	block.Set(19000400, `0x1"`)
+
+	out := bytes.Buffer{}
+	if err := registry.Write(&out); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{action="tokentx",result="rate_limited"} 1` + "\n",
+		`test_requests_total{action="txlist",result="ok"} 3` + "\n",
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{action="txlist",le="0.1"} 1` + "\n",
+		`test_latency_seconds_bucket{action="txlist",le="1"} 2` + "\n",
+		`test_latency_seconds_bucket{action="txlist",le="+Inf"} 3` + "\n",
+		`test_latency_seconds_sum{action="txlist"} 3.55` + "\n",
+		`test_latency_seconds_count{action="txlist"} 3` + "\n",
+		`test_block{wallet="0x1\""} 19000400` + "\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Write() output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "textfile", "tracker.prom")
+	if err := registry.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != out.String() {
+		t.Errorf("WriteFile() wrote %q, %v; want the Write() output", data, err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	client := InstrumentClient(server.Client(), "test-provider", "test-chain")
+
+	tests := []struct {
+		name   string
+		status int
+		post   bool
+		action string
+		result string
+	}{
+		{name: "Explorer Action", status: http.StatusOK, action: "txlist", result: ResultOK},
+		{name: "Rate Limited", status: http.StatusTooManyRequests, action: "tokentx", result: ResultRateLimited},
+		{name: "Server Error", status: http.StatusBadGateway, action: "txlistinternal", result: ResultServerError},
+		{name: "Invalid Key", status: http.StatusForbidden, action: "getLogs", result: ResultClientError},
+		{name: "RPC Method", status: http.StatusOK, post: true, action: "eth_getLogs", result: ResultOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			var resp *http.Response
+			var err error
+			if tt.post {
+				resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+tt.action+`","params":[]}`))
+			} else {
+				resp, err = client.Get(server.URL + "?module=account&action=" + tt.action)
+			}
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			resp.Body.Close()
+
+			out := bytes.Buffer{}
+			Default.Write(&out)
+			want := `tracker_provider_requests_total{provider="test-provider",chain="test-chain",action="` + tt.action + `",result="` + tt.result + `"} 1`
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("metrics are missing %s", want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package metrics
+
+// Default is the registry of the metrics below, served by serve and watch on /metrics.
+var Default = NewRegistry()
+
+// Result label of provider requests
+const (
+	ResultOK          = "ok"
+	ResultTimeout     = "timeout"
+	ResultCanceled    = "canceled"
+	ResultNetwork     = "network_error"
+	ResultRateLimited = "rate_limited" // HTTP 429
+	ResultClientError = "client_error" // Other HTTP 4xx, e.g. an invalid API key
+	ResultServerError = "server_error" // HTTP 5xx
+)
+
+var (
+	ProviderRequests = Default.NewCounter("tracker_provider_requests_total",
+		"Provider HTTP requests by provider, chain, action and result.", "provider", "chain", "action", "result")
+	ProviderRequestDuration = Default.NewHistogram("tracker_provider_request_duration_seconds",
+		"Latency of provider HTTP requests by provider, chain and action.", DefaultBuckets, "provider", "chain", "action")
+	RateLimitWait = Default.NewHistogram("tracker_rate_limit_wait_seconds",
+		"Time requests waited for the client side rate limit.", DefaultBuckets)
+	Retries = Default.NewCounter("tracker_retries_total",
+		"Retried requests by component: failover (next provider tried), http (explorer request sent again) or webhook (delivery retried).", "component")
+	ReportRows = Default.NewCounter("tracker_report_rows_total",
+		"Rows written by chain and report type.", "chain", "report_type")
+	ReportDuration = Default.NewHistogram("tracker_report_duration_seconds",
+		"Duration of report generation by chain, report type and status.", []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}, "chain", "report_type", "status")
+	LastSyncedBlock = Default.NewGauge("tracker_last_synced_block",
+		"Newest block handled by watch by chain, wallet and report type.", "chain", "wallet", "report_type")
+)
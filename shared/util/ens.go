@@ -0,0 +1,35 @@
+package util
+
+import (
+	"strings"
+)
+
+/*
+NameHash returns the ENS namehash of a name, the node the registry and resolvers are keyed by.
+Names are lower cased, the full UTS-46 normalization of ENS is not applied.
+*/
+func NameHash(name string) [32]byte {
+	var node [32]byte
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		copy(node[:], Keccak256(node[:], Keccak256([]byte(labels[i]))))
+	}
+	return node
+}
+
+// IsENSName reports whether the string looks like an ENS name, e.g. treasury.eth, the name is not resolved.
+func IsENSName(name string) bool {
+	if IsHexAddress(name) || !strings.Contains(name, ".") || strings.ContainsAny(name, " \t/:") {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,35 +1,203 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
 )
 
-func TriggerHttpRequest(requestMethod, requestUrl, tag string, client *http.Client) (string, error) {
+func TriggerHttpRequest(ctx context.Context, requestMethod, requestUrl, tag string, client *http.Client) (string, error) {
+
+	body, err := OpenHttpStream(ctx, requestMethod, requestUrl, client)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	bodyBytes, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read etherscan response body: %w", RedactError(err))
+	}
+
+	return string(bodyBytes), nil
+}
+
+/*
+OpenHttpStream sends the request and returns the response body without reading it, so large
+responses can be decoded while they are received. The caller must close the body.
+*/
+func OpenHttpStream(ctx context.Context, requestMethod, requestUrl string, client *http.Client) (io.ReadCloser, error) {
 
-	req, err := http.NewRequest(requestMethod, requestUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, requestMethod, requestUrl, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create etherscan request: %w", err)
+		return nil, fmt.Errorf("failed to create etherscan request: %w", RedactError(err))
 	}
 
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute etherscan request: %w", err)
+		return nil, fmt.Errorf("failed to execute etherscan request: %w", RedactError(err))
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for context even on error
-		return "", fmt.Errorf("etherscan API request failed with status %s: %s", resp.Status, string(bodyBytes))
+		return nil, fmt.Errorf("etherscan API request failed with status %s: %s", resp.Status, Redact(string(bodyBytes)))
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	return resp.Body, nil
+}
+
+/*
+Remove the given query parameters (e.g. the API key) from a URL. Used to build cache keys
+that do not contain secrets. Remaining parameters are sorted so the result is stable.
+*/
+func StripQueryParams(requestUrl string, params ...string) string {
+	parsed, err := url.Parse(requestUrl)
 	if err != nil {
-		return "", fmt.Errorf("failed to read etherscan response body: %w", err)
+		return requestUrl
 	}
 
-	return string(bodyBytes), nil
+	query := parsed.Query()
+	for _, param := range params {
+		query.Del(param)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+/*
+RateLimitTransport spaces out the requests sent through it to at most RequestsPerSecond, e.g. to
+stay below the rate limit of an explorer API key. Waiting stops when the request is canceled.
+*/
+type RateLimitTransport struct {
+	Base     http.RoundTripper // Transport sending the requests, http.DefaultTransport when nil
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // Earliest time the next request may be sent
+}
+
+// NewRateLimitTransport limits the requests of the base transport, a non-positive rate does not limit.
+func NewRateLimitTransport(base http.RoundTripper, requestsPerSecond float64) *RateLimitTransport {
+	transport := &RateLimitTransport{Base: base}
+	if requestsPerSecond > 0 {
+		transport.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return transport
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.reserve()
+	if t.interval > 0 {
+		metrics.RateLimitWait.Observe(wait.Seconds())
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// reserve returns how long the request has to wait for its slot
+func (t *RateLimitTransport) reserve() time.Duration {
+	if t.interval <= 0 {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	return wait
+}
+
+/*
+RetryTransport retries requests that failed with a network error, a 429 or a 5xx response up to
+Retries times, waiting Backoff before the first retry and twice as long before every further one.
+Only requests whose body can be sent again are retried. Waiting stops when the request is canceled.
+*/
+type RetryTransport struct {
+	Base    http.RoundTripper // Transport sending the requests, http.DefaultTransport when nil
+	Retries int
+	Backoff time.Duration
+}
+
+// NewRetryTransport retries the requests of the base transport, non-positive retries send every request once.
+func NewRetryTransport(base http.RoundTripper, retries int) *RetryTransport {
+	return &RetryTransport{
+		Base:    base,
+		Retries: retries,
+		Backoff: constants.PROVIDER_RETRY_BACKOFF_MILLISECONDS * time.Millisecond,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	retryable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	backoff := t.Backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := base.RoundTrip(req)
+		if !retryable || attempt >= t.Retries || !shouldRetry(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+
+		metrics.Retries.Inc("http")
+		timer := time.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, constants.PROVIDER_RETRY_MAX_BACKOFF_SECONDS*time.Second)
+	}
+}
+
+// shouldRetry reports whether a failed attempt may succeed when it is sent again
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
 }
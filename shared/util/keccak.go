@@ -0,0 +1,15 @@
+package util
+
+import "golang.org/x/crypto/sha3"
+
+/*
+Keccak256 returns the legacy Keccak-256 hash used by Ethereum (this is NOT the NIST SHA3-256,
+the padding differs). It is used for function selectors, event topics and address checksums.
+*/
+func Keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, chunk := range data {
+		hash.Write(chunk)
+	}
+	return hash.Sum(nil)
+}
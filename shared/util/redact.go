@@ -0,0 +1,96 @@
+package util
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const RedactedValue = "REDACTED"
+
+// Query parameters which hold credentials, their values never show up in logs, errors or cache keys
+var SecretQueryParams = []string{"apikey", "api_key", "key", "token", "access_token"}
+
+// Secrets are only registered when they are long enough to not redact unrelated text
+const minSecretLength = 6
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// RegisterSecret makes Redact replace every occurrence of the secret, e.g. API keys loaded from the config.
+func RegisterSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Redact replaces the registered secrets in the text.
+func Redact(text string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, RedactedValue)
+	}
+	return text
+}
+
+/*
+RedactURL masks the credentials of a URL before it is logged: the values of the secret query
+parameters, the password of the user info and any registered secret, e.g. a key in the path.
+*/
+func RedactURL(requestUrl string) string {
+	parsed, err := url.Parse(requestUrl)
+	if err != nil {
+		return Redact(requestUrl)
+	}
+
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), RedactedValue)
+	}
+	query := parsed.Query()
+	for _, param := range SecretQueryParams {
+		if query.Has(param) {
+			query.Set(param, RedactedValue)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return Redact(parsed.String())
+}
+
+// redactedError hides secrets in the message of the wrapped error, errors.Is and errors.As still see the original error
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError masks secrets in an error, URLs of *url.Error (returned by the HTTP client) are redacted as well.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return &redactedError{err: err}
+}
@@ -0,0 +1,239 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+/*
+CSVWriter writes rows of T one at a time, the columns are the fields with a `csv` tag.
+Columns tagged `csv:"Header,optional"` are only written when their header is passed to NewCSVWriter.
+Rows are flushed to a temporary file every flushEvery rows so memory stays flat for any report size.
+Close renames the temporary file to the report, so a crash never leaves a corrupted report behind
+and the previous report is only replaced once the new one is complete.
+*/
+type CSVWriter[T any] struct {
+	filePath     string
+	file         *os.File // temporary file in the directory of the report
+	hash         hash.Hash
+	writer       *csv.Writer
+	fieldIndices []int
+	flushEvery   int
+	rows         int
+}
+
+// NewCSVWriter creates the temporary file and writes the header row, flushEvery <= 0 flushes only on Close.
+func NewCSVWriter[T any](filePath string, flushEvery int, optionalColumns ...string) (*CSVWriter[T], error) {
+	headers, fieldIndices, err := csvColumns(reflect.TypeOf((*T)(nil)).Elem(), optionalColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		slog.Warn("no fields with csv tags found, the CSV will be empty", "file", filePath)
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", filePath, err)
+	}
+	// Temporary files are private, reports are readable like files created with os.Create
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to set permissions of %s: %w", filePath, err)
+	}
+
+	// The checksum is computed while writing so the file is not read again
+	checksum := sha256.New()
+	w := &CSVWriter[T]{
+		filePath:     filePath,
+		file:         file,
+		hash:         checksum,
+		writer:       csv.NewWriter(io.MultiWriter(file, checksum)),
+		fieldIndices: fieldIndices,
+		flushEvery:   flushEvery,
+	}
+	if len(headers) > 0 {
+		if err := w.writer.Write(headers); err != nil {
+			w.Abort()
+			return nil, fmt.Errorf("failed to write CSV header to %s: %w", filePath, err)
+		}
+	}
+	return w, nil
+}
+
+// Write appends a row for the item.
+func (w *CSVWriter[T]) Write(item T) error {
+	if len(w.fieldIndices) == 0 {
+		return fmt.Errorf("data provided but no fields found with 'csv' tag")
+	}
+
+	itemValue := reflect.ValueOf(item)
+	record := make([]string, 0, len(w.fieldIndices))
+	for _, index := range w.fieldIndices {
+		record = append(record, valueToString(itemValue.Field(index)))
+	}
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record %+v to CSV file %s: %w", record, w.filePath, err)
+	}
+
+	w.rows++
+	if w.flushEvery > 0 && w.rows%w.flushEvery == 0 {
+		w.writer.Flush()
+		if err := w.writer.Error(); err != nil {
+			return fmt.Errorf("error occurred during CSV flushing for %s: %w", w.filePath, err)
+		}
+	}
+	return nil
+}
+
+// Rows returns the number of data rows written so far.
+func (w *CSVWriter[T]) Rows() int {
+	return w.rows
+}
+
+// Checksum returns the hex encoded SHA-256 of the rows written so far, of the whole file after Close.
+func (w *CSVWriter[T]) Checksum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
+
+// Close flushes the remaining rows, syncs the file to disk and atomically replaces the report with it.
+func (w *CSVWriter[T]) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.Abort()
+		return fmt.Errorf("error occurred during CSV writing/flushing for %s: %w", w.filePath, err)
+	}
+	if err := w.file.Sync(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to sync CSV file %s: %w", w.filePath, err)
+	}
+	if err := w.file.Close(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to close CSV file %s: %w", w.filePath, err)
+	}
+	if err := os.Rename(w.file.Name(), w.filePath); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to replace CSV file %s: %w", w.filePath, err)
+	}
+	return nil
+}
+
+// CloseAs closes the writer like Close but replaces the report at filePath, in the same directory.
+func (w *CSVWriter[T]) CloseAs(filePath string) error {
+	w.filePath = filePath
+	return w.Close()
+}
+
+// Abort discards the rows written so far, the previous report (if any) is kept.
+func (w *CSVWriter[T]) Abort() error {
+	w.file.Close()
+	if err := os.Remove(w.file.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove incomplete CSV file %s: %w", w.file.Name(), err)
+	}
+	return nil
+}
+
+// csvColumns returns the headers and field indices of the fields with a `csv` tag, optional columns only when listed
+func csvColumns(dataType reflect.Type, optionalColumns []string) ([]string, []int, error) {
+	if dataType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("input data must be a slice of structs, got %s", dataType.Kind())
+	}
+
+	var headers []string
+	var fieldIndices []int
+	for i := 0; i < dataType.NumField(); i++ {
+		tag, option, _ := strings.Cut(dataType.Field(i).Tag.Get("csv"), ",")
+		if option == "optional" && !slices.Contains(optionalColumns, tag) {
+			continue
+		}
+		// Only include fields that have the 'csv' tag and it's not "-"
+		if tag != "" && tag != "-" {
+			headers = append(headers, tag)
+			fieldIndices = append(fieldIndices, i)
+		}
+	}
+	return headers, fieldIndices, nil
+}
+
+/*
+DecodeJSONArray decodes the JSON array read by the decoder one element at a time and calls fn for
+each element, so the array is never held in memory. A null value is treated as an empty array.
+*/
+func DecodeJSONArray[T any](dec *json.Decoder, fn func(T) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error reading JSON array: %w", err)
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", token)
+	}
+
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("error decoding JSON array element: %w", err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing bracket
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("error reading JSON array: %w", err)
+	}
+	return nil
+}
+
+/*
+WriteFileAtomic writes data like os.WriteFile through a synced temporary file in the same directory
+that is renamed to filePath, a crash leaves either the previous or the new file but never a partial one.
+*/
+func WriteFileAtomic(filePath string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+	}
+	cleanup := func(err error) error {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Chmod(perm); err != nil {
+		return cleanup(fmt.Errorf("failed to set permissions of %s: %w", filePath, err))
+	}
+	if _, err := file.Write(data); err != nil {
+		return cleanup(fmt.Errorf("failed to write %s: %w", filePath, err))
+	}
+	if err := file.Sync(); err != nil {
+		return cleanup(fmt.Errorf("failed to sync %s: %w", filePath, err))
+	}
+	if err := file.Close(); err != nil {
+		return cleanup(fmt.Errorf("failed to close %s: %w", filePath, err))
+	}
+	if err := os.Rename(file.Name(), filePath); err != nil {
+		return cleanup(fmt.Errorf("failed to replace %s: %w", filePath, err))
+	}
+	return nil
+}
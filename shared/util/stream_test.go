@@ -0,0 +1,133 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type streamRow struct {
+	Name  string `csv:"Name"`
+	Value int    `csv:"Value"`
+	Note  string
+	Extra string `csv:"Extra,optional"`
+}
+
+func TestDecodeJSONArray(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []string
+		expectErr bool
+	}{
+		{name: "Array", input: `[{"Name":"a"},{"Name":"b"}]`, want: []string{"a", "b"}},
+		{name: "Empty Array", input: `[]`, want: nil},
+		{name: "Null", input: `null`, want: nil},
+		{name: "String Result", input: `"Max rate limit reached"`, expectErr: true},
+		{name: "Truncated", input: `[{"Name":"a"},{"Na`, want: []string{"a"}, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			err := DecodeJSONArray(json.NewDecoder(strings.NewReader(tc.input)), func(row streamRow) error {
+				got = append(got, row.Name)
+				return nil
+			})
+
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("DecodeJSONArray(%q) error = %v; expectErr %v", tc.input, err, tc.expectErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("DecodeJSONArray(%q) elements = %v; want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "reports", "rows.csv")
+
+	writer, err := NewCSVWriter[streamRow](filePath, 2)
+	if err != nil {
+		t.Fatalf("NewCSVWriter unexpected error: %v", err)
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		if err := writer.Write(streamRow{Name: name, Value: i, Note: "not written"}); err != nil {
+			t.Fatalf("Write unexpected error: %v", err)
+		}
+	}
+
+	// Rows are written to a temporary file, the report only appears on Close
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("report exists before Close: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filePath)
+	if want := "Name,Value\na,0\nb,1\nc,2\n"; string(data) != want || writer.Rows() != 3 {
+		t.Errorf("file = %q with %d rows; want %q with 3 rows", data, writer.Rows(), want)
+	}
+	if sum := sha256.Sum256(data); writer.Checksum() != hex.EncodeToString(sum[:]) {
+		t.Errorf("Checksum() = %s; want the SHA-256 of the file", writer.Checksum())
+	}
+
+	aborted, err := NewCSVWriter[streamRow](filePath, 0)
+	if err != nil {
+		t.Fatalf("NewCSVWriter unexpected error: %v", err)
+	}
+	aborted.Write(streamRow{Name: "partial"})
+	if err := aborted.Abort(); err != nil {
+		t.Fatalf("Abort unexpected error: %v", err)
+	}
+
+	// The previous report is kept and no temporary file is left behind
+	if kept, _ := os.ReadFile(filePath); string(kept) != string(data) {
+		t.Errorf("report after Abort = %q; want the previous report %q", kept, data)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(filePath)); len(files) != 1 {
+		t.Errorf("files after Abort = %d; want only the report", len(files))
+	}
+
+	// Optional columns are only written when requested
+	optional, err := NewCSVWriter[streamRow](filePath, 0, "Extra")
+	if err != nil {
+		t.Fatalf("NewCSVWriter unexpected error: %v", err)
+	}
+	optional.Write(streamRow{Name: "a", Extra: "x"})
+	if err := optional.Close(); err != nil {
+		t.Fatalf("Close unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "Name,Value,Extra\na,0,x\n" {
+		t.Errorf("file with optional column = %q", data)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(filePath, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(filePath, []byte(`{"files":[]}`), 0o644); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil || string(content) != `{"files":[]}` {
+		t.Errorf("file = %q, %v; want the new content", content, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries; want the temporary file renamed", len(entries))
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "manifest.json"), nil, 0o644); err == nil {
+		t.Error("WriteFileAtomic() into a missing directory succeeded")
+	}
+}
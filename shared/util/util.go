@@ -1,12 +1,14 @@
 package util
 
 import (
-	"encoding/csv"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"math/big"
 	"os"
-	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coin-tracker/transaction-tracker/shared/constants"
@@ -25,6 +27,80 @@ func StringToInt(inp string) (int64, error) {
 
 }
 
+/*
+Convert a 0x prefixed hex string (as used by the logs and proxy endpoints) to a decimal string
+*/
+func HexToDecimalString(inp string) (string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(inp, "0x"), "0X")
+	if trimmed == "" {
+		return "0", nil
+	}
+
+	res, ok := new(big.Int).SetString(trimmed, 16)
+	if !ok {
+		return "", fmt.Errorf("invalid hex string format '%s'", inp)
+	}
+	return res.String(), nil
+}
+
+/*
+Convert an integer amount in the smallest unit (e.g. wei) to a decimal string in whole units,
+e.g. FormatUnits("1500000000000000000", 18) -> "1.5"
+*/
+func FormatUnits(amount string, decimals int) (string, error) {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount string format '%s'", amount)
+	}
+	if decimals <= 0 {
+		return value.String(), nil
+	}
+
+	negative := value.Sign() < 0
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole := digits[:len(digits)-decimals]
+	fraction := strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	res := whole
+	if fraction != "" {
+		res += "." + fraction
+	}
+	if negative {
+		res = "-" + res
+	}
+	return res, nil
+}
+
+/*
+Calculate the fee of a transaction (gasUsed * gasPrice) in whole units of the native currency
+*/
+func CalculateGasFee(gasUsed, gasPrice string, decimals int) (string, error) {
+	used, ok := new(big.Int).SetString(gasUsed, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid gas used string format '%s'", gasUsed)
+	}
+	price, ok := new(big.Int).SetString(gasPrice, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid gas price string format '%s'", gasPrice)
+	}
+	return FormatUnits(new(big.Int).Mul(used, price).String(), decimals)
+}
+
+/*
+Left pad an address to a 32 byte topic, the way indexed address arguments are stored in logs
+*/
+func AddressToTopic(address string) string {
+	trimmed := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X"))
+	if len(trimmed) > 64 {
+		return "0x" + trimmed
+	}
+	return "0x" + strings.Repeat("0", 64-len(trimmed)) + trimmed
+}
+
 /*
 Convert a unix timestamp string to a formatted string
 */
@@ -84,114 +160,24 @@ The type T can be absolutely any type (any constraint).
 */
 func WriteCSV[T any](filePath string, data []T) error {
 	if len(data) == 0 {
-		fmt.Printf("Info: No data provided to WriteCSV for file: %s. Creating empty file with headers (if any).\n", filePath)
-		// Allow creating an empty file with only headers if needed, or return an error:
-		// return fmt.Errorf("no data provided to write to CSV")
-	}
-
-	// --- 1. Reflect to get headers and field indices ---
-	var headers []string
-	var fieldIndices []int
-
-	// Need at least one element to reflect type, or handle empty data case separately
-	if len(data) > 0 {
-		dataType := reflect.TypeOf(data[0])
-		if dataType.Kind() != reflect.Struct {
-			return fmt.Errorf("input data must be a slice of structs, got %s", dataType.Kind())
-		}
-
-		for i := 0; i < dataType.NumField(); i++ {
-			field := dataType.Field(i)
-			tag := field.Tag.Get("csv") // Get the value associated with the "csv" key
-
-			// Only include fields that have the 'csv' tag and it's not "-"
-			if tag != "" && tag != "-" {
-				headers = append(headers, tag)         // Add header name from tag
-				fieldIndices = append(fieldIndices, i) // Add index of the field
-			}
-		}
-	} else {
-		// Attempt to get type from slice definition even if empty, requires Go 1.18+ generics knowledge
-		sliceType := reflect.TypeOf(data)
-		if sliceType.Kind() == reflect.Slice {
-			elemType := sliceType.Elem()
-			if elemType.Kind() == reflect.Struct {
-				for i := 0; i < elemType.NumField(); i++ {
-					field := elemType.Field(i)
-					tag := field.Tag.Get("csv")
-					if tag != "" && tag != "-" {
-						headers = append(headers, tag)
-						fieldIndices = append(fieldIndices, i)
-					}
-				}
-			} else {
-				return fmt.Errorf("input data slice element is not a struct, got %s", elemType.Kind())
-			}
-		} else {
-			return fmt.Errorf("input data is not a slice")
-		}
-
-		if len(headers) == 0 {
-			fmt.Printf("Warning: No fields with 'csv' tags found in struct type for file %s. CSV will be empty.\n", filePath)
-			// Optionally return an error here if headers are mandatory
-		}
-	}
-
-	// --- 2. Ensure directory exists ---
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil { // os.ModePerm (0777) might be too permissive, adjust if needed
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		slog.Info("no data provided to WriteCSV, creating a file with headers only", "file", filePath)
 	}
 
-	// --- 3. Create and open file ---
-	file, err := os.Create(filePath)
+	writer, err := NewCSVWriter[T](filePath, 0)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+		return err
 	}
-	defer file.Close() // Ensure file is closed
-
-	// --- 4. Create CSV writer ---
-	writer := csv.NewWriter(file)
-	defer writer.Flush() // Ensure all buffered data is written to the file
-
-	// --- 5. Write Header Row ---
-	if len(headers) > 0 {
-		if err := writer.Write(headers); err != nil {
-			return fmt.Errorf("failed to write CSV header to %s: %w", filePath, err)
-		}
-	} else if len(data) > 0 {
-		// We have data but no headers, which is weird.
-		return fmt.Errorf("data provided but no fields found with 'csv' tag")
-	}
-
-	// --- 6. Write Data Rows ---
 	for _, item := range data {
-		itemValue := reflect.ValueOf(item) // Get the value representation of the struct
-		if itemValue.Kind() != reflect.Struct {
-			// This check might be redundant with generics but good for safety
-			return fmt.Errorf("encountered non-struct item in data slice")
-		}
-
-		var record []string
-		for _, index := range fieldIndices {
-			fieldValue := itemValue.Field(index) // Get the value of the field by index
-			// Convert field value to string
-			record = append(record, valueToString(fieldValue))
-		}
-
-		// Write the record to CSV
-		if err := writer.Write(record); err != nil {
-			// Log the problematic record?
-			return fmt.Errorf("failed to write record %+v to CSV file %s: %w", record, filePath, err)
+		if err := writer.Write(item); err != nil {
+			writer.Abort()
+			return err
 		}
 	}
-
-	// --- 7. Check for writer errors after flush ---
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("error occurred during CSV writing/flushing for %s: %w", filePath, err)
+	if err := writer.Close(); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully wrote %d data rows to CSV file: %s\n", len(data), filePath)
+	slog.Info("wrote CSV file", "rows", len(data), "file", filePath)
 	return nil // Success
 }
 
@@ -234,3 +220,45 @@ func valueToString(v reflect.Value) string {
 		return "" // Return empty for invalid/zero values
 	}
 }
+
+// IsHexAddress reports whether the string is a 0x prefixed 20 byte hex address, the checksum is not verified.
+func IsHexAddress(address string) bool {
+	if len(address) != 42 || !(strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X")) {
+		return false
+	}
+	_, err := hex.DecodeString(address[2:])
+	return err == nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed case form of a hex address, other strings are returned unchanged.
+func ChecksumAddress(address string) string {
+	if !IsHexAddress(address) {
+		return address
+	}
+	lower := strings.ToLower(address[2:])
+	hash := hex.EncodeToString(Keccak256([]byte(lower)))
+
+	checksummed := []byte("0x" + lower)
+	for i := range lower {
+		// Letters are upper cased when the nibble of the hash at their position is 8 or higher
+		if lower[i] >= 'a' && hash[i] >= '8' {
+			checksummed[i+2] = lower[i] - 'a' + 'A'
+		}
+	}
+	return string(checksummed)
+}
+
+/*
+IsChecksumAddress reports whether a hex address passes the EIP-55 check. All lower or all
+upper case addresses carry no checksum and are accepted, mixed case must match exactly.
+*/
+func IsChecksumAddress(address string) bool {
+	if !IsHexAddress(address) {
+		return false
+	}
+	digits := address[2:]
+	if digits == strings.ToLower(digits) || digits == strings.ToUpper(digits) {
+		return true
+	}
+	return address[:2] == "0x" && address == ChecksumAddress(address)
+}
@@ -0,0 +1,190 @@
+/*
+Package webhook delivers signed JSON payloads. Every attempt carries the Unix time of the attempt
+in X-Tracker-Timestamp and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret in
+X-Tracker-Signature as "sha256=<hex>". Failed deliveries are retried with exponential backoff,
+payloads that still could not be delivered are appended to a dead-letter file.
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// Sender posts payloads to a webhook URL.
+type Sender struct {
+	URL            string
+	Secret         string
+	MaxRetries     int
+	Backoff        time.Duration // Delay before the first retry, doubled for every further retry
+	MaxBackoff     time.Duration
+	DeadLetterFile string // Payloads are dropped after the last retry when empty
+	Client         *http.Client
+
+	mu sync.Mutex // Serializes writes to the dead-letter file
+}
+
+// NewSender creates a Sender from the webhook config, unset values fall back to the defaults.
+func NewSender(config models.WebhookConfig, deadLetterFile string) *Sender {
+	maxRetries := config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = constants.WEBHOOK_DEFAULT_MAX_RETRIES
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	backoff := config.BackoffSeconds
+	if backoff <= 0 {
+		backoff = constants.WEBHOOK_DEFAULT_BACKOFF_SECONDS
+	}
+	timeout := config.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = constants.WEBHOOK_DEFAULT_TIMEOUT_SECONDS
+	}
+	return &Sender{
+		URL:            config.URL,
+		Secret:         config.Secret,
+		MaxRetries:     maxRetries,
+		Backoff:        time.Duration(backoff) * time.Second,
+		MaxBackoff:     constants.WEBHOOK_MAX_BACKOFF_SECONDS * time.Second,
+		DeadLetterFile: deadLetterFile,
+		Client:         &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+// Sign returns the signature header value of a payload, receivers compare it with hmac.Equal.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+Send delivers the payload as JSON. Network errors, 429 and 5xx responses are retried, other
+responses are final. A payload that could not be delivered is written to the dead-letter file
+and Send returns nil, an error means the payload was neither delivered nor dead-lettered,
+e.g. because the context was canceled.
+*/
+func (s *Sender) Send(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	backoff := s.Backoff
+	attempts := 0
+	for {
+		attempts++
+		retry, err := s.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !retry || attempts > s.MaxRetries {
+			return s.deadLetter(ctx, body, attempts, err)
+		}
+
+		logging.FromContext(ctx).Warn("webhook delivery failed, retrying", "attempt", attempts, "backoff", backoff, logging.KeyError, err)
+		metrics.Retries.Inc("webhook")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if s.MaxBackoff > 0 && backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (s *Sender) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, util.RedactError(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.WEBHOOK_TIMESTAMP_HEADER, timestamp)
+	if s.Secret != "" {
+		req.Header.Set(constants.WEBHOOK_SIGNATURE_HEADER, Sign(s.Secret, timestamp, body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return true, util.RedactError(err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+}
+
+func (s *Sender) deadLetter(ctx context.Context, body []byte, attempts int, sendErr error) error {
+	logger := logging.FromContext(ctx)
+	if s.DeadLetterFile == "" {
+		logger.Error("webhook delivery failed, payload dropped", "attempts", attempts, logging.KeyError, sendErr)
+		return nil
+	}
+
+	entry, err := json.Marshal(models.DeadLetter{
+		URL:      util.RedactURL(s.URL),
+		Payload:  body,
+		Attempts: attempts,
+		Error:    sendErr.Error(),
+		FailedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.DeadLetterFile), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	file, err := os.OpenFile(s.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	if _, err := file.Write(append(entry, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	logger.Error("webhook delivery failed, payload dead-lettered", "attempts", attempts, "file", s.DeadLetterFile, logging.KeyError, sendErr)
+	return nil
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []int // Response of every attempt, the last one repeats
+		wantAttempts   int32
+		wantDeadLetter bool
+	}{
+		{name: "Delivered", statuses: []int{http.StatusOK}, wantAttempts: 1},
+		{name: "Retried Server Error", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusNoContent}, wantAttempts: 3},
+		{name: "Retries Exhausted", statuses: []int{http.StatusServiceUnavailable}, wantAttempts: 3, wantDeadLetter: true},
+		{name: "Rejected Payload", statuses: []int{http.StatusBadRequest}, wantAttempts: 1, wantDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				body, _ := io.ReadAll(r.Body)
+				timestamp := r.Header.Get(constants.WEBHOOK_TIMESTAMP_HEADER)
+				if got := r.Header.Get(constants.WEBHOOK_SIGNATURE_HEADER); got != Sign("test-secret", timestamp, body) {
+					t.Errorf("signature = %q; want the HMAC of the timestamp and body", got)
+				}
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			deadLetterFile := filepath.Join(t.TempDir(), "dead_letter.jsonl")
+			sender := NewSender(models.WebhookConfig{URL: server.URL, Secret: "test-secret", MaxRetries: 2}, deadLetterFile)
+			sender.Backoff = time.Millisecond
+
+			if err := sender.Send(context.Background(), map[string]string{"id": "1"}); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if attempts.Load() != tt.wantAttempts {
+				t.Errorf("attempts = %d; want %d", attempts.Load(), tt.wantAttempts)
+			}
+
+			file, err := os.Open(deadLetterFile)
+			if !tt.wantDeadLetter {
+				if err == nil {
+					file.Close()
+					t.Error("dead-letter file written for a delivered payload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dead-letter file not written: %v", err)
+			}
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			if !scanner.Scan() {
+				t.Fatal("dead-letter file is empty")
+			}
+			entry := models.DeadLetter{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid dead-letter entry: %v", err)
+			}
+			if entry.Attempts != int(tt.wantAttempts) || string(entry.Payload) != `{"id":"1"}` {
+				t.Errorf("dead-letter entry = %d attempts, payload %s; want %d attempts", entry.Attempts, entry.Payload, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSendCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "dead_letter.jsonl")
+	sender := NewSender(models.WebhookConfig{URL: server.URL}, deadLetterFile)
+	sender.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sender.Send(ctx, map[string]string{"id": "1"}); err == nil {
+		t.Error("Send() succeeded; want the error of the canceled context")
+	}
+	if _, err := os.Stat(deadLetterFile); err == nil {
+		t.Error("canceled payload was dead-lettered; want it to be sent again later")
+	}
+}
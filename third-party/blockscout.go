@@ -0,0 +1,33 @@
+package thirdparty
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+)
+
+/*
+BlockscoutProvider talks to a Blockscout instance. Blockscout serves an Etherscan compatible
+API (module/action query parameters, same response format), so the Etherscan implementation is
+reused with the instance URL. Instances are chain specific, the chainid parameter is ignored, so
+BASE_URL is only a fallback for runs on a single chain.
+*/
+type BlockscoutProvider struct {
+	*EtherscanProvider
+}
+
+// NewBlockscoutProvider creates a new Blockscout provider instance for the given chain.
+func NewBlockscoutProvider(config models.ThirdPartyApiConfig, chain models.Chain, client *http.Client) (*BlockscoutProvider, error) {
+	baseURL := config.BaseURLs[chain.Name]
+	if baseURL == "" {
+		baseURL = config.BaseURL
+	}
+	if baseURL == "" {
+		// Blockscout instances have different URLs, MUST be provided in config
+		return nil, fmt.Errorf("blockscout base URL is required for chain %s", chain.Name)
+	}
+	config.BaseURL = baseURL
+
+	return &BlockscoutProvider{EtherscanProvider: NewEtherscanProvider(config, chain, client)}, nil
+}
@@ -0,0 +1,182 @@
+package thirdparty
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	"gopkg.in/yaml.v3"
+)
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// NameResolver resolves ENS names to addresses and addresses to their primary ENS name.
+type NameResolver interface {
+	// ResolveName returns the EIP-55 address of the name, an error when the name does not resolve
+	ResolveName(ctx context.Context, name string) (string, error)
+	// LookupAddress returns the primary name of the address, an empty name when it has none
+	LookupAddress(ctx context.Context, address string) (string, error)
+}
+
+/*
+NewNameResolver creates the resolver of the ENS config: rpc resolves names through the ENS registry
+on the RPC endpoint of ENS.CHAIN, file reads them from ENS.FILE. Without a resolver nil is returned.
+*/
+func NewNameResolver(config models.Config) (NameResolver, error) {
+	switch strings.ToLower(config.Ens.Resolver) {
+	case "":
+		return nil, nil
+
+	case constants.ENS_RESOLVER_FILE:
+		return NewMappingResolver(config.Ens.File)
+
+	case constants.ENS_RESOLVER_RPC:
+		chainName := config.Ens.Chain
+		if chainName == "" {
+			chainName = constants.CHAIN_ETHEREUM
+		}
+		chain, err := chains.Lookup(chainName)
+		if err != nil {
+			return nil, err
+		}
+		client := metrics.InstrumentClient(&http.Client{Timeout: 15 * time.Second}, constants.PROVIDER_RPC, chain.Name)
+		provider, err := NewRPCProvider(config.Rpc, chain, client)
+		if err != nil {
+			return nil, fmt.Errorf("ENS resolver: %w", err)
+		}
+		return provider, nil
+	}
+	return nil, fmt.Errorf("unknown ENS resolver '%s', expected rpc or file", config.Ens.Resolver)
+}
+
+// ResolveName reads the resolver of the name from the ENS registry and the address from the resolver.
+func (p *RPCProvider) ResolveName(ctx context.Context, name string) (string, error) {
+	node := util.NameHash(name)
+	resolver, err := p.ensResolver(ctx, node)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ENS name %s: %w", name, err)
+	}
+	if resolver == "" {
+		return "", fmt.Errorf("ENS name %s has no resolver", name)
+	}
+
+	res, err := p.ethCall(ctx, resolver, constants.ENS_ADDR_SELECTOR+hex.EncodeToString(node[:]))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ENS name %s: %w", name, err)
+	}
+	address := topicToAddress(res)
+	if !util.IsHexAddress(address) || address == zeroAddress {
+		return "", fmt.Errorf("ENS name %s does not resolve to an address", name)
+	}
+	return util.ChecksumAddress(address), nil
+}
+
+/*
+LookupAddress reads the name of the reverse record <address>.addr.reverse. Anyone can point a
+reverse record at any name, the name only counts when it resolves back to the address.
+*/
+func (p *RPCProvider) LookupAddress(ctx context.Context, address string) (string, error) {
+	node := util.NameHash(strings.ToLower(strings.TrimPrefix(address, "0x")) + constants.ENS_REVERSE_SUFFIX)
+	resolver, err := p.ensResolver(ctx, node)
+	if err != nil || resolver == "" {
+		return "", err
+	}
+
+	res, err := p.ethCall(ctx, resolver, constants.ENS_NAME_SELECTOR+hex.EncodeToString(node[:]))
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(res, "0x"))
+	if err != nil || len(data) == 0 {
+		return "", nil
+	}
+	decoded, err := abi.DecodeArguments([]abi.Argument{{Type: "string"}}, data)
+	if err != nil || decoded[0].Value == "" {
+		return "", nil
+	}
+
+	name := decoded[0].Value
+	if forward, err := p.ResolveName(ctx, name); err != nil || !strings.EqualFold(forward, address) {
+		return "", nil
+	}
+	return name, nil
+}
+
+// ensResolver returns the resolver the registry has set for the node, empty when there is none
+func (p *RPCProvider) ensResolver(ctx context.Context, node [32]byte) (string, error) {
+	res, err := p.ethCall(ctx, constants.ENS_REGISTRY_ADDRESS, constants.ENS_RESOLVER_SELECTOR+hex.EncodeToString(node[:]))
+	if err != nil {
+		return "", err
+	}
+	resolver := topicToAddress(res)
+	if !util.IsHexAddress(resolver) || resolver == zeroAddress {
+		return "", nil
+	}
+	return resolver, nil
+}
+
+// MappingResolver resolves ENS names from a local YAML file mapping names to addresses, e.g. for offline runs.
+type MappingResolver struct {
+	addresses map[string]string // name -> address
+	names     map[string]string // lower case address -> name
+}
+
+// NewMappingResolver reads a YAML file of `name: address` entries, e.g. `treasury.eth: "0x..."`.
+func NewMappingResolver(path string) (*MappingResolver, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the file ENS resolver requires ENS.FILE")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ENS names %s: %w", path, err)
+	}
+	entries := map[string]string{}
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error unmarshalling ENS names %s: %w", path, err)
+	}
+
+	resolver := &MappingResolver{addresses: map[string]string{}, names: map[string]string{}}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	// Several names may point to the same address, the first name in order is its primary name
+	sort.Strings(names)
+	for _, name := range names {
+		address := strings.TrimSpace(entries[name])
+		if !util.IsHexAddress(address) {
+			return nil, fmt.Errorf("ENS name %s in %s maps to '%s', not a hex address", name, path, address)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		resolver.addresses[name] = util.ChecksumAddress(address)
+		if _, ok := resolver.names[strings.ToLower(address)]; !ok {
+			resolver.names[strings.ToLower(address)] = name
+		}
+	}
+	return resolver, nil
+}
+
+// ResolveName returns the address the file maps the name to.
+func (r *MappingResolver) ResolveName(ctx context.Context, name string) (string, error) {
+	address, ok := r.addresses[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("ENS name %s is not in the ENS names file", name)
+	}
+	return address, nil
+}
+
+// LookupAddress returns the first name the file maps to the address.
+func (r *MappingResolver) LookupAddress(ctx context.Context, address string) (string, error) {
+	return r.names[strings.ToLower(strings.TrimSpace(address))], nil
+}
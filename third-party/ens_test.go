@@ -0,0 +1,127 @@
+package thirdparty
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+const (
+	ensTestResolver = "0x00000000000000000000000000000000000000ee"
+	ensTestTreasury = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	ensTestSpoofer  = "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+)
+
+// ensCall returns the eth_call data of a selector called with the namehash of the name
+func ensCall(selector, name string) string {
+	node := util.NameHash(name)
+	return selector + hex.EncodeToString(node[:])
+}
+
+// ensString ABI encodes a string return value
+func ensString(value string) string {
+	data := hex.EncodeToString([]byte(value))
+	return "0x" + rpcWord("20") + rpcWord(fmt.Sprintf("%x", len(value))) + data + strings.Repeat("0", 64-len(data)%64)
+}
+
+// newENSStandIn serves the registry and one resolver, the spoofer claims the name of the treasury
+func newENSStandIn(t *testing.T) *httptest.Server {
+	reverse := func(address string) string {
+		return strings.ToLower(strings.TrimPrefix(address, "0x")) + constants.ENS_REVERSE_SUFFIX
+	}
+	calls := map[string]string{
+		constants.ENS_REGISTRY_ADDRESS + ensCall(constants.ENS_RESOLVER_SELECTOR, "treasury.eth"):           "0x" + rpcWord(ensTestResolver[2:]),
+		constants.ENS_REGISTRY_ADDRESS + ensCall(constants.ENS_RESOLVER_SELECTOR, reverse(ensTestTreasury)): "0x" + rpcWord(ensTestResolver[2:]),
+		constants.ENS_REGISTRY_ADDRESS + ensCall(constants.ENS_RESOLVER_SELECTOR, reverse(ensTestSpoofer)):  "0x" + rpcWord(ensTestResolver[2:]),
+		ensTestResolver + ensCall(constants.ENS_ADDR_SELECTOR, "treasury.eth"):                              "0x" + rpcWord(strings.ToLower(ensTestTreasury[2:])),
+		ensTestResolver + ensCall(constants.ENS_NAME_SELECTOR, reverse(ensTestTreasury)):                    ensString("treasury.eth"),
+		ensTestResolver + ensCall(constants.ENS_NAME_SELECTOR, reverse(ensTestSpoofer)):                     ensString("treasury.eth"),
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := models.RpcRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_call" {
+			t.Fatalf("unexpected rpc request %s: %v", req.Method, err)
+		}
+		call := req.Params[0].(map[string]any)
+		result, ok := calls[call["to"].(string)+call["data"].(string)]
+		if !ok {
+			result = "0x" + rpcWord("0")
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%q}`, req.ID, result)
+	}))
+}
+
+func TestRPCNameResolver(t *testing.T) {
+	server := newENSStandIn(t)
+	defer server.Close()
+
+	provider, err := NewRPCProvider(models.RpcConfig{Endpoints: map[string]string{constants.CHAIN_ETHEREUM: server.URL}}, chains.Default(), server.Client())
+	if err != nil {
+		t.Fatalf("NewRPCProvider unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	if address, err := provider.ResolveName(ctx, "Treasury.eth"); err != nil || address != ensTestTreasury {
+		t.Errorf("ResolveName() = %q, %v; want %s", address, err, ensTestTreasury)
+	}
+	if _, err := provider.ResolveName(ctx, "unknown.eth"); err == nil {
+		t.Error("ResolveName() of a name without resolver returned no error")
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{name: "Primary Name", address: strings.ToLower(ensTestTreasury), want: "treasury.eth"},
+		{name: "Name Not Resolving Back", address: ensTestSpoofer, want: ""},
+		{name: "No Reverse Record", address: rpcTestOther, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := provider.LookupAddress(ctx, tt.address); err != nil || got != tt.want {
+				t.Errorf("LookupAddress(%s) = %q, %v; want %q", tt.address, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMappingResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ens.yml")
+	names := "treasury.eth: \"" + strings.ToLower(ensTestTreasury) + "\"\nvault.treasury.eth: \"" + ensTestTreasury + "\"\n"
+	if err := os.WriteFile(path, []byte(names), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := NewNameResolver(models.Config{Ens: models.EnsConfig{Resolver: constants.ENS_RESOLVER_FILE, File: path}})
+	if err != nil {
+		t.Fatalf("NewNameResolver unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	if address, err := resolver.ResolveName(ctx, "vault.treasury.eth"); err != nil || address != ensTestTreasury {
+		t.Errorf("ResolveName() = %q, %v; want %s", address, err, ensTestTreasury)
+	}
+	if _, err := resolver.ResolveName(ctx, "unknown.eth"); err == nil {
+		t.Error("ResolveName() of an unknown name returned no error")
+	}
+	if name, _ := resolver.LookupAddress(ctx, ensTestTreasury); name != "treasury.eth" {
+		t.Errorf("LookupAddress() = %q; want the first name of the address", name)
+	}
+	if name, _ := resolver.LookupAddress(ctx, ensTestSpoofer); name != "" {
+		t.Errorf("LookupAddress() = %q; want no name for an unknown address", name)
+	}
+}
@@ -1,32 +1,59 @@
 package thirdparty
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/cache"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
 	"github.com/coin-tracker/transaction-tracker/shared/util"
 )
 
+// Etherscan treats this end block as "up to the latest block"
+const defaultEndBlock = 99999999
+
 type EtherscanProvider struct {
 	ApiKey     string
 	BaseURL    string
+	ChainID    int64 // Sent as chainid, the unified V2 API serves every supported chain from one base URL
 	Client     *http.Client
 	ListParams map[string]string
+
+	// Optional response cache, see FetchTransactionData
+	Cache         *cache.ResponseCache
+	Offline       bool
+	FinalityDepth uint64
+
+	mu            sync.Mutex
+	latestBlock   uint64
+	latestBlockAt time.Time
 }
 
-// NewEtherscanProvider creates a new Etherscan provider instance.
+// NewEtherscanProvider creates a new Etherscan provider instance for the given chain.
 // Typically called by the factory, but can be used directly.
-func NewEtherscanProvider(config models.ThirdPartyApiConfig, client *http.Client) *EtherscanProvider {
+func NewEtherscanProvider(config models.ThirdPartyApiConfig, chain models.Chain, client *http.Client) *EtherscanProvider {
+	if chainURL := config.BaseURLs[chain.Name]; chainURL != "" {
+		config.BaseURL = chainURL
+	}
 	if config.BaseURL == "" {
-		config.BaseURL = "https://api.etherscan.io/api"
+		config.BaseURL = "https://api.etherscan.io/v2/api"
 	}
 
 	// Default list params, some values if required can be taken from a config file or some input
 	listParams := map[string]string{
 		"startblock": "0",
-		"endblock":   "99999999",
+		"endblock":   strconv.FormatUint(defaultEndBlock, 10),
 		"sort":       "asc",
 		"module":     "account",
 	}
@@ -34,6 +61,7 @@ func NewEtherscanProvider(config models.ThirdPartyApiConfig, client *http.Client
 	return &EtherscanProvider{
 		ApiKey:     config.ApiKey,
 		BaseURL:    config.BaseURL,
+		ChainID:    chain.ChainID,
 		Client:     client,
 		ListParams: listParams,
 	}
@@ -45,6 +73,7 @@ func (p *EtherscanProvider) BuildRequestURL(action, walletAddress string) string
 
 	// Prepare query parameters
 	queryParams := url.Values{}
+	queryParams.Set("chainid", strconv.FormatInt(p.ChainID, 10))
 	queryParams.Set("address", walletAddress)
 	queryParams.Set("action", action)
 	queryParams.Set("apikey", p.ApiKey) // Add API key automatically
@@ -61,12 +90,188 @@ func (p *EtherscanProvider) BuildRequestURL(action, walletAddress string) string
 	return fullURL
 }
 
-// FetchTransactionData implements the BlockchainDataProvider interface for Etherscan.
-func (p *EtherscanProvider) FetchTransactionData(url, tag string) (string, error) {
+/*
+BuildLogsRequestURL implements the EventLogProvider interface using the logs/getLogs endpoint.
+The wallet is matched as topic1 OR topic2, which covers the sender/owner and the receiver/spender
+of the common token events. An empty fromBlock starts at the configured start block.
+*/
+func (p *EtherscanProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	walletTopic := util.AddressToTopic(walletAddress)
+	if fromBlock == "" {
+		fromBlock = p.ListParams["startblock"]
+	}
+
+	queryParams := url.Values{}
+	queryParams.Set("chainid", strconv.FormatInt(p.ChainID, 10))
+	queryParams.Set("module", "logs")
+	queryParams.Set("action", constants.EVENT_LOG_REPORT_ACTION)
+	queryParams.Set("fromBlock", fromBlock)
+	queryParams.Set("toBlock", p.ListParams["endblock"])
+	queryParams.Set("topic1", walletTopic)
+	queryParams.Set("topic1_2_opr", "or")
+	queryParams.Set("topic2", walletTopic)
+	queryParams.Set("page", strconv.Itoa(page))
+	queryParams.Set("offset", strconv.Itoa(offset))
+	queryParams.Set("apikey", p.ApiKey)
 
-	res, err := util.TriggerHttpRequest(http.MethodGet, url, tag, p.Client)
+	return fmt.Sprintf("%s?%s", p.BaseURL, queryParams.Encode())
+}
+
+/*
+FetchTransactionData implements the BlockchainDataProvider interface for Etherscan.
+When a cache is configured responses are served from it, keyed by the request URL without the API key.
+Successful responses are cached with the TTL of the cache, responses for block ranges that ended at least
+FinalityDepth blocks before the head are final and cached forever. In offline mode only the cache is used.
+*/
+func (p *EtherscanProvider) FetchTransactionData(ctx context.Context, url, tag string) (string, error) {
+	if p.Cache == nil {
+		return util.TriggerHttpRequest(ctx, http.MethodGet, url, tag, p.Client)
+	}
+
+	key := util.StripQueryParams(url, util.SecretQueryParams...)
+	if res, ok := p.Cache.Get(key); ok {
+		return res, nil
+	}
+	if p.Offline {
+		return "", fmt.Errorf("[%s] %w: %s", tag, cache.ErrCacheMiss, key)
+	}
+
+	res, err := util.TriggerHttpRequest(ctx, http.MethodGet, url, tag, p.Client)
 	if err != nil {
 		return "", err
 	}
+
+	// Error responses (rate limits, invalid keys) must not be served again
+	if checkProviderResponse(res) != nil {
+		return res, nil
+	}
+	if err := p.Cache.Put(key, res, p.isFinalized(ctx, url)); err != nil {
+		logging.FromContext(ctx).Warn("caching response failed", "tag", tag, logging.KeyError, err)
+	}
 	return res, nil
 }
+
+/*
+StreamTransactionData implements the StreamingDataProvider interface. Without a cache the response
+body is returned as it is received, cached responses have to be complete and are read into memory.
+*/
+func (p *EtherscanProvider) StreamTransactionData(ctx context.Context, url, tag string) (io.ReadCloser, error) {
+	if p.Cache != nil {
+		res, err := p.FetchTransactionData(ctx, url, tag)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(strings.NewReader(res)), nil
+	}
+	return util.OpenHttpStream(ctx, http.MethodGet, url, p.Client)
+}
+
+// isFinalized reports whether the block range of the request can no longer change
+func (p *EtherscanProvider) isFinalized(ctx context.Context, requestURL string) bool {
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+
+	endBlock := parsed.Query().Get("endblock")
+	if endBlock == "" {
+		endBlock = parsed.Query().Get("toBlock")
+	}
+	end, err := strconv.ParseUint(endBlock, 10, 64)
+	if err != nil || end >= defaultEndBlock {
+		return false
+	}
+
+	latest, err := p.latestBlockNumber(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("could not fetch the latest block, caching with TTL", logging.KeyError, err)
+		return false
+	}
+	return end+p.FinalityDepth <= latest
+}
+
+// LatestBlockNumber implements the ChainHeadProvider interface, the head is reused for a short TTL.
+func (p *EtherscanProvider) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	if p.Offline {
+		return 0, fmt.Errorf("the latest block is not available in offline mode")
+	}
+	return p.latestBlockNumber(ctx)
+}
+
+/*
+latestBlockNumber fetches the head of the chain, it is kept in memory for CACHE_LATEST_BLOCK_TTL_SECONDS
+so long running commands like watch and serve move the finality cutoff forward. It is never cached on disk.
+The lock is not held during the request, callers arriving meanwhile fetch the head themselves.
+*/
+func (p *EtherscanProvider) latestBlockNumber(ctx context.Context) (uint64, error) {
+	p.mu.Lock()
+	latest, fetchedAt := p.latestBlock, p.latestBlockAt
+	p.mu.Unlock()
+	if latest != 0 && time.Since(fetchedAt) < constants.CACHE_LATEST_BLOCK_TTL_SECONDS*time.Second {
+		return latest, nil
+	}
+
+	queryParams := url.Values{}
+	queryParams.Set("chainid", strconv.FormatInt(p.ChainID, 10))
+	queryParams.Set("module", "proxy")
+	queryParams.Set("action", constants.BLOCK_NUMBER_ACTION)
+	queryParams.Set("apikey", p.ApiKey)
+
+	res, err := util.TriggerHttpRequest(ctx, http.MethodGet, fmt.Sprintf("%s?%s", p.BaseURL, queryParams.Encode()), constants.BLOCK_NUMBER_ACTION, p.Client)
+	if err != nil {
+		return 0, err
+	}
+
+	// The proxy module returns a JSON-RPC response
+	rpcResp := models.RpcResponse{}
+	if err := json.Unmarshal([]byte(res), &rpcResp); err != nil {
+		return 0, fmt.Errorf("error unmarshalling block number: %w", err)
+	}
+	var head string
+	if err := json.Unmarshal(rpcResp.Result, &head); err != nil {
+		return 0, fmt.Errorf("unexpected block number result: %s", string(rpcResp.Result))
+	}
+	latest, err = hexToUint(head)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	p.latestBlock = latest
+	p.latestBlockAt = time.Now()
+	p.mu.Unlock()
+	return latest, nil
+}
+
+// FetchContractABI implements the ContractABIProvider interface using the contract/getabi endpoint.
+func (p *EtherscanProvider) FetchContractABI(ctx context.Context, address string) ([]byte, error) {
+	queryParams := url.Values{}
+	queryParams.Set("chainid", strconv.FormatInt(p.ChainID, 10))
+	queryParams.Set("module", "contract")
+	queryParams.Set("action", constants.CONTRACT_ABI_ACTION)
+	queryParams.Set("address", address)
+	queryParams.Set("apikey", p.ApiKey)
+
+	res, err := p.FetchTransactionData(ctx, fmt.Sprintf("%s?%s", p.BaseURL, queryParams.Encode()), constants.CONTRACT_ABI_ACTION)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.EtherscanBaseResponse{}
+	if err := json.Unmarshal([]byte(res), &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling ABI response for %s: %w", address, err)
+	}
+
+	// The ABI is returned as a JSON encoded string, on failure the result holds the error message
+	var result string
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("unexpected ABI result for %s: %w", address, err)
+	}
+	if resp.Status != "1" {
+		if strings.Contains(strings.ToLower(result), "not verified") {
+			return nil, abi.ErrABINotFound
+		}
+		return nil, fmt.Errorf("etherscan getabi failed for %s: %s - %s", address, resp.Message, result)
+	}
+	return []byte(result), nil
+}
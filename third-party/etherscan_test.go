@@ -0,0 +1,87 @@
+package thirdparty
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestEtherscanFinality(t *testing.T) {
+	head := atomic.Uint64{}
+	head.Store(1000)
+	headRequests := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") != constants.BLOCK_NUMBER_ACTION {
+			t.Fatalf("unexpected request %s", r.URL.RawQuery)
+		}
+		headRequests.Add(1)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":83,"result":"0x%x"}`, head.Load())
+	}))
+	defer server.Close()
+
+	chain, _ := chains.Lookup(constants.CHAIN_ETHEREUM)
+	provider := NewEtherscanProvider(models.ThirdPartyApiConfig{BaseURL: server.URL}, chain, server.Client())
+	provider.FinalityDepth = 64
+	ctx := context.Background()
+	requestURL := func(endBlock uint64) string {
+		return server.URL + "?" + url.Values{"endblock": {fmt.Sprint(endBlock)}}.Encode()
+	}
+
+	if !provider.isFinalized(ctx, requestURL(900)) || provider.isFinalized(ctx, requestURL(950)) {
+		t.Errorf("isFinalized() with head 1000; want blocks up to 936 final")
+	}
+	if got := headRequests.Load(); got != 1 {
+		t.Errorf("head requested %d times; want it reused within the TTL", got)
+	}
+
+	// Once the TTL passed the head is fetched again and the cutoff moves forward
+	head.Store(2000)
+	provider.latestBlockAt = time.Now().Add(-constants.CACHE_LATEST_BLOCK_TTL_SECONDS * time.Second)
+	if !provider.isFinalized(ctx, requestURL(950)) {
+		t.Errorf("isFinalized() = false after the head moved to 2000; want true")
+	}
+	if got := headRequests.Load(); got != 2 {
+		t.Errorf("head requested %d times; want it fetched again after the TTL", got)
+	}
+}
+
+func TestEtherscanLatestBlockUnlocked(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":83,"result":"0x3e8"}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	chain, _ := chains.Lookup(constants.CHAIN_ETHEREUM)
+	provider := NewEtherscanProvider(models.ThirdPartyApiConfig{BaseURL: server.URL}, chain, server.Client())
+	go provider.latestBlockNumber(context.Background()) // hangs until the server is released
+	time.Sleep(20 * time.Millisecond)
+
+	// A caller giving up must not wait for the pending request of another caller
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := provider.latestBlockNumber(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("latestBlockNumber() succeeded; want the deadline error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("latestBlockNumber() waited for the request of another caller")
+	}
+}
@@ -0,0 +1,422 @@
+package thirdparty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// ErrAllProvidersFailed is returned when no provider of the failover chain could serve a request.
+var ErrAllProvidersFailed = errors.New("all data providers failed")
+
+// NamedProvider is a provider of the failover chain together with the name used in logs and health.
+type NamedProvider struct {
+	Name     string
+	Provider BlockchainDataProvider
+}
+
+// ProviderHealth is a snapshot of the health of a provider in the failover chain.
+type ProviderHealth struct {
+	Name                string    `json:"name"`
+	State               string    `json:"state"` // closed (healthy), open (skipped) or half-open (on trial)
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError,omitempty"`
+	OpenedAt            time.Time `json:"openedAt,omitempty"`
+}
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+/*
+FailoverProvider wraps an ordered list of providers, e.g. Etherscan, then Blockscout, then RPC.
+Every request goes to the first healthy provider, on errors or rate limits the next one is tried.
+
+Each provider has a circuit breaker: after FailureThreshold consecutive failures it is skipped
+(open) for Cooldown, after that a single request is let through (half-open). A success closes the
+circuit again, a failure opens it for another cooldown.
+*/
+type FailoverProvider struct {
+	providers        []NamedProvider
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu       sync.Mutex
+	health   map[string]*ProviderHealth
+	logPages map[string]logPagesPin // wallet -> provider that served the first page of its latest logs query
+}
+
+// logPagesPin is the provider that served the first page of the logs of a wallet from a block
+type logPagesPin struct {
+	fromBlock string
+	provider  string
+}
+
+// NewFailoverProvider creates a failover chain from the given providers, tried in order.
+func NewFailoverProvider(providers []NamedProvider, config models.FailoverConfig) (*FailoverProvider, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("failover requires at least one provider")
+	}
+
+	threshold := config.FailureThreshold
+	if threshold <= 0 {
+		threshold = constants.FAILOVER_DEFAULT_FAILURE_THRESHOLD
+	}
+	cooldown := config.CooldownSeconds
+	if cooldown <= 0 {
+		cooldown = constants.FAILOVER_DEFAULT_COOLDOWN_SECONDS
+	}
+
+	health := map[string]*ProviderHealth{}
+	for _, provider := range providers {
+		health[provider.Name] = &ProviderHealth{Name: provider.Name, State: circuitClosed}
+	}
+
+	return &FailoverProvider{
+		providers:        providers,
+		FailureThreshold: threshold,
+		Cooldown:         time.Duration(cooldown) * time.Second,
+		health:           health,
+		logPages:         map[string]logPagesPin{},
+	}, nil
+}
+
+func (p *FailoverProvider) BuildRequestURL(action, walletAddress string) string {
+	queryParams := url.Values{}
+	queryParams.Set("action", action)
+	queryParams.Set("address", walletAddress)
+	return constants.PROVIDER_FAILOVER + ":?" + queryParams.Encode()
+}
+
+// BuildLogsRequestURL implements the EventLogProvider interface.
+func (p *FailoverProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	queryParams := url.Values{}
+	queryParams.Set("action", constants.EVENT_LOG_REPORT_ACTION)
+	queryParams.Set("address", walletAddress)
+	queryParams.Set("fromBlock", fromBlock)
+	queryParams.Set("page", strconv.Itoa(page))
+	queryParams.Set("offset", strconv.Itoa(offset))
+	return constants.PROVIDER_FAILOVER + ":?" + queryParams.Encode()
+}
+
+// FetchTransactionData implements the BlockchainDataProvider interface by trying each provider in order.
+func (p *FailoverProvider) FetchTransactionData(ctx context.Context, requestURL, tag string) (string, error) {
+	body, err := p.serve(ctx, requestURL, tag, fetchResponse)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	res, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response of [%s]: %w", tag, err)
+	}
+	return string(res), nil
+}
+
+/*
+StreamTransactionData implements the StreamingDataProvider interface with the same failover rules.
+Providers that stream are streamed, the others are fetched. Errors are detected from the head of the
+response, once the body is returned a connection dropped mid-stream is not failed over.
+*/
+func (p *FailoverProvider) StreamTransactionData(ctx context.Context, requestURL, tag string) (io.ReadCloser, error) {
+	return p.serve(ctx, requestURL, tag, openResponse)
+}
+
+// openFunc requests a provider and returns its checked response
+type openFunc func(ctx context.Context, provider BlockchainDataProvider, url, tag string) (io.ReadCloser, error)
+
+func fetchResponse(ctx context.Context, provider BlockchainDataProvider, url, tag string) (io.ReadCloser, error) {
+	res, err := provider.FetchTransactionData(ctx, url, tag)
+	if err == nil {
+		err = checkProviderResponse(res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(res)), nil
+}
+
+func openResponse(ctx context.Context, provider BlockchainDataProvider, url, tag string) (io.ReadCloser, error) {
+	streaming, ok := provider.(StreamingDataProvider)
+	if !ok {
+		return fetchResponse(ctx, provider, url, tag)
+	}
+	body, err := streaming.StreamTransactionData(ctx, url, tag)
+	if err != nil {
+		return nil, err
+	}
+	return checkProviderStream(body)
+}
+
+/*
+checkProviderStream checks a streamed response with checkProviderResponse when it is short.
+Error responses (rate limits, invalid keys) are short, a response longer than
+FAILOVER_STREAM_CHECK_BYTES is a result array. The returned body starts with the checked head.
+*/
+func checkProviderStream(body io.ReadCloser) (io.ReadCloser, error) {
+	head := make([]byte, constants.FAILOVER_STREAM_CHECK_BYTES)
+	n, err := io.ReadFull(body, head)
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		body.Close()
+		if err := checkProviderResponse(string(head[:n])); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(head[:n])), nil
+	case err != nil:
+		body.Close()
+		return nil, fmt.Errorf("failed to read provider response: %w", util.RedactError(err))
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), body), body}, nil
+}
+
+/*
+serve sends the request to the first healthy provider and fails over to the next one on errors.
+The provider that served the request is recorded in the ServedProviders of the context.
+*/
+func (p *FailoverProvider) serve(ctx context.Context, requestURL, tag string, open openFunc) (io.ReadCloser, error) {
+	_, rawQuery, _ := strings.Cut(requestURL, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid failover request %s: %w", util.RedactURL(requestURL), err)
+	}
+
+	action := params.Get("action")
+	walletAddress := params.Get("address")
+	fromBlock := params.Get("fromBlock")
+	isLogs := action == constants.EVENT_LOG_REPORT_ACTION
+	page, _ := strconv.Atoi(params.Get("page"))
+	offset, _ := strconv.Atoi(params.Get("offset"))
+
+	// Providers paginate logs differently, later pages must come from the provider of the first page
+	pinned := ""
+	if isLogs && page > 1 {
+		p.mu.Lock()
+		if pin := p.logPages[walletAddress]; pin.fromBlock == fromBlock {
+			pinned = pin.provider
+		}
+		p.mu.Unlock()
+	}
+
+	errs := []error{}
+	for _, named := range p.providers {
+		if pinned != "" && named.Name != pinned {
+			continue
+		}
+
+		childURL := ""
+		if isLogs {
+			logsProvider, ok := named.Provider.(EventLogProvider)
+			if !ok {
+				continue
+			}
+			childURL = logsProvider.BuildLogsRequestURL(walletAddress, fromBlock, page, offset)
+		} else {
+			childURL = named.Provider.BuildRequestURL(action, walletAddress)
+		}
+
+		if pinned == "" && !p.allow(named.Name) {
+			errs = append(errs, fmt.Errorf("%s: skipped, circuit open", named.Name))
+			continue
+		}
+
+		body, err := open(ctx, named.Provider, childURL, tag)
+		if err != nil && ctx.Err() != nil {
+			// The run was canceled, this says nothing about the health of the provider
+			p.recordCanceled(named.Name)
+			return nil, err
+		}
+		if err != nil {
+			logging.FromContext(ctx).Warn("provider failed", "tag", tag, "failover_provider", named.Name, logging.KeyError, err)
+			p.recordFailure(named.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", named.Name, err))
+			metrics.Retries.Inc("failover")
+			continue
+		}
+
+		p.recordSuccess(named.Name)
+		if isLogs && page <= 1 {
+			p.mu.Lock()
+			// Only the latest logs query of a wallet is pinned, the pins are bounded by the wallets
+			p.logPages[walletAddress] = logPagesPin{fromBlock: fromBlock, provider: named.Name}
+			p.mu.Unlock()
+		}
+		ServedProvidersFromContext(ctx).Record(named.Name)
+		logging.FromContext(ctx).Debug("request served", "tag", tag, "failover_provider", named.Name)
+		return body, nil
+	}
+
+	return nil, fmt.Errorf("%w for [%s]: %w", ErrAllProvidersFailed, tag, errors.Join(errs...))
+}
+
+// FetchContractABI implements the ContractABIProvider interface with the same failover rules.
+func (p *FailoverProvider) FetchContractABI(ctx context.Context, address string) ([]byte, error) {
+	errs := []error{}
+	for _, named := range p.providers {
+		abiProvider, ok := named.Provider.(ContractABIProvider)
+		if !ok || !p.allow(named.Name) {
+			continue
+		}
+		data, err := abiProvider.FetchContractABI(ctx, address)
+		if err != nil && ctx.Err() != nil {
+			p.recordCanceled(named.Name)
+			return nil, err
+		}
+		if err == nil || errors.Is(err, abi.ErrABINotFound) {
+			// An unverified contract is an answer, not a provider failure
+			p.recordSuccess(named.Name)
+			return data, err
+		}
+		p.recordFailure(named.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", named.Name, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: no provider can serve contract ABIs", ErrAllProvidersFailed)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// LatestBlockNumber implements the ChainHeadProvider interface with the same failover rules.
+func (p *FailoverProvider) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	errs := []error{}
+	for _, named := range p.providers {
+		headProvider, ok := named.Provider.(ChainHeadProvider)
+		if !ok || !p.allow(named.Name) {
+			continue
+		}
+		head, err := headProvider.LatestBlockNumber(ctx)
+		if err != nil && ctx.Err() != nil {
+			p.recordCanceled(named.Name)
+			return 0, err
+		}
+		if err == nil {
+			p.recordSuccess(named.Name)
+			return head, nil
+		}
+		p.recordFailure(named.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", named.Name, err))
+	}
+	if len(errs) == 0 {
+		return 0, fmt.Errorf("%w: no provider can serve the latest block", ErrAllProvidersFailed)
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Health returns a snapshot of the health of every provider in failover order.
+func (p *FailoverProvider) Health() []ProviderHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot := make([]ProviderHealth, 0, len(p.providers))
+	for _, named := range p.providers {
+		snapshot = append(snapshot, *p.health[named.Name])
+	}
+	return snapshot
+}
+
+// allow reports whether a request may be sent to the provider, moving open circuits to half-open after the cooldown
+func (p *FailoverProvider) allow(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := p.health[name]
+	switch health.State {
+	case circuitOpen:
+		if time.Since(health.OpenedAt) < p.Cooldown {
+			return false
+		}
+		health.State = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		// Only one trial request at a time
+		return false
+	}
+	return true
+}
+
+func (p *FailoverProvider) recordSuccess(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := p.health[name]
+	health.Successes++
+	health.ConsecutiveFailures = 0
+	health.State = circuitClosed
+}
+
+func (p *FailoverProvider) recordFailure(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := p.health[name]
+	health.Failures++
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	if health.State == circuitHalfOpen || health.ConsecutiveFailures >= p.FailureThreshold {
+		health.State = circuitOpen
+		health.OpenedAt = time.Now()
+	}
+}
+
+// recordCanceled ends a trial request that was canceled, the provider gets a new trial with the next request
+func (p *FailoverProvider) recordCanceled(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := p.health[name]
+	if health.State == circuitHalfOpen {
+		health.State = circuitOpen
+	}
+}
+
+/*
+Explorer APIs report errors (invalid key, rate limits) with HTTP 200, status "0" and the message
+as the result string. Empty results also use status "0" but keep an array result.
+*/
+func checkProviderResponse(res string) error {
+	resp := models.EtherscanBaseResponse{}
+	if err := json.Unmarshal([]byte(res), &resp); err != nil {
+		return fmt.Errorf("invalid provider response: %w", err)
+	}
+	if resp.Status == "1" {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(string(resp.Result))
+	if strings.HasPrefix(trimmed, "[") {
+		return nil
+	}
+
+	message := trimmed
+	var result string
+	if err := json.Unmarshal(resp.Result, &result); err == nil {
+		message = result
+	}
+	if strings.Contains(strings.ToLower(message), "rate limit") {
+		return fmt.Errorf("rate limited: %s", message)
+	}
+	return fmt.Errorf("%s - %s", resp.Message, message)
+}
@@ -0,0 +1,183 @@
+package thirdparty
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// stubProvider returns a fixed response or error and counts its calls
+type stubProvider struct {
+	response string
+	err      error
+	calls    int
+}
+
+func (p *stubProvider) BuildRequestURL(action, walletAddress string) string {
+	return "stub://" + action + "/" + walletAddress
+}
+
+func (p *stubProvider) FetchTransactionData(ctx context.Context, url, tag string) (string, error) {
+	p.calls++
+	return p.response, p.err
+}
+
+func TestFailoverProvider(t *testing.T) {
+	rateLimited := &stubProvider{response: `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`}
+	down := &stubProvider{err: errors.New("connection refused")}
+	healthy := &stubProvider{response: `{"status":"0","message":"No transactions found","result":[]}`}
+
+	failover, err := NewFailoverProvider([]NamedProvider{
+		{Name: "etherscan", Provider: rateLimited},
+		{Name: "blockscout", Provider: down},
+		{Name: "rpc", Provider: healthy},
+	}, models.FailoverConfig{FailureThreshold: 2, CooldownSeconds: 60})
+	if err != nil {
+		t.Fatalf("NewFailoverProvider unexpected error: %v", err)
+	}
+
+	url := failover.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, "0x1111111111111111111111111111111111111111")
+	servedBy := &ServedProviders{}
+	ctx := WithServedProviders(context.Background(), servedBy)
+	for i := 0; i < 3; i++ {
+		if _, err := failover.FetchTransactionData(ctx, url, constants.EXTERNAL_REPORT); err != nil {
+			t.Fatalf("FetchTransactionData call %d unexpected error: %v", i, err)
+		}
+	}
+
+	if got := servedBy.String(); got != "rpc" {
+		t.Errorf("ServedBy = %q; want %q", got, "rpc")
+	}
+
+	// The failing providers are skipped once their circuit opened after two failures
+	if rateLimited.calls != 2 || down.calls != 2 || healthy.calls != 3 {
+		t.Errorf("calls = %d/%d/%d; want 2/2/3", rateLimited.calls, down.calls, healthy.calls)
+	}
+
+	wantStates := []string{circuitOpen, circuitOpen, circuitClosed}
+	for i, health := range failover.Health() {
+		if health.State != wantStates[i] {
+			t.Errorf("Health()[%d] (%s) state = %q; want %q", i, health.Name, health.State, wantStates[i])
+		}
+	}
+}
+
+func TestFailoverProviderAllFailed(t *testing.T) {
+	failover, err := NewFailoverProvider([]NamedProvider{
+		{Name: "etherscan", Provider: &stubProvider{err: errors.New("timeout")}},
+	}, models.FailoverConfig{})
+	if err != nil {
+		t.Fatalf("NewFailoverProvider unexpected error: %v", err)
+	}
+
+	url := failover.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, "0x1111111111111111111111111111111111111111")
+	if _, err := failover.FetchTransactionData(context.Background(), url, constants.EXTERNAL_REPORT); !errors.Is(err, ErrAllProvidersFailed) {
+		t.Errorf("FetchTransactionData error = %v; want ErrAllProvidersFailed", err)
+	}
+}
+
+func TestFailoverProviderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	first := &stubProvider{err: context.Canceled}
+	second := &stubProvider{response: `{"status":"1","message":"OK","result":[]}`}
+	failover, err := NewFailoverProvider([]NamedProvider{
+		{Name: "etherscan", Provider: first},
+		{Name: "blockscout", Provider: second},
+	}, models.FailoverConfig{FailureThreshold: 1})
+	if err != nil {
+		t.Fatalf("NewFailoverProvider unexpected error: %v", err)
+	}
+
+	// A canceled run neither fails over nor counts as a provider failure
+	url := failover.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, "0x1111111111111111111111111111111111111111")
+	if _, err := failover.FetchTransactionData(ctx, url, constants.EXTERNAL_REPORT); !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchTransactionData error = %v; want %v", err, context.Canceled)
+	}
+	if second.calls != 0 {
+		t.Errorf("next provider called %d times after cancellation; want 0", second.calls)
+	}
+	if health := failover.Health()[0]; health.State != circuitClosed || health.Failures != 0 {
+		t.Errorf("Health()[0] = %+v; want a closed circuit without failures", health)
+	}
+}
+
+// streamingStubProvider streams a fixed response
+type streamingStubProvider struct {
+	stubProvider
+}
+
+func (p *streamingStubProvider) StreamTransactionData(ctx context.Context, url, tag string) (io.ReadCloser, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return io.NopCloser(strings.NewReader(p.response)), nil
+}
+
+func TestFailoverProviderStreaming(t *testing.T) {
+	tx := `{"hash":"0x` + strings.Repeat("ab", 32) + `","value":"1"}`
+	large := `{"status":"1","message":"OK","result":[` + strings.Repeat(tx+",", 100) + tx + `]}`
+
+	tests := []struct {
+		name         string
+		first        *streamingStubProvider
+		second       NamedProvider
+		wantServedBy string
+		wantBody     string
+	}{
+		{
+			name:         "Error Response Fails Over",
+			first:        &streamingStubProvider{stubProvider{response: `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`}},
+			second:       NamedProvider{Name: "blockscout", Provider: &streamingStubProvider{stubProvider{response: large}}},
+			wantServedBy: "blockscout",
+			wantBody:     large,
+		},
+		{
+			name:         "Large Response Streamed",
+			first:        &streamingStubProvider{stubProvider{response: large}},
+			second:       NamedProvider{Name: "blockscout", Provider: &stubProvider{}},
+			wantServedBy: "etherscan",
+			wantBody:     large,
+		},
+		{
+			name:         "Fetched Provider",
+			first:        &streamingStubProvider{stubProvider{err: errors.New("connection refused")}},
+			second:       NamedProvider{Name: "rpc", Provider: &stubProvider{response: `{"status":"1","message":"OK","result":[]}`}},
+			wantServedBy: "rpc",
+			wantBody:     `{"status":"1","message":"OK","result":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failover, err := NewFailoverProvider([]NamedProvider{{Name: "etherscan", Provider: tt.first}, tt.second}, models.FailoverConfig{})
+			if err != nil {
+				t.Fatalf("NewFailoverProvider unexpected error: %v", err)
+			}
+
+			servedBy := &ServedProviders{}
+			ctx := WithServedProviders(context.Background(), servedBy)
+			url := failover.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, "0x1111111111111111111111111111111111111111")
+			body, err := failover.StreamTransactionData(ctx, url, constants.EXTERNAL_REPORT)
+			if err != nil {
+				t.Fatalf("StreamTransactionData unexpected error: %v", err)
+			}
+			defer body.Close()
+
+			got, err := io.ReadAll(body)
+			if err != nil || string(got) != tt.wantBody {
+				t.Errorf("StreamTransactionData body = %.80q, %v; want %.80q", got, err, tt.wantBody)
+			}
+			if servedBy.String() != tt.wantServedBy {
+				t.Errorf("ServedBy = %q; want %q", servedBy.String(), tt.wantServedBy)
+			}
+		})
+	}
+}
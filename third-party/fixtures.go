@@ -0,0 +1,222 @@
+package thirdparty
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// ErrFixtureNotFound is returned by the replay provider for requests that were not recorded.
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+const fixtureScheme = "fixture:"
+
+/*
+Fixtures are keyed by the provider independent request (action, wallet and pagination) rather than
+the provider URL, so responses recorded from Etherscan can be replayed for any provider type and never
+contain API keys.
+*/
+func fixtureKey(action, walletAddress string, extra url.Values) string {
+	queryParams := url.Values{}
+	for key, values := range extra {
+		queryParams[key] = values
+	}
+	queryParams.Set("action", action)
+	queryParams.Set("address", strings.ToLower(walletAddress))
+	return fixtureScheme + "?" + queryParams.Encode()
+}
+
+func logsFixtureParams(fromBlock string, page, offset int) url.Values {
+	return url.Values{
+		"fromBlock": {fromBlock},
+		"page":      {strconv.Itoa(page)},
+		"offset":    {strconv.Itoa(offset)},
+	}
+}
+
+/*
+RecordingProvider wraps a provider and saves every successful response as a fixture file,
+which the ReplayProvider serves later. Used to record real responses for hermetic tests.
+*/
+type RecordingProvider struct {
+	Provider  BlockchainDataProvider
+	Directory string
+}
+
+// NewRecordingProvider records the responses of the given provider into the directory.
+func NewRecordingProvider(provider BlockchainDataProvider, directory string) *RecordingProvider {
+	return &RecordingProvider{Provider: provider, Directory: directory}
+}
+
+func (p *RecordingProvider) BuildRequestURL(action, walletAddress string) string {
+	return fixtureKey(action, walletAddress, nil)
+}
+
+// BuildLogsRequestURL implements the EventLogProvider interface.
+func (p *RecordingProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	return fixtureKey(constants.EVENT_LOG_REPORT_ACTION, walletAddress, logsFixtureParams(fromBlock, page, offset))
+}
+
+// FetchTransactionData implements the BlockchainDataProvider interface, the response of the wrapped provider is recorded.
+func (p *RecordingProvider) FetchTransactionData(ctx context.Context, key, tag string) (string, error) {
+	params, err := parseFixtureKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	action, walletAddress := params.Get("action"), params.Get("address")
+	requestURL := ""
+	if action == constants.EVENT_LOG_REPORT_ACTION {
+		logsProvider, ok := p.Provider.(EventLogProvider)
+		if !ok {
+			return "", fmt.Errorf("data provider does not support event logs")
+		}
+		page, _ := strconv.Atoi(params.Get("page"))
+		offset, _ := strconv.Atoi(params.Get("offset"))
+		requestURL = logsProvider.BuildLogsRequestURL(walletAddress, params.Get("fromBlock"), page, offset)
+	} else {
+		requestURL = p.Provider.BuildRequestURL(action, walletAddress)
+	}
+
+	res, err := p.Provider.FetchTransactionData(ctx, requestURL, tag)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFixture(p.Directory, models.ProviderFixture{Key: key, Tag: tag, Response: util.Redact(res)}); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+// LatestBlockNumber implements the ChainHeadProvider interface, the head changes and is not recorded.
+func (p *RecordingProvider) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	headProvider, ok := p.Provider.(ChainHeadProvider)
+	if !ok {
+		return 0, fmt.Errorf("data provider does not support the latest block")
+	}
+	return headProvider.LatestBlockNumber(ctx)
+}
+
+// FetchContractABI implements the ContractABIProvider interface, the ABI is recorded as well.
+func (p *RecordingProvider) FetchContractABI(ctx context.Context, address string) ([]byte, error) {
+	abiProvider, ok := p.Provider.(ContractABIProvider)
+	if !ok {
+		return nil, fmt.Errorf("data provider does not support contract ABIs")
+	}
+	data, err := abiProvider.FetchContractABI(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fixtureKey(constants.CONTRACT_ABI_ACTION, address, nil)
+	if err := writeFixture(p.Directory, models.ProviderFixture{Key: key, Tag: constants.CONTRACT_ABI_ACTION, Response: string(data)}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ReplayProvider implements BlockchainDataProvider from fixtures recorded by the RecordingProvider.
+type ReplayProvider struct {
+	Directory string
+
+	mu       sync.Mutex
+	fixtures map[string]models.ProviderFixture
+}
+
+// NewReplayProvider loads every fixture (*.json) of the directory.
+func NewReplayProvider(directory string) (*ReplayProvider, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing fixtures in %s: %w", directory, err)
+	}
+
+	fixtures := map[string]models.ProviderFixture{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixture %s: %w", file, err)
+		}
+		fixture := models.ProviderFixture{}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("error unmarshalling fixture %s: %w", file, err)
+		}
+		fixtures[fixture.Key] = fixture
+	}
+
+	return &ReplayProvider{Directory: directory, fixtures: fixtures}, nil
+}
+
+func (p *ReplayProvider) BuildRequestURL(action, walletAddress string) string {
+	return fixtureKey(action, walletAddress, nil)
+}
+
+// BuildLogsRequestURL implements the EventLogProvider interface.
+func (p *ReplayProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	return fixtureKey(constants.EVENT_LOG_REPORT_ACTION, walletAddress, logsFixtureParams(fromBlock, page, offset))
+}
+
+// FetchTransactionData implements the BlockchainDataProvider interface from the recorded fixtures.
+func (p *ReplayProvider) FetchTransactionData(ctx context.Context, key, tag string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fixture, ok := p.fixtures[key]
+	if !ok {
+		return "", fmt.Errorf("[%s] %w in %s: %s", tag, ErrFixtureNotFound, p.Directory, key)
+	}
+	return fixture.Response, nil
+}
+
+// FetchContractABI implements the ContractABIProvider interface from the recorded fixtures.
+func (p *ReplayProvider) FetchContractABI(ctx context.Context, address string) ([]byte, error) {
+	res, err := p.FetchTransactionData(ctx, fixtureKey(constants.CONTRACT_ABI_ACTION, address, nil), constants.CONTRACT_ABI_ACTION)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(res), nil
+}
+
+func parseFixtureKey(key string) (url.Values, error) {
+	rawQuery, ok := strings.CutPrefix(key, fixtureScheme+"?")
+	if !ok {
+		return nil, fmt.Errorf("invalid fixture request %s", key)
+	}
+	return url.ParseQuery(rawQuery)
+}
+
+// writeFixture stores the fixture as <action>_<hash of the key>.json so files stay readable and unique
+func writeFixture(directory string, fixture models.ProviderFixture) error {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create fixture directory %s: %w", directory, err)
+	}
+
+	params, _ := parseFixtureKey(fixture.Key)
+	sum := sha256.Sum256([]byte(fixture.Key))
+	name := fmt.Sprintf("%s_%s.json", params.Get("action"), hex.EncodeToString(sum[:])[:16])
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package thirdparty
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// apiKeyStubProvider builds request URLs holding an API key
+type apiKeyStubProvider struct {
+	stubProvider
+}
+
+func (p *apiKeyStubProvider) BuildRequestURL(action, walletAddress string) string {
+	return p.stubProvider.BuildRequestURL(action, walletAddress) + "?apikey=SECRET"
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	wallet := "0x1111111111111111111111111111111111111111"
+	response := `{"status":"1","message":"OK","result":[]}`
+
+	// The recorded provider builds URLs holding an API key, fixtures must not contain them
+	inner := &apiKeyStubProvider{stubProvider{response: response}}
+	recorder := NewRecordingProvider(inner, dir)
+
+	key := recorder.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, wallet)
+	if res, err := recorder.FetchTransactionData(context.Background(), key, constants.EXTERNAL_REPORT); err != nil || res != response {
+		t.Fatalf("record FetchTransactionData = %q, %v; want %q", res, err, response)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 || inner.calls != 1 {
+		t.Fatalf("recorded %d fixtures from %d calls; want 1", len(files), inner.calls)
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "SECRET") {
+		t.Errorf("fixture contains the API key: %s", data)
+	}
+
+	replay, err := NewReplayProvider(dir)
+	if err != nil {
+		t.Fatalf("NewReplayProvider unexpected error: %v", err)
+	}
+
+	// Wallet addresses are matched case insensitively
+	key = replay.BuildRequestURL(constants.EXTERNAL_REPORT_ACTION, strings.ToUpper(wallet))
+	if res, err := replay.FetchTransactionData(context.Background(), key, constants.EXTERNAL_REPORT); err != nil || res != response {
+		t.Errorf("replay FetchTransactionData = %q, %v; want %q", res, err, response)
+	}
+
+	key = replay.BuildRequestURL(constants.INTERNAL_REPORT_ACTION, wallet)
+	if _, err := replay.FetchTransactionData(context.Background(), key, constants.INTERNAL_REPORT); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("replay of an unrecorded request error = %v; want %v", err, ErrFixtureNotFound)
+	}
+}
@@ -1,38 +1,173 @@
 package thirdparty
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/cache"
 	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
 )
 
 // BlockchainDataProvider defines the interface for fetching data from blockchain explorers.
+// Fetching stops when the context is canceled or its deadline is exceeded.
 type BlockchainDataProvider interface {
-	FetchTransactionData(url, tag string) (string, error)
+	FetchTransactionData(ctx context.Context, url, tag string) (string, error)
 
 	// builds a request URL for the provider and if in future if a provider has a requets body another method can be defined to build requets body
 	BuildRequestURL(action, walletAddress string) string
 }
 
-func NewDataProvider(providerType string, config models.Config) (BlockchainDataProvider, error) {
+// EventLogProvider is implemented by providers that can serve event logs filtered by topics.
+type EventLogProvider interface {
+	// builds the request URL for one page of logs in which the wallet is an indexed topic (topic1 or topic2)
+	BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string
+}
+
+/*
+ServedProviders collects the names of the providers that served the requests of one report task.
+Providers that delegate requests, e.g. the failover chain, record the provider that served each
+request in the ServedProviders of the request context. A nil ServedProviders records nothing.
+*/
+type ServedProviders struct {
+	mu    sync.Mutex
+	names []string
+}
+
+type servedProvidersKey struct{}
+
+// WithServedProviders returns a context whose requests are recorded in served.
+func WithServedProviders(ctx context.Context, served *ServedProviders) context.Context {
+	return context.WithValue(ctx, servedProvidersKey{}, served)
+}
+
+// ServedProvidersFromContext returns the ServedProviders of the context, nil if there is none.
+func ServedProvidersFromContext(ctx context.Context) *ServedProviders {
+	served, _ := ctx.Value(servedProvidersKey{}).(*ServedProviders)
+	return served
+}
+
+// Record adds the name of a provider that served a request, every name is kept once.
+func (s *ServedProviders) Record(name string) {
+	if s == nil || name == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, served := range s.names {
+		if served == name {
+			return
+		}
+	}
+	s.names = append(s.names, name)
+}
+
+// String returns the names in the order they first served a request, comma separated.
+func (s *ServedProviders) String() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.names, ",")
+}
+
+// StreamingDataProvider is implemented by providers that can return the response body while it is
+// received, so large responses are decoded without holding them in memory.
+type StreamingDataProvider interface {
+	StreamTransactionData(ctx context.Context, url, tag string) (io.ReadCloser, error)
+}
+
+// ChainHeadProvider is implemented by providers that can tell the latest block of the chain they serve.
+type ChainHeadProvider interface {
+	LatestBlockNumber(ctx context.Context) (uint64, error)
+}
+
+// ContractABIProvider is implemented by providers that can serve the ABI of verified contracts.
+type ContractABIProvider interface {
+	FetchContractABI(ctx context.Context, address string) ([]byte, error)
+}
+
+/*
+NewDataProvider acts as a factory to create the BlockchainDataProvider of a chain.
+With fixtures configured the provider records its responses, or is replaced by a replay of them.
+Fixtures are stored per chain.
+*/
+func NewDataProvider(providerType string, config models.Config, chain models.Chain) (BlockchainDataProvider, error) {
 	// Use a shared HTTP client with a reasonable timeout
-	httpClient := &http.Client{Timeout: 15 * time.Second}
+	return NewDataProviderWithClient(providerType, config, chain, &http.Client{Timeout: 15 * time.Second})
+}
+
+// NewDataProviderWithClient creates the provider like NewDataProvider, requests are sent with the given HTTP client.
+func NewDataProviderWithClient(providerType string, config models.Config, chain models.Chain, httpClient *http.Client) (BlockchainDataProvider, error) {
+	if config.Fixtures.ReplayDirectory != "" {
+		return NewReplayProvider(filepath.Join(config.Fixtures.ReplayDirectory, chain.Name))
+	}
+
+	provider, err := newDataProvider(providerType, config, chain, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if config.Fixtures.RecordDirectory != "" {
+		return NewRecordingProvider(provider, filepath.Join(config.Fixtures.RecordDirectory, chain.Name)), nil
+	}
+	return provider, nil
+}
+
+func newDataProvider(providerType string, config models.Config, chain models.Chain, httpClient *http.Client) (BlockchainDataProvider, error) {
 	var err error
 
 	switch strings.ToLower(providerType) {
 	case constants.PROVIDER_ETHERSCAN:
 		// Use default URL if not provided in config
-		return NewEtherscanProvider(config.Etherscan, httpClient), nil
+		client := withRetries(metrics.InstrumentClient(httpClient, constants.PROVIDER_ETHERSCAN, chain.Name), config.Etherscan.Retries)
+		provider := NewEtherscanProvider(config.Etherscan, chain, client)
+		if err := configureExplorer(provider, config); err != nil {
+			return nil, err
+		}
+		return provider, nil
+
+	case constants.PROVIDER_RPC:
+		return NewRPCProvider(config.Rpc, chain, metrics.InstrumentClient(httpClient, constants.PROVIDER_RPC, chain.Name))
 
-	// TODO: Add support Blockscout provider
 	case constants.PROVIDER_BLOCKSCOUT:
 		// Blockscout API key usage is optional/depends on instance
-		// provider := NewBlockscoutProvider(config.Blockscout.Key, config.Blockscout.BaseURL, httpClient)
-		// return provider, nil
+		if len(config.Chains) > 1 && config.Blockscout.BaseURLs[chain.Name] == "" {
+			return nil, fmt.Errorf("blockscout base URL for chain %s is required in BASE_URLS when more than one chain is configured", chain.Name)
+		}
+		client := withRetries(metrics.InstrumentClient(httpClient, constants.PROVIDER_BLOCKSCOUT, chain.Name), config.Blockscout.Retries)
+		provider, err := NewBlockscoutProvider(config.Blockscout, chain, client)
+		if err != nil {
+			return nil, err
+		}
+		if err := configureExplorer(provider.EtherscanProvider, config); err != nil {
+			return nil, err
+		}
+		return provider, nil
+
+	case constants.PROVIDER_FAILOVER:
+		// Failover chain over the providers listed in the config, tried in order
+		providers := make([]NamedProvider, 0, len(config.Providers))
+		for _, name := range config.Providers {
+			if strings.EqualFold(name, constants.PROVIDER_FAILOVER) {
+				return nil, fmt.Errorf("failover can not contain itself")
+			}
+			provider, err := newDataProvider(name, config, chain, httpClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create provider %s for failover: %w", name, err)
+			}
+			providers = append(providers, NamedProvider{Name: strings.ToLower(name), Provider: provider})
+		}
+		return NewFailoverProvider(providers, config.Failover)
 
 	default:
 		// Return a wrapped error for better context
@@ -40,3 +175,33 @@ func NewDataProvider(providerType string, config models.Config) (BlockchainDataP
 	}
 	return nil, err
 }
+
+// withRetries returns a copy of the client that retries failed requests, every attempt is instrumented.
+func withRetries(client *http.Client, retries int) *http.Client {
+	retrying := *client
+	retrying.Transport = util.NewRetryTransport(client.Transport, retries)
+	return &retrying
+}
+
+// configureExplorer applies the block range and response cache settings to an Etherscan compatible provider.
+func configureExplorer(provider *EtherscanProvider, config models.Config) error {
+	provider.ListParams["startblock"] = strconv.FormatUint(config.StartBlock, 10)
+	if config.EndBlock != 0 {
+		provider.ListParams["endblock"] = strconv.FormatUint(config.EndBlock, 10)
+	}
+
+	if config.Cache.Directory == "" {
+		if config.Cache.Offline {
+			return fmt.Errorf("offline mode requires a cache directory (CACHE.DIRECTORY)")
+		}
+		return nil
+	}
+
+	provider.Cache = cache.NewResponseCache(config.Cache.Directory, time.Duration(config.Cache.TTLSeconds)*time.Second)
+	provider.Offline = config.Cache.Offline
+	provider.FinalityDepth = config.Cache.FinalityDepth
+	if provider.FinalityDepth == 0 {
+		provider.FinalityDepth = constants.CACHE_DEFAULT_FINALITY_DEPTH
+	}
+	return nil
+}
@@ -0,0 +1,846 @@
+package thirdparty
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/cache"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// JSON-RPC error code for methods the node does not implement
+const rpcMethodNotFound = -32601
+
+/*
+RPCProvider implements BlockchainDataProvider on top of any Ethereum JSON-RPC endpoint.
+Results are normalized into the Etherscan response format so the report builders work unchanged:
+  - tokentx / tokennfttx are rebuilt from Transfer logs (eth_getLogs), ERC-20 has 3 topics, ERC-721 has 4
+  - txlist uses trace_filter when the node supports it, otherwise every block in the range is scanned
+  - txlistinternal requires trace_filter
+  - getLogs returns the logs in which the wallet is topic1 or topic2
+
+The request "URL" is the endpoint with the action and address in the fragment, it is never sent as is.
+*/
+type RPCProvider struct {
+	Endpoint      string
+	Chain         models.Chain
+	Client        *http.Client
+	StartBlock    uint64
+	EndBlock      uint64 // 0 for the latest block
+	LogBlockRange uint64
+
+	requestID atomic.Int64
+
+	// Node lookups are cached, bounded so long running watch and serve processes stay flat
+	blockTimes   *cache.LRU[uint64, string]
+	tokens       *cache.LRU[string, rpcTokenInfo]
+	receipts     *cache.LRU[string, models.RpcReceipt]
+	transactions *cache.LRU[string, models.RpcTransaction]
+
+	mu           sync.Mutex
+	traceSupport *bool
+}
+
+type rpcTokenInfo struct {
+	Name     string
+	Symbol   string
+	Decimals string
+}
+
+// NewRPCProvider creates a JSON-RPC provider for the endpoint of the given chain.
+func NewRPCProvider(config models.RpcConfig, chain models.Chain, client *http.Client) (*RPCProvider, error) {
+	endpoint := config.Endpoints[chain.Name]
+	if endpoint == "" {
+		return nil, fmt.Errorf("no RPC endpoint configured for chain %s", chain.Name)
+	}
+
+	logBlockRange := config.LogBlockRange
+	if logBlockRange == 0 {
+		logBlockRange = constants.RPC_DEFAULT_LOG_BLOCK_RANGE
+	}
+
+	return &RPCProvider{
+		Endpoint:      endpoint,
+		Chain:         chain,
+		Client:        client,
+		StartBlock:    config.StartBlock,
+		EndBlock:      config.EndBlock,
+		LogBlockRange: logBlockRange,
+		blockTimes:    cache.NewLRU[uint64, string](constants.RPC_CACHE_ENTRIES),
+		tokens:        cache.NewLRU[string, rpcTokenInfo](constants.RPC_CACHE_ENTRIES),
+		receipts:      cache.NewLRU[string, models.RpcReceipt](constants.RPC_CACHE_ENTRIES),
+		transactions:  cache.NewLRU[string, models.RpcTransaction](constants.RPC_CACHE_ENTRIES),
+	}, nil
+}
+
+func (p *RPCProvider) BuildRequestURL(action, walletAddress string) string {
+	fragment := url.Values{}
+	fragment.Set("action", action)
+	fragment.Set("address", walletAddress)
+	return p.Endpoint + "#" + fragment.Encode()
+}
+
+// BuildLogsRequestURL implements the EventLogProvider interface, all logs are returned on the first page.
+func (p *RPCProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	fragment := url.Values{}
+	fragment.Set("action", constants.EVENT_LOG_REPORT_ACTION)
+	fragment.Set("address", walletAddress)
+	fragment.Set("fromBlock", fromBlock)
+	fragment.Set("page", strconv.Itoa(page))
+	return p.Endpoint + "#" + fragment.Encode()
+}
+
+// FetchTransactionData implements the BlockchainDataProvider interface for JSON-RPC nodes.
+func (p *RPCProvider) FetchTransactionData(ctx context.Context, requestURL, tag string) (string, error) {
+	_, rawFragment, _ := strings.Cut(requestURL, "#")
+	params, err := url.ParseQuery(rawFragment)
+	if err != nil {
+		return "", fmt.Errorf("invalid rpc request %s: %w", util.RedactURL(requestURL), err)
+	}
+	walletAddress := strings.ToLower(params.Get("address"))
+
+	var result any
+	switch params.Get("action") {
+	case constants.EXTERNAL_REPORT_ACTION:
+		result, err = p.externalTransactions(ctx, walletAddress)
+	case constants.INTERNAL_REPORT_ACTION:
+		result, err = p.internalTransactions(ctx, walletAddress)
+	case constants.ERC20_REPORT_ACTION:
+		result, err = p.tokenTransfers(ctx, walletAddress)
+	case constants.ERC721_REPORT_ACTION:
+		result, err = p.nftTransfers(ctx, walletAddress)
+	case constants.EVENT_LOG_REPORT_ACTION:
+		if page := params.Get("page"); page != "" && page != "1" {
+			return `{"status":"0","message":"No records found","result":[]}`, nil
+		}
+		result, err = p.walletLogs(ctx, walletAddress, params.Get("fromBlock"))
+	default:
+		return "", fmt.Errorf("action %s is not supported by the rpc provider", params.Get("action"))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return etherscanResponse(result)
+}
+
+// etherscanResponse wraps the result the way Etherscan does so it is parsed by the same code
+func etherscanResponse(result any) (string, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	resp, err := json.Marshal(models.EtherscanBaseResponse{Status: "1", Message: "OK", Result: resultBytes})
+	if err != nil {
+		return "", err
+	}
+	return string(resp), nil
+}
+
+// --- Token transfers ---
+
+func (p *RPCProvider) tokenTransfers(ctx context.Context, walletAddress string) ([]models.TokenTransaction, error) {
+	logs, err := p.transferLogs(ctx, walletAddress, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	txList := make([]models.TokenTransaction, 0, len(logs))
+	for _, log := range logs {
+		base, err := p.transferBase(ctx, log)
+		if err != nil {
+			return nil, err
+		}
+		token := p.tokenInfo(ctx, log.Address)
+		value, _ := util.HexToDecimalString(log.Data)
+
+		txList = append(txList, models.TokenTransaction{
+			BlockNumber:       base.BlockNumber,
+			TimeStamp:         base.TimeStamp,
+			Hash:              base.Hash,
+			Nonce:             base.Nonce,
+			BlockHash:         base.BlockHash,
+			From:              base.From,
+			ContractAddress:   strings.ToLower(log.Address),
+			To:                base.To,
+			Value:             value,
+			TokenName:         token.Name,
+			TokenSymbol:       token.Symbol,
+			TokenDecimal:      token.Decimals,
+			TransactionIndex:  base.TransactionIndex,
+			Gas:               base.Gas,
+			GasPrice:          base.GasPrice,
+			GasUsed:           base.GasUsed,
+			CumulativeGasUsed: base.CumulativeGasUsed,
+			Input:             "deprecated",
+		})
+	}
+	return txList, nil
+}
+
+func (p *RPCProvider) nftTransfers(ctx context.Context, walletAddress string) ([]models.NftTransaction, error) {
+	logs, err := p.transferLogs(ctx, walletAddress, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	txList := make([]models.NftTransaction, 0, len(logs))
+	for _, log := range logs {
+		base, err := p.transferBase(ctx, log)
+		if err != nil {
+			return nil, err
+		}
+		token := p.tokenInfo(ctx, log.Address)
+		tokenID, _ := util.HexToDecimalString(log.Topics[3])
+
+		txList = append(txList, models.NftTransaction{
+			BlockNumber:       base.BlockNumber,
+			TimeStamp:         base.TimeStamp,
+			Hash:              base.Hash,
+			Nonce:             base.Nonce,
+			BlockHash:         base.BlockHash,
+			From:              base.From,
+			ContractAddress:   strings.ToLower(log.Address),
+			To:                base.To,
+			TokenID:           tokenID,
+			TokenName:         token.Name,
+			TokenSymbol:       token.Symbol,
+			TokenDecimal:      "0",
+			TransactionIndex:  base.TransactionIndex,
+			Gas:               base.Gas,
+			GasPrice:          base.GasPrice,
+			GasUsed:           base.GasUsed,
+			CumulativeGasUsed: base.CumulativeGasUsed,
+			Input:             "deprecated",
+		})
+	}
+	return txList, nil
+}
+
+// transferLogs returns the Transfer logs sent or received by the wallet with the given topic count
+func (p *RPCProvider) transferLogs(ctx context.Context, walletAddress string, numTopics int) ([]models.RpcLog, error) {
+	walletTopic := util.AddressToTopic(walletAddress)
+
+	// Topics are AND-ed position wise, sent and received transfers need one query each
+	sent, err := p.getLogs(ctx, p.StartBlock, []any{constants.TRANSFER_EVENT_TOPIC, walletTopic})
+	if err != nil {
+		return nil, err
+	}
+	received, err := p.getLogs(ctx, p.StartBlock, []any{constants.TRANSFER_EVENT_TOPIC, nil, walletTopic})
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []models.RpcLog{}
+	for _, log := range mergeLogs(sent, received) {
+		if len(log.Topics) == numTopics {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// transferBase fills the transaction level fields shared by token and NFT transfers
+func (p *RPCProvider) transferBase(ctx context.Context, log models.RpcLog) (models.ExternalTransaction, error) {
+	tx, err := p.transaction(ctx, log.TransactionHash)
+	if err != nil {
+		return models.ExternalTransaction{}, err
+	}
+	receipt, err := p.receipt(ctx, log.TransactionHash)
+	if err != nil {
+		return models.ExternalTransaction{}, err
+	}
+	blockNumber, err := hexToUint(log.BlockNumber)
+	if err != nil {
+		return models.ExternalTransaction{}, err
+	}
+	timestamp, err := p.blockTimestamp(ctx, blockNumber)
+	if err != nil {
+		return models.ExternalTransaction{}, err
+	}
+
+	external := normalizeTransaction(tx, receipt, timestamp)
+	external.From = topicToAddress(log.Topics[1])
+	external.To = topicToAddress(log.Topics[2])
+	return external, nil
+}
+
+// tokenInfo reads name, symbol and decimals of a token, missing values are left empty
+func (p *RPCProvider) tokenInfo(ctx context.Context, contractAddress string) rpcTokenInfo {
+	key := strings.ToLower(contractAddress)
+
+	info, ok := p.tokens.Get(key)
+	if ok {
+		return info
+	}
+
+	info = rpcTokenInfo{
+		Name:   p.callString(ctx, key, "0x06fdde03"), // name()
+		Symbol: p.callString(ctx, key, "0x95d89b41"), // symbol()
+	}
+	if decimals, err := p.ethCall(ctx, key, "0x313ce567"); err == nil && len(decimals) > 0 { // decimals()
+		info.Decimals, _ = util.HexToDecimalString(decimals)
+	}
+
+	p.tokens.Put(key, info)
+	return info
+}
+
+// callString calls a string getter, older tokens (e.g. MKR) return bytes32 instead
+func (p *RPCProvider) callString(ctx context.Context, contractAddress, selector string) string {
+	res, err := p.ethCall(ctx, contractAddress, selector)
+	if err != nil {
+		return ""
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(res, "0x"))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	if decoded, err := abi.DecodeArguments([]abi.Argument{{Type: "string"}}, data); err == nil {
+		return decoded[0].Value
+	}
+	if len(data) == 32 {
+		return string(bytes.TrimRight(data, "\x00"))
+	}
+	return ""
+}
+
+// --- Event logs ---
+
+func (p *RPCProvider) walletLogs(ctx context.Context, walletAddress, fromBlock string) ([]models.EventLog, error) {
+	start := p.StartBlock
+	if fromBlock != "" {
+		parsed, err := strconv.ParseUint(fromBlock, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fromBlock %s: %w", fromBlock, err)
+		}
+		start = parsed
+	}
+
+	walletTopic := util.AddressToTopic(walletAddress)
+	asTopic1, err := p.getLogs(ctx, start, []any{nil, walletTopic})
+	if err != nil {
+		return nil, err
+	}
+	asTopic2, err := p.getLogs(ctx, start, []any{nil, nil, walletTopic})
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []models.EventLog{}
+	for _, log := range mergeLogs(asTopic1, asTopic2) {
+		blockNumber, err := hexToUint(log.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := p.blockTimestamp(ctx, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		unixTime, err := strconv.ParseUint(timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %s of block %d: %w", timestamp, blockNumber, err)
+		}
+		logs = append(logs, models.EventLog{
+			Address:          strings.ToLower(log.Address),
+			Topics:           log.Topics,
+			Data:             log.Data,
+			BlockNumber:      log.BlockNumber,
+			BlockHash:        log.BlockHash,
+			TimeStamp:        toHex(unixTime),
+			LogIndex:         log.LogIndex,
+			TransactionHash:  log.TransactionHash,
+			TransactionIndex: log.TransactionIndex,
+		})
+	}
+	return logs, nil
+}
+
+// getLogs queries eth_getLogs in chunks of LogBlockRange blocks
+func (p *RPCProvider) getLogs(ctx context.Context, fromBlock uint64, topics []any) ([]models.RpcLog, error) {
+	endBlock, err := p.endBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []models.RpcLog{}
+	for start := fromBlock; start <= endBlock; start += p.LogBlockRange {
+		end := min(start+p.LogBlockRange-1, endBlock)
+		filter := map[string]any{
+			"fromBlock": toHex(start),
+			"toBlock":   toHex(end),
+			"topics":    topics,
+		}
+
+		chunk := []models.RpcLog{}
+		if err := p.call(ctx, "eth_getLogs", &chunk, filter); err != nil {
+			return nil, fmt.Errorf("eth_getLogs failed for blocks %d-%d: %w", start, end, err)
+		}
+		for _, log := range chunk {
+			if !log.Removed {
+				logs = append(logs, log)
+			}
+		}
+	}
+	return logs, nil
+}
+
+// mergeLogs concatenates log lists dropping duplicates, sorted by block and log index
+func mergeLogs(lists ...[]models.RpcLog) []models.RpcLog {
+	seen := map[string]bool{}
+	merged := []models.RpcLog{}
+	for _, list := range lists {
+		for _, log := range list {
+			key := strings.ToLower(log.TransactionHash) + ":" + log.LogIndex
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, log)
+		}
+	}
+
+	sortByPosition(merged, func(log models.RpcLog) (uint64, uint64) {
+		block, _ := hexToUint(log.BlockNumber)
+		index, _ := hexToUint(log.LogIndex)
+		return block, index
+	})
+	return merged
+}
+
+// --- External and internal transactions ---
+
+func (p *RPCProvider) externalTransactions(ctx context.Context, walletAddress string) ([]models.ExternalTransaction, error) {
+	hasTrace, err := p.supportsTraceFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := []string{}
+	if hasTrace {
+		traces, err := p.walletTraces(ctx, walletAddress)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		for _, trace := range traces {
+			// Top level traces are the transactions themselves
+			if len(trace.TraceAddress) == 0 && !seen[trace.TransactionHash] {
+				seen[trace.TransactionHash] = true
+				hashes = append(hashes, trace.TransactionHash)
+			}
+		}
+	} else {
+		hashes, err = p.scanBlocks(ctx, walletAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txList := make([]models.ExternalTransaction, 0, len(hashes))
+	for _, hash := range hashes {
+		tx, err := p.transaction(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := p.receipt(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		blockNumber, err := hexToUint(tx.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := p.blockTimestamp(ctx, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		txList = append(txList, normalizeTransaction(tx, receipt, timestamp))
+	}
+
+	sortByPosition(txList, func(tx models.ExternalTransaction) (uint64, uint64) {
+		return parseUintOrZero(tx.BlockNumber), parseUintOrZero(tx.TransactionIndex)
+	})
+	return txList, nil
+}
+
+func (p *RPCProvider) internalTransactions(ctx context.Context, walletAddress string) ([]models.InternalTransaction, error) {
+	hasTrace, err := p.supportsTraceFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !hasTrace {
+		logging.FromContext(ctx).Warn("rpc endpoint does not support trace_filter, internal transactions are not available", logging.KeyChain, p.Chain.Name)
+		return []models.InternalTransaction{}, nil
+	}
+
+	traces, err := p.walletTraces(ctx, walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	txList := []models.InternalTransaction{}
+	for _, trace := range traces {
+		// Like Etherscan only nested calls moving value and contract creations are listed
+		value, _ := util.HexToDecimalString(trace.Action.Value)
+		if len(trace.TraceAddress) == 0 || (value == "0" && trace.Type != "create") {
+			continue
+		}
+
+		timestamp, err := p.blockTimestamp(ctx, uint64(trace.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		internal := models.InternalTransaction{
+			BlockNumber: strconv.FormatInt(trace.BlockNumber, 10),
+			TimeStamp:   timestamp,
+			Hash:        trace.TransactionHash,
+			From:        strings.ToLower(trace.Action.From),
+			To:          strings.ToLower(trace.Action.To),
+			Value:       value,
+			Input:       trace.Action.Input,
+			Type:        trace.Type,
+			TraceId:     traceID(trace.TraceAddress),
+			IsError:     "0",
+			ErrCode:     trace.Error,
+		}
+		internal.Gas, _ = util.HexToDecimalString(trace.Action.Gas)
+		if trace.Result != nil {
+			internal.GasUsed, _ = util.HexToDecimalString(trace.Result.GasUsed)
+			internal.ContractAddress = strings.ToLower(trace.Result.Address)
+		}
+		if trace.Error != "" {
+			internal.IsError = "1"
+		}
+		txList = append(txList, internal)
+	}
+	return txList, nil
+}
+
+// walletTraces returns all traces sent from or to the wallet using trace_filter
+func (p *RPCProvider) walletTraces(ctx context.Context, walletAddress string) ([]models.RpcTrace, error) {
+	endBlock, err := p.endBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	traces := []models.RpcTrace{}
+	for _, direction := range []string{"fromAddress", "toAddress"} {
+		filter := map[string]any{
+			"fromBlock": toHex(p.StartBlock),
+			"toBlock":   toHex(endBlock),
+			direction:   []string{walletAddress},
+		}
+		res := []models.RpcTrace{}
+		if err := p.call(ctx, "trace_filter", &res, filter); err != nil {
+			return nil, fmt.Errorf("trace_filter failed: %w", err)
+		}
+		traces = append(traces, res...)
+	}
+
+	// A self transfer matches both filters
+	seen := map[string]bool{}
+	unique := []models.RpcTrace{}
+	for _, trace := range traces {
+		key := trace.TransactionHash + ":" + traceID(trace.TraceAddress)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, trace)
+	}
+	return unique, nil
+}
+
+/*
+Scan every block in the range for transactions sent from or to the wallet. This is slow and
+only used when the node has no trace_filter, START_BLOCK/END_BLOCK should limit the range.
+*/
+func (p *RPCProvider) scanBlocks(ctx context.Context, walletAddress string) ([]string, error) {
+	endBlock, err := p.endBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Warn("rpc endpoint does not support trace_filter, scanning blocks", logging.KeyChain, p.Chain.Name, "from_block", p.StartBlock, "to_block", endBlock)
+
+	hashes := []string{}
+	for number := p.StartBlock; number <= endBlock; number++ {
+		block := models.RpcBlock{}
+		if err := p.call(ctx, "eth_getBlockByNumber", &block, toHex(number), true); err != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber failed for block %d: %w", number, err)
+		}
+
+		if timestamp, err := util.HexToDecimalString(block.Timestamp); err == nil {
+			p.blockTimes.Put(number, timestamp)
+		}
+
+		transactions := []models.RpcTransaction{}
+		if err := json.Unmarshal(block.Transactions, &transactions); err != nil {
+			return nil, fmt.Errorf("unexpected transactions in block %d: %w", number, err)
+		}
+		for _, tx := range transactions {
+			if strings.EqualFold(tx.From, walletAddress) || strings.EqualFold(tx.To, walletAddress) {
+				p.transactions.Put(strings.ToLower(tx.Hash), tx)
+				hashes = append(hashes, tx.Hash)
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// supportsTraceFilter probes trace_filter once on an empty range
+func (p *RPCProvider) supportsTraceFilter(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	cached := p.traceSupport
+	p.mu.Unlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
+	probe := map[string]any{"fromBlock": toHex(p.StartBlock), "toBlock": toHex(p.StartBlock), "count": 1}
+	res := []models.RpcTrace{}
+	err := p.call(ctx, "trace_filter", &res, probe)
+
+	supported := err == nil
+	var rpcErr *models.RpcError
+	if err != nil && !errors.As(err, &rpcErr) {
+		// Transport errors are not a sign of missing support
+		return false, err
+	}
+
+	p.mu.Lock()
+	p.traceSupport = &supported
+	p.mu.Unlock()
+	return supported, nil
+}
+
+// --- Cached node lookups ---
+
+func (p *RPCProvider) transaction(ctx context.Context, hash string) (models.RpcTransaction, error) {
+	key := strings.ToLower(hash)
+	tx, ok := p.transactions.Get(key)
+	if ok {
+		return tx, nil
+	}
+
+	if err := p.call(ctx, "eth_getTransactionByHash", &tx, hash); err != nil {
+		return tx, fmt.Errorf("eth_getTransactionByHash failed for %s: %w", hash, err)
+	}
+	p.transactions.Put(key, tx)
+	return tx, nil
+}
+
+func (p *RPCProvider) receipt(ctx context.Context, hash string) (models.RpcReceipt, error) {
+	key := strings.ToLower(hash)
+	receipt, ok := p.receipts.Get(key)
+	if ok {
+		return receipt, nil
+	}
+
+	if err := p.call(ctx, "eth_getTransactionReceipt", &receipt, hash); err != nil {
+		return receipt, fmt.Errorf("eth_getTransactionReceipt failed for %s: %w", hash, err)
+	}
+	p.receipts.Put(key, receipt)
+	return receipt, nil
+}
+
+// blockTimestamp returns the unix timestamp of the block as a decimal string
+func (p *RPCProvider) blockTimestamp(ctx context.Context, number uint64) (string, error) {
+	timestamp, ok := p.blockTimes.Get(number)
+	if ok {
+		return timestamp, nil
+	}
+
+	block := models.RpcBlock{}
+	if err := p.call(ctx, "eth_getBlockByNumber", &block, toHex(number), false); err != nil {
+		return "", fmt.Errorf("eth_getBlockByNumber failed for block %d: %w", number, err)
+	}
+	timestamp, err := util.HexToDecimalString(block.Timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	p.blockTimes.Put(number, timestamp)
+	return timestamp, nil
+}
+
+// LatestBlockNumber implements the ChainHeadProvider interface with the end block the queries of the provider use.
+func (p *RPCProvider) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	return p.endBlock(ctx)
+}
+
+/*
+endBlock returns the end of the range the queries cover. Open ranges end at the head of the chain when
+the query starts, like on an explorer, so a provider reused by long running commands (watch, serve)
+keeps seeing new blocks.
+*/
+func (p *RPCProvider) endBlock(ctx context.Context) (uint64, error) {
+	if p.EndBlock != 0 {
+		return p.EndBlock, nil
+	}
+
+	var res string
+	if err := p.call(ctx, "eth_blockNumber", &res); err != nil {
+		return 0, fmt.Errorf("eth_blockNumber failed: %w", err)
+	}
+	return hexToUint(res)
+}
+
+func (p *RPCProvider) ethCall(ctx context.Context, contractAddress, data string) (string, error) {
+	var res string
+	err := p.call(ctx, "eth_call", &res, map[string]string{"to": contractAddress, "data": data}, "latest")
+	return res, err
+}
+
+// call sends a single JSON-RPC request and unmarshals the result
+func (p *RPCProvider) call(ctx context.Context, method string, result any, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(models.RpcRequest{
+		JSONRPC: "2.0",
+		ID:      p.requestID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rpc request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create rpc request: %w", util.RedactError(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute rpc request: %w", util.RedactError(err))
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read rpc response body: %w", util.RedactError(err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc request %s failed with status %s: %s", method, resp.Status, util.Redact(string(respBytes)))
+	}
+
+	rpcResp := models.RpcResponse{}
+	if err := json.Unmarshal(respBytes, &rpcResp); err != nil {
+		return fmt.Errorf("error unmarshalling rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("unexpected rpc result for %s: %w", method, err)
+	}
+	return nil
+}
+
+// --- Normalization helpers ---
+
+// normalizeTransaction converts a node transaction and receipt into Etherscan's txlist format
+func normalizeTransaction(tx models.RpcTransaction, receipt models.RpcReceipt, timestamp string) models.ExternalTransaction {
+	external := models.ExternalTransaction{
+		TimeStamp:       timestamp,
+		Hash:            tx.Hash,
+		BlockHash:       tx.BlockHash,
+		From:            strings.ToLower(tx.From),
+		To:              strings.ToLower(tx.To),
+		Input:           tx.Input,
+		ContractAddress: strings.ToLower(receipt.ContractAddress),
+		IsError:         "0",
+		TxReceiptStatus: "1",
+	}
+	external.BlockNumber, _ = util.HexToDecimalString(tx.BlockNumber)
+	external.Nonce, _ = util.HexToDecimalString(tx.Nonce)
+	external.TransactionIndex, _ = util.HexToDecimalString(tx.TransactionIndex)
+	external.Value, _ = util.HexToDecimalString(tx.Value)
+	external.Gas, _ = util.HexToDecimalString(tx.Gas)
+	external.GasUsed, _ = util.HexToDecimalString(receipt.GasUsed)
+	external.CumulativeGasUsed, _ = util.HexToDecimalString(receipt.CumulativeGasUsed)
+
+	// effectiveGasPrice is what was paid since EIP-1559, gasPrice is the fee cap
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == "" {
+		gasPrice = tx.GasPrice
+	}
+	external.GasPrice, _ = util.HexToDecimalString(gasPrice)
+
+	if receipt.Status == "0x0" {
+		external.IsError = "1"
+		external.TxReceiptStatus = "0"
+	}
+	if len(tx.Input) >= 10 {
+		external.MethodId = tx.Input[:10]
+	}
+	return external
+}
+
+func topicToAddress(topic string) string {
+	trimmed := strings.TrimPrefix(topic, "0x")
+	if len(trimmed) < 40 {
+		return strings.ToLower(topic)
+	}
+	return "0x" + strings.ToLower(trimmed[len(trimmed)-40:])
+}
+
+func traceID(traceAddress []int) string {
+	parts := make([]string, 0, len(traceAddress))
+	for _, index := range traceAddress {
+		parts = append(parts, strconv.Itoa(index))
+	}
+	return strings.Join(parts, "_")
+}
+
+func toHex(number uint64) string {
+	return "0x" + strconv.FormatUint(number, 16)
+}
+
+func hexToUint(value string) (uint64, error) {
+	res, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex quantity '%s': %w", value, err)
+	}
+	return res, nil
+}
+
+// parseUintOrZero parses a decimal number, invalid values are 0 (e.g. to sort them first)
+func parseUintOrZero(value string) uint64 {
+	res, _ := strconv.ParseUint(value, 10, 64)
+	return res
+}
+
+// sortByPosition sorts items by block number and then by their index inside the block
+func sortByPosition[T any](items []T, position func(T) (uint64, uint64)) {
+	sort.SliceStable(items, func(i, j int) bool {
+		blockI, indexI := position(items[i])
+		blockJ, indexJ := position(items[j])
+		if blockI != blockJ {
+			return blockI < blockJ
+		}
+		return indexI < indexJ
+	})
+}
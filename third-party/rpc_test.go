@@ -0,0 +1,166 @@
+package thirdparty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+const (
+	rpcTestWallet = "0x00000000000000000000000000000000000000aa"
+	rpcTestOther  = "0x00000000000000000000000000000000000000bb"
+	rpcTestToken  = "0x00000000000000000000000000000000000000cc"
+)
+
+func rpcWord(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+// newRPCStandIn serves a three block chain without trace_filter support
+func newRPCStandIn(t *testing.T) *httptest.Server {
+	transfer := models.RpcTransaction{
+		Hash: "0x02", Nonce: "0x7", BlockHash: "0xb2", BlockNumber: "0x2", TransactionIndex: "0x0",
+		From: rpcTestWallet, To: rpcTestOther, Value: "0xde0b6b3a7640000", Gas: "0x5208", GasPrice: "0x3b9aca00", Input: "0x",
+	}
+	tokenTx := models.RpcTransaction{
+		Hash: "0x03", Nonce: "0x1", BlockHash: "0xb3", BlockNumber: "0x3", TransactionIndex: "0x1",
+		From: rpcTestOther, To: rpcTestToken, Value: "0x0", Gas: "0xfde8", GasPrice: "0x3b9aca00", Input: "0xa9059cbb",
+	}
+	tokenLog := models.RpcLog{
+		Address: rpcTestToken, BlockNumber: "0x3", BlockHash: "0xb3", TransactionHash: "0x03", TransactionIndex: "0x1", LogIndex: "0x0",
+		Topics: []string{constants.TRANSFER_EVENT_TOPIC, util.AddressToTopic(rpcTestOther), util.AddressToTopic(rpcTestWallet)},
+		Data:   "0x" + rpcWord("64"),
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := models.RpcRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("invalid rpc request: %v", err)
+		}
+
+		var result any
+		switch req.Method {
+		case "trace_filter":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"the method trace_filter does not exist"}}`, req.ID)
+			return
+		case "eth_blockNumber":
+			result = "0x3"
+		case "eth_getBlockByNumber":
+			number := req.Params[0].(string)
+			txs := []models.RpcTransaction{}
+			if number == "0x2" {
+				txs = append(txs, transfer)
+			}
+			if number == "0x3" {
+				txs = append(txs, tokenTx)
+			}
+			txBytes, _ := json.Marshal(txs)
+			result = models.RpcBlock{Number: number, Timestamp: "0x65f1146b", Transactions: txBytes}
+		case "eth_getTransactionByHash":
+			result = map[string]models.RpcTransaction{"0x02": transfer, "0x03": tokenTx}[req.Params[0].(string)]
+		case "eth_getTransactionReceipt":
+			result = models.RpcReceipt{TransactionHash: req.Params[0].(string), GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00", Status: "0x1"}
+		case "eth_getLogs":
+			filter := req.Params[0].(map[string]any)
+			topics := filter["topics"].([]any)
+			logs := []models.RpcLog{}
+			// The log is in block 3 and received by the wallet (topic2)
+			if filter["toBlock"] == "0x3" && len(topics) == 3 && topics[2] == util.AddressToTopic(rpcTestWallet) {
+				logs = append(logs, tokenLog)
+			}
+			result = logs
+		case "eth_call":
+			call := req.Params[0].(map[string]any)
+			switch call["data"] {
+			case "0x95d89b41": // symbol()
+				result = "0x" + rpcWord("20") + rpcWord("3") + "544b4e" + strings.Repeat("0", 58)
+			case "0x313ce567": // decimals()
+				result = "0x" + rpcWord("12")
+			default:
+				result = "0x"
+			}
+		default:
+			t.Fatalf("unexpected rpc method %s", req.Method)
+		}
+
+		resultBytes, _ := json.Marshal(result)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, resultBytes)
+	}))
+}
+
+func TestRPCProviderFetchTransactionData(t *testing.T) {
+	server := newRPCStandIn(t)
+	defer server.Close()
+
+	config := models.RpcConfig{
+		Endpoints:     map[string]string{constants.CHAIN_ETHEREUM: server.URL},
+		StartBlock:    1,
+		LogBlockRange: 2,
+	}
+	provider, err := NewRPCProvider(config, chains.Default(), server.Client())
+	if err != nil {
+		t.Fatalf("NewRPCProvider unexpected error: %v", err)
+	}
+
+	fetch := func(action string, result any) {
+		res, err := provider.FetchTransactionData(context.Background(), provider.BuildRequestURL(action, rpcTestWallet), action)
+		if err != nil {
+			t.Fatalf("FetchTransactionData(%s) unexpected error: %v", action, err)
+		}
+		resp := models.EtherscanBaseResponse{}
+		if err := json.Unmarshal([]byte(res), &resp); err != nil {
+			t.Fatalf("FetchTransactionData(%s) returned invalid json: %v", action, err)
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			t.Fatalf("FetchTransactionData(%s) returned invalid result: %v", action, err)
+		}
+	}
+
+	t.Run("External Transactions From Block Scan", func(t *testing.T) {
+		txList := []models.ExternalTransaction{}
+		fetch(constants.EXTERNAL_REPORT_ACTION, &txList)
+
+		if len(txList) != 1 {
+			t.Fatalf("got %d transactions; want 1", len(txList))
+		}
+		want := models.ExternalTransaction{
+			BlockNumber: "2", TimeStamp: "1710298219", Hash: "0x02", Nonce: "7", BlockHash: "0xb2", TransactionIndex: "0",
+			From: rpcTestWallet, To: rpcTestOther, Value: "1000000000000000000", Gas: "21000", GasPrice: "1000000000",
+			IsError: "0", TxReceiptStatus: "1", Input: "0x", GasUsed: "21000", CumulativeGasUsed: "0",
+		}
+		if txList[0] != want {
+			t.Errorf("got %+v; want %+v", txList[0], want)
+		}
+	})
+
+	t.Run("Token Transfers From Logs", func(t *testing.T) {
+		txList := []models.TokenTransaction{}
+		fetch(constants.ERC20_REPORT_ACTION, &txList)
+
+		if len(txList) != 1 {
+			t.Fatalf("got %d transfers; want 1", len(txList))
+		}
+		got := txList[0]
+		if got.From != rpcTestOther || got.To != rpcTestWallet || got.Value != "100" || got.TokenSymbol != "TKN" || got.TokenDecimal != "18" {
+			t.Errorf("got %+v; want 100 TKN from %s to %s", got, rpcTestOther, rpcTestWallet)
+		}
+	})
+
+	t.Run("Internal Transactions Without Trace Support", func(t *testing.T) {
+		txList := []models.InternalTransaction{}
+		fetch(constants.INTERNAL_REPORT_ACTION, &txList)
+
+		if len(txList) != 0 {
+			t.Errorf("got %d internal transactions; want 0", len(txList))
+		}
+	})
+}
@@ -0,0 +1,402 @@
+/*
+Package tracker embeds the transaction tracker in other services. A Tracker fetches the
+transactions of a wallet on one chain and builds the report rows in memory, nothing is written
+to disk:
+
+	t, err := tracker.New(
+		tracker.WithConfig(config),
+		tracker.WithChain("ethereum"),
+		tracker.WithRateLimit(5),
+	)
+	txs, err := t.FetchExternal(ctx, wallet, tracker.BlockRange{From: 19000000})
+	rows, err := t.BuildReport(ctx, constants.ERC20_REPORT, wallet, tracker.BlockRange{})
+
+A Tracker is safe for concurrent use.
+*/
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+	"github.com/coin-tracker/transaction-tracker/usecase"
+)
+
+// Providers built for different block ranges that are kept for reuse
+const maxCachedProviders = 16
+
+// BlockRange limits the requested transactions to the blocks From to To (inclusive), a To of 0 is the latest block.
+type BlockRange struct {
+	From uint64
+	To   uint64
+}
+
+// Contains reports whether the block is in the range.
+func (r BlockRange) Contains(block uint64) bool {
+	return block >= r.From && (r.To == 0 || block <= r.To)
+}
+
+// Tracker fetches wallet transactions from a data provider and builds report rows.
+type Tracker struct {
+	config       models.Config
+	providerType string
+	chain        models.Chain
+	provider     thirdparty.BlockchainDataProvider // Set with WithProvider, used for every block range
+	httpClient   *http.Client
+	rateLimit    float64
+	logger       *slog.Logger
+	addressBook  *usecase.AddressBook
+	transforms   []usecase.RowTransformer
+	filters      []usecase.RowFilter
+
+	mu        sync.Mutex
+	providers map[BlockRange]thirdparty.BlockchainDataProvider
+}
+
+// Option configures a Tracker.
+type Option func(*Tracker) error
+
+/*
+New creates a Tracker. Without options it uses the Etherscan provider on Ethereum mainnet,
+the API key is taken from the config (see WithConfig).
+*/
+func New(options ...Option) (*Tracker, error) {
+	t := &Tracker{
+		providerType: constants.PROVIDER_ETHERSCAN,
+		chain:        chains.Default(),
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		providers:    map[BlockRange]thirdparty.BlockchainDataProvider{},
+	}
+	for _, option := range options {
+		if err := option(t); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.httpClient == nil {
+		t.httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	if t.rateLimit > 0 {
+		// Copy the client so the rate limit does not leak into the caller's client
+		client := *t.httpClient
+		client.Transport = util.NewRateLimitTransport(client.Transport, t.rateLimit)
+		t.httpClient = &client
+	}
+	if t.addressBook == nil {
+		book, err := usecase.LoadAddressBook(t.config.AddressBookPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		t.addressBook = book
+	}
+	return t, nil
+}
+
+/*
+WithConfig uses the provider settings (API keys, base URLs, RPC endpoints, failover, cache and
+fixtures) and the address book of the config. The provider type is taken from PROVIDER or
+PROVIDERS, secrets are resolved like in the command line tool.
+*/
+func WithConfig(config models.Config) Option {
+	return func(t *Tracker) error {
+		if err := usecase.ResolveSecrets(&config); err != nil {
+			return err
+		}
+		t.config = config
+		if config.Provider != "" {
+			t.providerType = strings.ToLower(config.Provider)
+		}
+		if len(config.Providers) > 0 {
+			t.providerType = constants.PROVIDER_FAILOVER
+		}
+		return nil
+	}
+}
+
+// WithProviderType selects the provider built from the config: etherscan, blockscout, rpc or failover.
+func WithProviderType(providerType string) Option {
+	return func(t *Tracker) error {
+		t.providerType = strings.ToLower(providerType)
+		return nil
+	}
+}
+
+/*
+WithProvider uses the given provider instead of building one from the config. The provider
+can not be asked for a block range, transactions outside the range are dropped after fetching.
+The HTTP client and rate limit options do not apply to it.
+*/
+func WithProvider(provider thirdparty.BlockchainDataProvider) Option {
+	return func(t *Tracker) error {
+		if provider == nil {
+			return fmt.Errorf("provider is nil")
+		}
+		t.provider = provider
+		return nil
+	}
+}
+
+// WithChain selects the chain by name, e.g. polygon.
+func WithChain(name string) Option {
+	return func(t *Tracker) error {
+		chain, err := chains.Lookup(name)
+		if err != nil {
+			return err
+		}
+		t.chain = chain
+		return nil
+	}
+}
+
+// WithHTTPClient sends the provider requests with the given client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tracker) error {
+		if client == nil {
+			return fmt.Errorf("http client is nil")
+		}
+		t.httpClient = client
+		return nil
+	}
+}
+
+// WithRateLimit sends at most requestsPerSecond provider requests per second.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(t *Tracker) error {
+		if requestsPerSecond <= 0 {
+			return fmt.Errorf("rate limit must be positive, got %v", requestsPerSecond)
+		}
+		t.rateLimit = requestsPerSecond
+		return nil
+	}
+}
+
+/*
+WithLogger logs the requests of the Tracker and the messages of the report builders, with the
+chain, provider, wallet and report type as fields. Nothing is logged by default.
+*/
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Tracker) error {
+		if logger == nil {
+			return fmt.Errorf("logger is nil")
+		}
+		t.logger = logger
+		return nil
+	}
+}
+
+// WithAddressBook labels the counterparties of the report rows, overrides the address book of the config.
+func WithAddressBook(book *usecase.AddressBook) Option {
+	return func(t *Tracker) error {
+		t.addressBook = book
+		return nil
+	}
+}
+
+// WithTransforms adds transforms applied to the report rows after the address book labels.
+func WithTransforms(transforms ...usecase.RowTransformer) Option {
+	return func(t *Tracker) error {
+		t.transforms = append(t.transforms, transforms...)
+		return nil
+	}
+}
+
+// WithFilters adds filters applied to the report rows.
+func WithFilters(filters ...usecase.RowFilter) Option {
+	return func(t *Tracker) error {
+		t.filters = append(t.filters, filters...)
+		return nil
+	}
+}
+
+// Chain returns the chain of the Tracker.
+func (t *Tracker) Chain() models.Chain {
+	return t.chain
+}
+
+// LatestBlock returns the head of the chain as seen by the provider.
+func (t *Tracker) LatestBlock(ctx context.Context) (uint64, error) {
+	provider, err := t.dataProvider(BlockRange{})
+	if err != nil {
+		return 0, err
+	}
+	headProvider, ok := provider.(thirdparty.ChainHeadProvider)
+	if !ok {
+		return 0, fmt.Errorf("provider %s can not tell the latest block", t.providerType)
+	}
+	return headProvider.LatestBlockNumber(t.logContext(ctx, ""))
+}
+
+// FetchExternal returns the external (normal) transactions of the wallet in the block range.
+func (t *Tracker) FetchExternal(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.ExternalTransaction, error) {
+	txList, err := fetch[models.ExternalTransaction](ctx, t, walletAddress, blocks, constants.EXTERNAL_REPORT_ACTION, constants.EXTERNAL_REPORT)
+	return filterBlocks(txList, blocks, func(tx models.ExternalTransaction) string { return tx.BlockNumber }), err
+}
+
+// FetchInternal returns the internal transactions of the wallet in the block range.
+func (t *Tracker) FetchInternal(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.InternalTransaction, error) {
+	txList, err := fetch[models.InternalTransaction](ctx, t, walletAddress, blocks, constants.INTERNAL_REPORT_ACTION, constants.INTERNAL_REPORT)
+	return filterBlocks(txList, blocks, func(tx models.InternalTransaction) string { return tx.BlockNumber }), err
+}
+
+// FetchERC20 returns the ERC-20 token transfers of the wallet in the block range.
+func (t *Tracker) FetchERC20(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.TokenTransaction, error) {
+	txList, err := fetch[models.TokenTransaction](ctx, t, walletAddress, blocks, constants.ERC20_REPORT_ACTION, constants.ERC20_REPORT)
+	return filterBlocks(txList, blocks, func(tx models.TokenTransaction) string { return tx.BlockNumber }), err
+}
+
+// FetchERC721 returns the ERC-721 token transfers of the wallet in the block range.
+func (t *Tracker) FetchERC721(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.NftTransaction, error) {
+	txList, err := fetch[models.NftTransaction](ctx, t, walletAddress, blocks, constants.ERC721_REPORT_ACTION, constants.ERC721_REPORT)
+	return filterBlocks(txList, blocks, func(tx models.NftTransaction) string { return tx.BlockNumber }), err
+}
+
+// FetchEventLogs returns the event logs in which the wallet is an indexed topic, in the block range.
+func (t *Tracker) FetchEventLogs(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.EventLog, error) {
+	provider, err := t.dataProvider(blocks)
+	if err != nil {
+		return nil, err
+	}
+	ctx = t.logContext(ctx, walletAddress)
+	logging.FromContext(ctx).Debug("fetching event logs")
+	logs, err := usecase.FetchEventLogs(ctx, provider, walletAddress)
+	if err != nil {
+		return nil, err
+	}
+	return filterBlocks(logs, blocks, func(log models.EventLog) string {
+		block, _ := util.HexToDecimalString(log.BlockNumber)
+		return block
+	}), nil
+}
+
+/*
+BuildReport returns the rows of a transfer report (EXTERNAL_REPORT, INTERNAL_REPORT, ERC20_REPORT,
+ERC721_REPORT or a registered transfer report type) for the wallet in the block range.
+The rows are labeled, transformed and filtered like the rows of the written reports.
+*/
+func (t *Tracker) BuildReport(ctx context.Context, reportType, walletAddress string, blocks BlockRange) ([]models.ReportResponse, error) {
+	provider, err := t.dataProvider(blocks)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.With(t.logContext(ctx, walletAddress), logging.KeyReportType, reportType)
+	opts := t.reportOptions(ctx, provider)
+	opts.Filters = append(opts.Filters, usecase.BlockRangeFilter(blocks.From, blocks.To))
+
+	logging.FromContext(ctx).Debug("building report")
+	return usecase.ReportRows(ctx, provider, opts, walletAddress, reportType)
+}
+
+// BuildEventLogReport returns the rows of the event log report for the wallet in the block range.
+func (t *Tracker) BuildEventLogReport(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.EventLogReportResponse, error) {
+	ctx = t.logContext(ctx, walletAddress)
+	logs, err := t.FetchEventLogs(ctx, walletAddress, blocks)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := t.dataProvider(blocks)
+	if err != nil {
+		return nil, err
+	}
+	return usecase.BuildEventLogRows(logs, t.reportOptions(ctx, provider)), nil
+}
+
+// BuildApprovalReport returns the latest token approval per token and spender granted by the wallet in the block range.
+func (t *Tracker) BuildApprovalReport(ctx context.Context, walletAddress string, blocks BlockRange) ([]models.ApprovalReportResponse, error) {
+	ctx = t.logContext(ctx, walletAddress)
+	logs, err := t.FetchEventLogs(ctx, walletAddress, blocks)
+	if err != nil {
+		return nil, err
+	}
+	txList, err := t.FetchExternal(ctx, walletAddress, blocks)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := t.dataProvider(blocks)
+	if err != nil {
+		return nil, err
+	}
+	return usecase.BuildApprovalRows(logs, txList, t.reportOptions(ctx, provider), walletAddress), nil
+}
+
+func fetch[T any](ctx context.Context, t *Tracker, walletAddress string, blocks BlockRange, action, tag string) ([]T, error) {
+	provider, err := t.dataProvider(blocks)
+	if err != nil {
+		return nil, err
+	}
+	ctx = t.logContext(ctx, walletAddress)
+	logger := logging.FromContext(ctx)
+	logger.Debug("fetching transactions", "action", action)
+	txList, err := usecase.FetchResult[T](ctx, provider, walletAddress, action, tag)
+	if err != nil {
+		logger.Warn("fetching transactions failed", "action", action, logging.KeyError, err)
+		return nil, err
+	}
+	return txList, nil
+}
+
+// filterBlocks drops the items outside the block range, needed for providers that were not asked for the range
+func filterBlocks[T any](items []T, blocks BlockRange, blockNumber func(T) string) []T {
+	if items == nil {
+		return nil
+	}
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		block, err := strconv.ParseUint(blockNumber(item), 10, 64)
+		if err != nil || blocks.Contains(block) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// logContext carries the logger of the Tracker with the fields of the request to the report builders
+func (t *Tracker) logContext(ctx context.Context, walletAddress string) context.Context {
+	return logging.NewContext(ctx, t.logger.With(logging.KeyChain, t.chain.Name, logging.KeyProvider, t.providerType, logging.KeyWallet, walletAddress))
+}
+
+func (t *Tracker) reportOptions(ctx context.Context, provider thirdparty.BlockchainDataProvider) usecase.ReportOptions {
+	opts := usecase.NewReportOptions(ctx, t.config, provider, t.addressBook, t.chain)
+	opts.Transforms = append(opts.Transforms, t.transforms...)
+	opts.Filters = append(opts.Filters, t.filters...)
+	return opts
+}
+
+// dataProvider returns the provider asked for the block range, providers are built once per range
+func (t *Tracker) dataProvider(blocks BlockRange) (thirdparty.BlockchainDataProvider, error) {
+	if t.provider != nil {
+		return t.provider, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if provider, ok := t.providers[blocks]; ok {
+		return provider, nil
+	}
+	if len(t.providers) >= maxCachedProviders {
+		// Long running callers move the range forward on every poll, drop the old ranges
+		clear(t.providers)
+	}
+
+	config := t.config
+	config.StartBlock, config.EndBlock = blocks.From, blocks.To
+	config.Rpc.StartBlock, config.Rpc.EndBlock = blocks.From, blocks.To
+	provider, err := thirdparty.NewDataProviderWithClient(t.providerType, config, t.chain, t.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	t.providers[blocks] = provider
+	return provider, nil
+}
@@ -0,0 +1,131 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/usecase"
+)
+
+const wallet = "0x1111111111111111111111111111111111111111"
+
+// newReplayTracker serves the recorded fixtures of the usecase tests
+func newReplayTracker(t *testing.T, options ...Option) *Tracker {
+	t.Helper()
+	config := models.Config{Fixtures: models.FixtureConfig{ReplayDirectory: filepath.Join("..", "usecase", "testdata", "fixtures")}}
+	tracker, err := New(append([]Option{WithConfig(config)}, options...)...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return tracker
+}
+
+func TestTrackerFetch(t *testing.T) {
+	tracker := newReplayTracker(t)
+	ctx := context.Background()
+
+	txList, err := tracker.FetchExternal(ctx, wallet, BlockRange{})
+	if err != nil || len(txList) != 2 {
+		t.Fatalf("FetchExternal() = %d transactions, %v; want 2", len(txList), err)
+	}
+	txList, err = tracker.FetchExternal(ctx, wallet, BlockRange{From: 19000050})
+	if err != nil || len(txList) != 1 || txList[0].BlockNumber != "19000100" {
+		t.Errorf("FetchExternal() from block 19000050 = %+v, %v; want the transaction of block 19000100", txList, err)
+	}
+
+	// Log block numbers are hex encoded, 0x121eb6c is block 19000172
+	logs, err := tracker.FetchEventLogs(ctx, wallet, BlockRange{From: 19000000})
+	if err != nil || len(logs) != 1 || logs[0].BlockNumber != "0x121eb6c" {
+		t.Errorf("FetchEventLogs() from block 19000000 = %+v, %v; want the log of block 19000172", logs, err)
+	}
+}
+
+func TestTrackerBuildReport(t *testing.T) {
+	book := usecase.NewAddressBook([]models.AddressBookEntry{{Address: wallet, Label: "Treasury"}})
+	tracker := newReplayTracker(t, WithAddressBook(book))
+	ctx := context.Background()
+
+	rows, err := tracker.BuildReport(ctx, constants.ERC20_REPORT, wallet, BlockRange{})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("BuildReport() = %d rows, %v; want 1", len(rows), err)
+	}
+	if rows[0].TransactionType != constants.TRANSACTION_TYPE_ERC20_TRANSFER || (rows[0].FromLabel != "Treasury" && rows[0].ToLabel != "Treasury") {
+		t.Errorf("BuildReport() row = %+v; want a labeled ERC-20 transfer", rows[0])
+	}
+
+	rows, err = tracker.BuildReport(ctx, constants.EXTERNAL_REPORT, wallet, BlockRange{From: 19000000, To: 19000000})
+	if err != nil || len(rows) != 1 {
+		t.Errorf("BuildReport() of block 19000000 = %d rows, %v; want 1", len(rows), err)
+	}
+
+	if _, err := tracker.BuildReport(ctx, constants.APPROVAL_REPORT, wallet, BlockRange{}); err == nil {
+		t.Error("BuildReport() of the approval report succeeded; want an error, it has no transfer rows")
+	}
+
+	approvals, err := tracker.BuildApprovalReport(ctx, wallet, BlockRange{})
+	if err != nil || len(approvals) != 1 {
+		t.Errorf("BuildApprovalReport() = %d rows, %v; want 1", len(approvals), err)
+	}
+}
+
+func TestTrackerHTTPClient(t *testing.T) {
+	requests := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("startblock") != "100" {
+			t.Errorf("request startblock = %s; want 100", r.URL.Query().Get("startblock"))
+		}
+		w.Write([]byte(`{"status":"1","message":"OK","result":[{"blockNumber":"150","hash":"0x01"}]}`))
+	}))
+	defer server.Close()
+
+	config := models.Config{Etherscan: models.ThirdPartyApiConfig{BaseURL: server.URL, ApiKey: "test-key"}}
+	tracker, err := New(WithConfig(config), WithHTTPClient(server.Client()), WithRateLimit(100))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	txList, err := tracker.FetchInternal(context.Background(), wallet, BlockRange{From: 100})
+	if err != nil || len(txList) != 1 {
+		t.Fatalf("FetchInternal() = %d transactions, %v; want 1", len(txList), err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("server received %d requests; want 1", requests.Load())
+	}
+}
+
+func TestTrackerLogger(t *testing.T) {
+	out := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tracker := newReplayTracker(t, WithLogger(logger))
+
+	if _, err := tracker.BuildReport(context.Background(), constants.ERC20_REPORT, wallet, BlockRange{}); err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+
+	// The lines logged by the report builders carry the fields of the Tracker
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		entry := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %s: %v", line, err)
+		}
+		if entry["msg"] == "requesting transaction data" {
+			found = entry["chain"] == constants.CHAIN_ETHEREUM && entry["wallet"] == wallet &&
+				entry["report_type"] == constants.ERC20_REPORT && entry["provider"] == constants.PROVIDER_ETHERSCAN
+		}
+	}
+	if !found {
+		t.Errorf("no request logged with chain, wallet, report type and provider:\n%s", out.String())
+	}
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+/*
+NewWalletActivity maps a transaction of an account endpoint (external, internal, ERC-20 or ERC-721)
+to a transfer of the wallet, labeled with the address book. Without a wallet address the wallet
+is the own wallet of the address book that sent or received the transfer.
+Returns false for other sources.
+*/
+func NewWalletActivity(source any, walletAddress string, opts ReportOptions) (models.WalletActivity, bool) {
+	chain := opts.Chain
+	var activity models.WalletActivity
+	switch tx := source.(type) {
+	case models.ExternalTransaction:
+		activity = newActivity(NativeTransferType(chain), tx.BlockNumber, tx.TimeStamp, tx.Hash, tx.From, tx.To, "", chain.NativeSymbol, "", tx.Value, chain.Decimals)
+		activity.Status = transactionStatus(tx.IsError)
+		activity.GasFee, _ = util.CalculateGasFee(tx.GasUsed, tx.GasPrice, chain.Decimals)
+	case models.InternalTransaction:
+		activity = newActivity(constants.TRANSACTION_TYPE_INTERNAL_TRANSFER, tx.BlockNumber, tx.TimeStamp, tx.Hash, tx.From, tx.To, "", chain.NativeSymbol, "", tx.Value, chain.Decimals)
+		activity.Status = transactionStatus(tx.IsError)
+	case models.TokenTransaction:
+		decimals, _ := strconv.Atoi(tx.TokenDecimal)
+		activity = newActivity(constants.TRANSACTION_TYPE_ERC20_TRANSFER, tx.BlockNumber, tx.TimeStamp, tx.Hash, tx.From, tx.To, tx.ContractAddress, tx.TokenSymbol, "", tx.Value, decimals)
+		activity.GasFee, _ = util.CalculateGasFee(tx.GasUsed, tx.GasPrice, chain.Decimals)
+	case models.NftTransaction:
+		activity = newActivity(constants.TRANSACTION_TYPE_ERC721_TRANSFER, tx.BlockNumber, tx.TimeStamp, tx.Hash, tx.From, tx.To, tx.ContractAddress, tx.TokenSymbol, tx.TokenID, "", 0)
+		activity.GasFee, _ = util.CalculateGasFee(tx.GasUsed, tx.GasPrice, chain.Decimals)
+	default:
+		return models.WalletActivity{}, false
+	}
+
+	activity.Chain = chain.Name
+	activity.FromLabel = opts.AddressBook.Label(activity.FromAddress)
+	activity.ToLabel = opts.AddressBook.Label(activity.ToAddress)
+
+	fromWallet, toWallet := isOwnWallet(opts.AddressBook, activity.FromAddress), isOwnWallet(opts.AddressBook, activity.ToAddress)
+	if walletAddress != "" {
+		fromWallet = strings.EqualFold(activity.FromAddress, walletAddress)
+		toWallet = strings.EqualFold(activity.ToAddress, walletAddress)
+	}
+	switch {
+	case fromWallet && toWallet:
+		activity.Direction = constants.DIRECTION_SELF
+		activity.Wallet = activity.FromAddress
+	case fromWallet:
+		activity.Direction = constants.DIRECTION_OUT
+		activity.Wallet = activity.FromAddress
+	default:
+		activity.Direction = constants.DIRECTION_IN
+		activity.Wallet = activity.ToAddress
+	}
+	if walletAddress != "" {
+		activity.Wallet = util.ChecksumAddress(walletAddress)
+	}
+	activity.WalletLabel = opts.AddressBook.Label(activity.Wallet)
+	return activity, true
+}
+
+func newActivity(transactionType, blockNumber, timeStamp, hash, from, to, contract, symbol, tokenID, value string, decimals int) models.WalletActivity {
+	block, _ := strconv.ParseUint(blockNumber, 10, 64)
+	dateTime, _ := util.FormatUnixTimestampString(timeStamp)
+	amount := ""
+	if value != "" {
+		amount, _ = util.FormatUnits(value, decimals)
+	}
+	return models.WalletActivity{
+		TransactionType:      transactionType,
+		BlockNumber:          block,
+		DateTime:             dateTime,
+		TransactionHash:      hash,
+		FromAddress:          util.ChecksumAddress(from),
+		ToAddress:            util.ChecksumAddress(to),
+		AssetContractAddress: util.ChecksumAddress(contract),
+		AssetSymbol:          symbol,
+		TokenID:              tokenID,
+		Value:                value,
+		Amount:               amount,
+		Status:               constants.TRANSACTION_STATUS_SUCCESS,
+	}
+}
+
+func transactionStatus(isError string) string {
+	if isError == "1" {
+		return constants.TRANSACTION_STATUS_FAILED
+	}
+	return constants.TRANSACTION_STATUS_SUCCESS
+}
+
+func isOwnWallet(book *AddressBook, address string) bool {
+	entry, ok := book.Lookup(address)
+	return ok && entry.Category == constants.ADDRESS_CATEGORY_OWN_WALLET
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"gopkg.in/yaml.v3"
+)
+
+// AddressBook maps addresses to human readable labels. Lookups are case-insensitive.
+type AddressBook struct {
+	entries map[string]models.AddressBookEntry
+}
+
+// NewAddressBook creates an address book from the given entries.
+// Later entries override earlier ones for the same address.
+func NewAddressBook(entries []models.AddressBookEntry) *AddressBook {
+	book := &AddressBook{entries: make(map[string]models.AddressBookEntry, len(entries))}
+	for _, entry := range entries {
+		book.Add(entry)
+	}
+	return book
+}
+
+/*
+Load the address book from the given path and label the configured wallets.
+The file can either be a YAML file (.yml/.yaml) or a CSV file (.csv) with the headers
+Address, Label and Category. An empty path results in an address book only containing
+the configured wallets.
+*/
+func LoadAddressBook(path string, wallets []models.WalletConfig) (*AddressBook, error) {
+	entries := []models.AddressBookEntry{}
+
+	if path != "" {
+		var err error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml":
+			entries, err = readAddressBookYAML(path)
+		case ".csv":
+			entries, err = readAddressBookCSV(path)
+		default:
+			err = fmt.Errorf("unsupported address book format '%s', expected .yml, .yaml or .csv", filepath.Ext(path))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	book := NewAddressBook(entries)
+
+	// Our own wallets always win over the address book so internal transfers are obvious
+	for _, wallet := range wallets {
+		label := wallet.Label
+		if label == "" {
+			label = constants.ADDRESS_CATEGORY_OWN_WALLET
+		}
+		book.Add(models.AddressBookEntry{
+			Address:  wallet.Address,
+			Label:    label,
+			Category: constants.ADDRESS_CATEGORY_OWN_WALLET,
+		})
+	}
+
+	return book, nil
+}
+
+// Add inserts or replaces an entry in the address book.
+func (b *AddressBook) Add(entry models.AddressBookEntry) {
+	key := normalizeAddress(entry.Address)
+	if key == "" {
+		return
+	}
+	b.entries[key] = entry
+}
+
+// Lookup returns the entry for the given address, if present.
+func (b *AddressBook) Lookup(address string) (models.AddressBookEntry, bool) {
+	if b == nil {
+		return models.AddressBookEntry{}, false
+	}
+	entry, ok := b.entries[normalizeAddress(address)]
+	return entry, ok
+}
+
+// Label returns the label for the given address or an empty string if the address is unknown.
+func (b *AddressBook) Label(address string) string {
+	entry, _ := b.Lookup(address)
+	return entry.Label
+}
+
+// LabelRow returns the report row with the From/To label columns filled.
+func (b *AddressBook) LabelRow(row models.ReportResponse) models.ReportResponse {
+	row.FromLabel = b.Label(row.FromAddress)
+	row.ToLabel = b.Label(row.ToAddress)
+	return row
+}
+
+// Transform implements the RowTransformer interface, the address book labels every transfer report.
+func (b *AddressBook) Transform(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error) {
+	return b.LabelRow(row), nil
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+func readAddressBookYAML(path string) ([]models.AddressBookEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading address book %s: %w", path, err)
+	}
+
+	file := models.AddressBookFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error unmarshalling address book %s: %w", path, err)
+	}
+	return file.Addresses, nil
+}
+
+func readAddressBookCSV(path string) ([]models.AddressBookEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening address book %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Category is optional
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading address book header %s: %w", path, err)
+	}
+
+	// Resolve the column positions from the header so column order does not matter
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	addressIdx, ok := columns["address"]
+	if !ok {
+		return nil, fmt.Errorf("address book %s is missing the 'Address' column", path)
+	}
+	labelIdx, ok := columns["label"]
+	if !ok {
+		return nil, fmt.Errorf("address book %s is missing the 'Label' column", path)
+	}
+	categoryIdx, hasCategory := columns["category"]
+
+	entries := []models.AddressBookEntry{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading address book %s: %w", path, err)
+		}
+
+		entry := models.AddressBookEntry{
+			Address: csvColumn(record, addressIdx),
+			Label:   csvColumn(record, labelIdx),
+		}
+		if hasCategory {
+			entry.Category = csvColumn(record, categoryIdx)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func csvColumn(record []string, idx int) string {
+	if idx < len(record) {
+		return strings.TrimSpace(record[idx])
+	}
+	return ""
+}
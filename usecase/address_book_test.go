@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestLoadAddressBook(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "book.yml")
+	yamlData := `ADDRESSES:
+  - ADDRESS: "0xE592427A0AEce92De3Edee1F18E0157C05861564"
+    LABEL: "Uniswap V3 Router"
+    CATEGORY: "DeFi"
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	csvPath := filepath.Join(dir, "book.csv")
+	csvData := "Label,Address\nPayroll,0x71C7656EC7ab88b098defB751B7401B5f6d8976F\n"
+	if err := os.WriteFile(csvPath, []byte(csvData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wallets := []models.WalletConfig{
+		{Address: "0x1111111111111111111111111111111111111111", Label: "Treasury"},
+		{Address: "0x2222222222222222222222222222222222222222"},
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		address      string
+		wantLabel    string
+		wantCategory string
+	}{
+		{
+			name:         "YAML - Case Insensitive",
+			path:         yamlPath,
+			address:      "0xe592427a0aece92de3edee1f18e0157c05861564",
+			wantLabel:    "Uniswap V3 Router",
+			wantCategory: "DeFi",
+		},
+		{
+			name:      "CSV - Columns In Any Order",
+			path:      csvPath,
+			address:   "0x71c7656ec7ab88b098defb751b7401b5f6d8976f",
+			wantLabel: "Payroll",
+		},
+		{
+			name:         "Own Wallet With Label",
+			path:         "",
+			address:      "0x1111111111111111111111111111111111111111",
+			wantLabel:    "Treasury",
+			wantCategory: constants.ADDRESS_CATEGORY_OWN_WALLET,
+		},
+		{
+			name:         "Own Wallet Without Label",
+			path:         csvPath,
+			address:      "0x2222222222222222222222222222222222222222",
+			wantLabel:    constants.ADDRESS_CATEGORY_OWN_WALLET,
+			wantCategory: constants.ADDRESS_CATEGORY_OWN_WALLET,
+		},
+		{
+			name:    "Unknown Address",
+			path:    yamlPath,
+			address: "0x3333333333333333333333333333333333333333",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			book, err := LoadAddressBook(tc.path, wallets)
+			if err != nil {
+				t.Fatalf("LoadAddressBook(%q) unexpected error: %v", tc.path, err)
+			}
+
+			entry, _ := book.Lookup(tc.address)
+			if entry.Label != tc.wantLabel {
+				t.Errorf("Lookup(%q).Label = %q; want %q", tc.address, entry.Label, tc.wantLabel)
+			}
+			if entry.Category != tc.wantCategory {
+				t.Errorf("Lookup(%q).Category = %q; want %q", tc.address, entry.Category, tc.wantCategory)
+			}
+		})
+	}
+}
+
+func TestLoadAddressBookUnsupportedFormat(t *testing.T) {
+	if _, err := LoadAddressBook("book.json", nil); err == nil {
+		t.Errorf("LoadAddressBook(%q) expected an error, but got nil", "book.json")
+	}
+}
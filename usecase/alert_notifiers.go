@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/webhook"
+)
+
+func newAlertNotifiers(config models.AlertConfig) ([]AlertNotifier, error) {
+	notifiers := []AlertNotifier{}
+	if config.File != "" {
+		if err := os.MkdirAll(filepath.Dir(config.File), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create alerts directory: %w", err)
+		}
+		notifiers = append(notifiers, fileAlertNotifier(config.File))
+	}
+	if config.Webhook.URL != "" {
+		deadLetterFile := config.DeadLetterFile
+		if deadLetterFile == "" {
+			deadLetterFile = constants.ALERTS_DEFAULT_DEAD_LETTER_FILE
+		}
+		sender := webhook.NewSender(config.Webhook, deadLetterFile)
+		notifiers = append(notifiers, AlertNotifierFunc(func(ctx context.Context, alert models.Alert) error {
+			return sender.Send(ctx, alert)
+		}))
+	}
+	if config.Smtp.Address != "" {
+		if config.Smtp.From == "" || len(config.Smtp.To) == 0 {
+			return nil, fmt.Errorf("ALERTS.SMTP needs FROM and TO")
+		}
+		notifiers = append(notifiers, smtpAlertNotifier(config.Smtp))
+	}
+	if config.Stdout || len(notifiers) == 0 {
+		notifiers = append(notifiers, AlertNotifierFunc(printAlert))
+	}
+	return notifiers, nil
+}
+
+func printAlert(ctx context.Context, alert models.Alert) error {
+	severity := alert.Severity
+	if severity == "" {
+		severity = "alert"
+	}
+	fmt.Printf("[ALERT][%s] %s\n", severity, alert.Message)
+	return nil
+}
+
+// fileAlertNotifier appends every alert as one line of JSON
+func fileAlertNotifier(path string) AlertNotifier {
+	return AlertNotifierFunc(func(ctx context.Context, alert models.Alert) error {
+		data, err := json.Marshal(alert)
+		if err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open alerts file: %w", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write alerts file: %w", err)
+		}
+		return file.Close()
+	})
+}
+
+// smtpAlertNotifier mails every alert through a local relay, the relay takes care of authentication and TLS
+func smtpAlertNotifier(config models.SmtpConfig) AlertNotifier {
+	return AlertNotifierFunc(func(ctx context.Context, alert models.Alert) error {
+		body, err := json.MarshalIndent(alert, "", "  ")
+		if err != nil {
+			return err
+		}
+		subject := fmt.Sprintf("%s %s", constants.ALERT_MAIL_SUBJECT_PREFIX, alert.Rule)
+		if alert.Severity != "" {
+			subject = fmt.Sprintf("%s [%s] %s", constants.ALERT_MAIL_SUBJECT_PREFIX, alert.Severity, alert.Rule)
+		}
+
+		message := strings.Builder{}
+		fmt.Fprintf(&message, "From: %s\r\n", config.From)
+		fmt.Fprintf(&message, "To: %s\r\n", strings.Join(config.To, ", "))
+		fmt.Fprintf(&message, "Subject: %s\r\n", mailHeaderValue(subject))
+		fmt.Fprintf(&message, "Date: %s\r\n", alert.TriggeredAt.Format(time.RFC1123Z))
+		message.WriteString("MIME-Version: 1.0\r\n")
+		message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		message.WriteString(alert.Message + "\r\n\r\n")
+		message.WriteString(strings.ReplaceAll(string(body), "\n", "\r\n") + "\r\n")
+
+		if err := smtp.SendMail(config.Address, nil, config.From, config.To, []byte(message.String())); err != nil {
+			return fmt.Errorf("failed to mail alert: %w", err)
+		}
+		return nil
+	})
+}
+
+// mailHeaderValue keeps labels from the address book from injecting headers
+func mailHeaderValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
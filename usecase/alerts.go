@@ -0,0 +1,298 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// AlertNotifier delivers the alerts of matched rules, e.g. to stdout, a file, a webhook or by mail.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert models.Alert) error
+}
+
+// AlertNotifierFunc adapts a function to the AlertNotifier interface.
+type AlertNotifierFunc func(ctx context.Context, alert models.Alert) error
+
+func (f AlertNotifierFunc) Notify(ctx context.Context, alert models.Alert) error {
+	return f(ctx, alert)
+}
+
+/*
+AlertEngine evaluates the alert rules of the config against transfers and approvals, every match
+is sent to all notifiers. A nil engine has no rules, so report builders call it unconditionally.
+*/
+type AlertEngine struct {
+	rules     []alertRule
+	notifiers []AlertNotifier
+
+	mu sync.Mutex // Alerts are delivered one at a time
+}
+
+// alertRule is a rule with its amounts parsed
+type alertRule struct {
+	models.AlertRule
+	amountAbove *big.Rat
+	gasFeeAbove *big.Rat
+}
+
+/*
+NewAlertEngine loads the rules of ALERTS.RULES and ALERTS.RULES_FILE and creates the notifiers of
+the config. Returns nil when no rules are configured. Alerts are printed unless a file, webhook
+or mail destination is configured.
+*/
+func NewAlertEngine(config models.AlertConfig) (*AlertEngine, error) {
+	rules := append([]models.AlertRule{}, config.Rules...)
+	if config.RulesFile != "" {
+		data, err := os.ReadFile(config.RulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read alert rules: %w", err)
+		}
+		file := models.AlertRulesFile{}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid alert rules file %s: %w", config.RulesFile, err)
+		}
+		rules = append(rules, file.Rules...)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	engine := &AlertEngine{}
+	for i, rule := range rules {
+		compiled, err := compileAlertRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert rule %d (%s): %w", i+1, rule.Name, err)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	notifiers, err := newAlertNotifiers(config)
+	if err != nil {
+		return nil, err
+	}
+	engine.notifiers = notifiers
+	return engine, nil
+}
+
+// AddNotifier sends the alerts to one more destination.
+func (e *AlertEngine) AddNotifier(notifier AlertNotifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers = append(e.notifiers, notifier)
+}
+
+// HasRules reports whether a rule can match events of the type, e.g. to skip fetching approvals.
+func (e *AlertEngine) HasRules(eventType string) bool {
+	if e == nil {
+		return false
+	}
+	for _, rule := range e.rules {
+		if rule.Event == "" || strings.EqualFold(rule.Event, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Evaluate matches the event against every rule and returns the alerts sent. Notifier errors are
+logged and do not stop the other notifiers, alerts never fail a report.
+*/
+func (e *AlertEngine) Evaluate(ctx context.Context, event models.AlertEvent) []models.Alert {
+	if e == nil {
+		return nil
+	}
+
+	alerts := []models.Alert{}
+	for _, rule := range e.rules {
+		if !rule.matches(event) {
+			continue
+		}
+		alert := models.Alert{
+			Rule:        rule.Name,
+			Severity:    rule.Severity,
+			Message:     alertMessage(rule.Name, event),
+			TriggeredAt: time.Now().UTC(),
+			AlertEvent:  event,
+		}
+		alerts = append(alerts, alert)
+
+		e.mu.Lock()
+		for _, notifier := range e.notifiers {
+			if err := notifier.Notify(ctx, alert); err != nil {
+				logging.FromContext(ctx).Error("sending alert failed", "rule", rule.Name, logging.KeyError, err)
+			}
+		}
+		e.mu.Unlock()
+	}
+	return alerts
+}
+
+// ObserveTransfer evaluates the rules against a row of a transfer report and the transaction it was mapped from.
+func (e *AlertEngine) ObserveTransfer(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) {
+	if e == nil {
+		return
+	}
+	activity, ok := NewWalletActivity(source, "", opts)
+	if !ok {
+		return
+	}
+	// Transforms may have relabeled the row
+	activity.FromLabel, activity.ToLabel = row.FromLabel, row.ToLabel
+	e.Evaluate(ctx, TransferAlertEvent(activity))
+}
+
+// ObserveApproval evaluates the rules against a row of the approval report of the wallet.
+func (e *AlertEngine) ObserveApproval(ctx context.Context, row models.ApprovalReportResponse, walletAddress string, opts ReportOptions) {
+	if e == nil {
+		return
+	}
+	e.Evaluate(ctx, models.AlertEvent{
+		Type:        constants.ALERT_EVENT_APPROVAL,
+		Wallet:      walletAddress,
+		WalletLabel: opts.AddressBook.Label(walletAddress),
+		Approval:    &row,
+	})
+}
+
+// TransferAlertEvent returns the alert event of a transfer.
+func TransferAlertEvent(activity models.WalletActivity) models.AlertEvent {
+	return models.AlertEvent{
+		Type:        constants.ALERT_EVENT_TRANSFER,
+		Wallet:      activity.Wallet,
+		WalletLabel: activity.WalletLabel,
+		Transfer:    &activity,
+	}
+}
+
+func compileAlertRule(rule models.AlertRule) (alertRule, error) {
+	compiled := alertRule{AlertRule: rule}
+	if strings.TrimSpace(rule.Name) == "" {
+		return compiled, fmt.Errorf("NAME is required")
+	}
+	switch strings.ToLower(rule.Event) {
+	case "", constants.ALERT_EVENT_TRANSFER, constants.ALERT_EVENT_APPROVAL:
+	default:
+		return compiled, fmt.Errorf("unknown EVENT '%s', expected transfer or approval", rule.Event)
+	}
+	switch strings.ToLower(rule.Direction) {
+	case "", constants.DIRECTION_IN, constants.DIRECTION_OUT, constants.DIRECTION_SELF:
+	default:
+		return compiled, fmt.Errorf("unknown DIRECTION '%s', expected in, out or self", rule.Direction)
+	}
+
+	var err error
+	if compiled.amountAbove, err = parseAlertAmount("AMOUNT_ABOVE", rule.AmountAbove); err != nil {
+		return compiled, err
+	}
+	if compiled.gasFeeAbove, err = parseAlertAmount("GAS_FEE_ABOVE", rule.GasFeeAbove); err != nil {
+		return compiled, err
+	}
+	return compiled, nil
+}
+
+func parseAlertAmount(name, value string) (*big.Rat, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return amount, nil
+}
+
+func (r alertRule) matches(event models.AlertEvent) bool {
+	if r.Event != "" && !strings.EqualFold(r.Event, event.Type) {
+		return false
+	}
+	if r.Wallet != "" && !matchesParty(r.Wallet, event.Wallet, event.WalletLabel) {
+		return false
+	}
+
+	switch {
+	case event.Transfer != nil:
+		t := event.Transfer
+		counterparty := t.ToLabel
+		if t.Direction == constants.DIRECTION_IN {
+			counterparty = t.FromLabel
+		}
+		return matchesText(r.TransactionType, t.TransactionType) &&
+			matchesText(r.Direction, t.Direction) &&
+			matchesText(r.Status, t.Status) &&
+			matchesText(r.Asset, t.AssetSymbol) &&
+			(r.From == "" || matchesParty(r.From, t.FromAddress, t.FromLabel)) &&
+			(r.To == "" || matchesParty(r.To, t.ToAddress, t.ToLabel)) &&
+			(r.CounterpartyLabeled == nil || t.Direction == constants.DIRECTION_SELF || *r.CounterpartyLabeled == (counterparty != "")) &&
+			exceeds(t.Amount, r.amountAbove) &&
+			exceeds(t.GasFee, r.gasFeeAbove)
+	case event.Approval != nil:
+		a := event.Approval
+		// Approvals have no amount in whole units, gas fee or direction
+		return r.TransactionType == "" && r.Direction == "" && r.amountAbove == nil && r.gasFeeAbove == nil &&
+			matchesText(r.Status, a.Status) &&
+			matchesText(r.Asset, a.TokenLabel) &&
+			(r.From == "" || matchesParty(r.From, event.Wallet, event.WalletLabel)) &&
+			(r.To == "" || matchesParty(r.To, a.SpenderAddress, a.SpenderLabel)) &&
+			(r.CounterpartyLabeled == nil || *r.CounterpartyLabeled == (a.SpenderLabel != ""))
+	}
+	return false
+}
+
+func matchesText(want, value string) bool {
+	return want == "" || strings.EqualFold(strings.TrimSpace(want), value)
+}
+
+// matchesParty matches the address or the label of a party
+func matchesParty(want, address, label string) bool {
+	want = strings.TrimSpace(want)
+	return strings.EqualFold(want, address) || (label != "" && strings.EqualFold(want, label))
+}
+
+// exceeds reports whether the decimal amount is above the limit, a missing amount never is
+func exceeds(amount string, limit *big.Rat) bool {
+	if limit == nil {
+		return true
+	}
+	value, ok := new(big.Rat).SetString(amount)
+	return ok && value.Cmp(limit) > 0
+}
+
+func alertMessage(rule string, event models.AlertEvent) string {
+	wallet := event.WalletLabel
+	if wallet == "" {
+		wallet = event.Wallet
+	}
+	switch {
+	case event.Transfer != nil:
+		t := event.Transfer
+		amount := strings.TrimSpace(t.Amount + " " + t.AssetSymbol)
+		if t.TokenID != "" {
+			amount = strings.TrimSpace(t.AssetSymbol + " #" + t.TokenID)
+		}
+		return fmt.Sprintf("%s: %s %s %s of %s from %s to %s on %s, tx %s", rule, t.Status, t.Direction, t.TransactionType, amount,
+			partyName(t.FromAddress, t.FromLabel), partyName(t.ToAddress, t.ToLabel), t.Chain, t.TransactionHash)
+	case event.Approval != nil:
+		a := event.Approval
+		return fmt.Sprintf("%s: %s approval of %s to %s by %s on %s, tx %s", rule, a.Status, partyName(a.TokenAddress, a.TokenLabel),
+			partyName(a.SpenderAddress, a.SpenderLabel), wallet, a.Chain, a.TransactionHash)
+	}
+	return rule
+}
+
+func partyName(address, label string) string {
+	if label == "" {
+		return address
+	}
+	return label + " (" + address + ")"
+}
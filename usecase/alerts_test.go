@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestAlertRules(t *testing.T) {
+	labeled, unlabeled := true, false
+	outgoing := models.WalletActivity{
+		Chain: "ethereum", Wallet: "0xhot", WalletLabel: "Hot Wallet", TransactionType: "ETH Transfer",
+		Direction: constants.DIRECTION_OUT, Status: constants.TRANSACTION_STATUS_SUCCESS,
+		FromAddress: "0xhot", FromLabel: "Hot Wallet", ToAddress: "0xunknown",
+		AssetSymbol: "ETH", Amount: "12.5", GasFee: "0.0021",
+	}
+	failed := outgoing
+	failed.Status, failed.Amount = constants.TRANSACTION_STATUS_FAILED, "0"
+	incoming := outgoing
+	incoming.Direction, incoming.FromAddress, incoming.FromLabel, incoming.ToAddress, incoming.ToLabel = constants.DIRECTION_IN, "0xbinance", "Binance", "0xhot", "Hot Wallet"
+	approval := models.AlertEvent{
+		Type: constants.ALERT_EVENT_APPROVAL, Wallet: "0xhot", WalletLabel: "Hot Wallet",
+		Approval: &models.ApprovalReportResponse{TokenAddress: "0xusdc", TokenLabel: "USDC", SpenderAddress: "0xspender", Status: constants.APPROVAL_STATUS_ACTIVE},
+	}
+
+	tests := []struct {
+		name  string
+		rule  models.AlertRule
+		event models.AlertEvent
+		want  bool
+	}{
+		{name: "Outgoing ETH Above Limit", rule: models.AlertRule{Direction: "out", Asset: "eth", AmountAbove: "10"}, event: TransferAlertEvent(outgoing), want: true},
+		{name: "Outgoing ETH Below Limit", rule: models.AlertRule{Direction: "out", Asset: "ETH", AmountAbove: "12.5"}, event: TransferAlertEvent(outgoing), want: false},
+		{name: "Incoming Is Not Outgoing", rule: models.AlertRule{Direction: "out"}, event: TransferAlertEvent(incoming), want: false},
+		{name: "Transfer To Unlabeled Address", rule: models.AlertRule{Event: "transfer", CounterpartyLabeled: &unlabeled}, event: TransferAlertEvent(outgoing), want: true},
+		{name: "Transfer From Labeled Address", rule: models.AlertRule{Event: "transfer", CounterpartyLabeled: &unlabeled}, event: TransferAlertEvent(incoming), want: false},
+		{name: "Failed Tx From Hot Wallet", rule: models.AlertRule{Status: "failed", From: "hot wallet"}, event: TransferAlertEvent(failed), want: true},
+		{name: "Successful Tx From Hot Wallet", rule: models.AlertRule{Status: "failed", From: "Hot Wallet"}, event: TransferAlertEvent(outgoing), want: false},
+		{name: "Gas Fee Above Limit", rule: models.AlertRule{GasFeeAbove: "0.002"}, event: TransferAlertEvent(outgoing), want: true},
+		{name: "Gas Fee Below Limit", rule: models.AlertRule{GasFeeAbove: "0.01"}, event: TransferAlertEvent(outgoing), want: false},
+		{name: "Approval To Unknown Spender", rule: models.AlertRule{Event: "approval", Status: "active", CounterpartyLabeled: &unlabeled}, event: approval, want: true},
+		{name: "Approval To Known Spender", rule: models.AlertRule{Event: "approval", CounterpartyLabeled: &labeled}, event: approval, want: false},
+		{name: "Approval By Wallet Label", rule: models.AlertRule{Wallet: "Hot Wallet", Asset: "USDC"}, event: approval, want: true},
+		{name: "Amount Rule Skips Approvals", rule: models.AlertRule{AmountAbove: "0"}, event: approval, want: false},
+		{name: "Transfer Rule Skips Approvals", rule: models.AlertRule{Event: "transfer"}, event: approval, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Name = tt.name
+			rule, err := compileAlertRule(tt.rule)
+			if err != nil {
+				t.Fatalf("compileAlertRule() error = %v", err)
+			}
+			if got := rule.matches(tt.event); got != tt.want {
+				t.Errorf("matches() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAlertEngine(t *testing.T) {
+	engine, err := NewAlertEngine(models.AlertConfig{})
+	if err != nil || engine != nil {
+		t.Fatalf("NewAlertEngine() without rules = %v, %v; want nil", engine, err)
+	}
+	if alerts := engine.Evaluate(context.Background(), models.AlertEvent{}); alerts != nil {
+		t.Errorf("Evaluate() of a nil engine = %v; want nil", alerts)
+	}
+
+	for _, rule := range []models.AlertRule{
+		{Direction: "out"},
+		{Name: "Unknown Event", Event: "swap"},
+		{Name: "Unknown Direction", Direction: "outgoing"},
+		{Name: "Invalid Amount", AmountAbove: "ten"},
+	} {
+		if _, err := NewAlertEngine(models.AlertConfig{Rules: []models.AlertRule{rule}}); err == nil {
+			t.Errorf("NewAlertEngine(%+v) succeeded; want an error", rule)
+		}
+	}
+
+	rulesFile := filepath.Join(t.TempDir(), "alerts.yml")
+	rules := "RULES:\n  - NAME: Large transfer\n    SEVERITY: critical\n    AMOUNT_ABOVE: 100\n"
+	if err := os.WriteFile(rulesFile, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err = NewAlertEngine(models.AlertConfig{RulesFile: rulesFile, File: filepath.Join(t.TempDir(), "alerts.jsonl")})
+	if err != nil {
+		t.Fatalf("NewAlertEngine() error = %v", err)
+	}
+	received := []models.Alert{}
+	engine.AddNotifier(AlertNotifierFunc(func(ctx context.Context, alert models.Alert) error {
+		received = append(received, alert)
+		return nil
+	}))
+
+	transfer := models.WalletActivity{Direction: constants.DIRECTION_IN, Amount: "250", AssetSymbol: "ETH"}
+	alerts := engine.Evaluate(context.Background(), TransferAlertEvent(transfer))
+	if len(alerts) != 1 || len(received) != 1 || received[0].Severity != "critical" || received[0].Transfer == nil {
+		t.Errorf("Evaluate() = %+v, notified %d; want one critical alert", alerts, len(received))
+	}
+}
+
+func TestSmtpAlertNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Minimal relay, accepts one mail and returns its data
+	mail := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		data := strings.Builder{}
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				mail <- data.String()
+				conn.Write([]byte("250 OK\r\n"))
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				conn.Write([]byte("250 localhost\r\n"))
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				conn.Write([]byte("354 Go ahead\r\n"))
+			case strings.HasPrefix(line, "QUIT"):
+				conn.Write([]byte("221 Bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+
+	notifier := smtpAlertNotifier(models.SmtpConfig{Address: listener.Addr().String(), From: "tracker@example.com", To: []string{"ops@example.com"}})
+	alert := models.Alert{Rule: "Large transfer", Severity: "critical", Message: "Large transfer: 250 ETH"}
+	if err := notifier.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	got := <-mail
+	if !strings.Contains(got, "Subject: [transaction-tracker] [critical] Large transfer") || !strings.Contains(got, "Large transfer: 250 ETH") {
+		t.Errorf("mail = %q; want the subject and message of the alert", got)
+	}
+}
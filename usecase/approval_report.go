@@ -0,0 +1,310 @@
+package usecase
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+const (
+	approvalSignature       = "Approval(address,address,uint256)"
+	approvalForAllSignature = "ApprovalForAll(address,address,bool)"
+	approveSignature        = "approve(address,uint256)"
+	setApprovalForAllSig    = "setApprovalForAll(address,bool)"
+	zeroAddress             = "0x0000000000000000000000000000000000000000"
+)
+
+var (
+	// Some tokens (e.g. UNI, COMP) store allowances as uint96 and cap an unlimited approval to its maximum
+	maxUint96Allowance = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+)
+
+// approvalGrant is a single approval with its position on chain, used to find the latest one
+type approvalGrant struct {
+	key      string
+	row      models.ApprovalReportResponse
+	block    int64
+	txIndex  int64
+	subIndex int64 // log index, -1 for calldata so the emitted event of the same tx wins
+}
+
+// generateApprovalReport collects the approvals from the event logs and the calls sent by the wallet.
+func generateApprovalReport(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, opts ReportOptions, walletAddress string) (int, error) {
+	logs, err := FetchEventLogs(ctx, dataProvider, walletAddress)
+	if err != nil {
+		logging.FromContext(ctx).Error("fetching event logs failed", logging.KeyError, err)
+		return 0, err
+	}
+	txList, err := FetchExternalTransactions(ctx, dataProvider, walletAddress)
+	if err != nil {
+		logging.FromContext(ctx).Error("fetching transaction data failed", logging.KeyError, err)
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	csvResp := BuildApprovalRows(logs, txList, opts, walletAddress)
+	for _, row := range csvResp {
+		opts.Alerts.ObserveApproval(ctx, row, walletAddress, opts)
+	}
+	return writeApprovalReport(ctx, csvResp, opts, walletAddress)
+}
+
+/*
+Build the approvals audit report from the wallet's Approval/ApprovalForAll events and the
+approve/setApprovalForAll calls it sent. Only the latest approval per token and spender
+(and token ID for single ERC-721 approvals) is kept. increaseAllowance/decreaseAllowance are
+covered by the Approval event they emit, the resulting allowance is not known from calldata.
+*/
+func ApprovalReport(ctx context.Context, logs []models.EventLog, txList []models.ExternalTransaction, opts ReportOptions, walletAddress string) (int, error) {
+	return writeApprovalReport(ctx, BuildApprovalRows(logs, txList, opts, walletAddress), opts, walletAddress)
+}
+
+func writeApprovalReport(ctx context.Context, csvResp []models.ApprovalReportResponse, opts ReportOptions, walletAddress string) (int, error) {
+	if len(csvResp) == 0 {
+		logging.FromContext(ctx).Info("no token approvals found", logging.KeyWallet, walletAddress)
+		return 0, nil
+	}
+
+	report, err := newReportWriter[models.ApprovalReportResponse](ctx, opts, walletAddress, constants.APPROVAL_REPORT)
+	if err != nil {
+		logging.FromContext(ctx).Error("resolving report path failed", logging.KeyError, err)
+		return 0, err
+	}
+	for _, row := range csvResp {
+		if err := report.Write(row); err != nil {
+			return finishReports(err, report)
+		}
+	}
+
+	rows, err := finishReports(nil, report)
+	if err != nil {
+		logging.FromContext(ctx).Error("writing approval report failed", logging.KeyError, err)
+		return 0, err
+	}
+	return rows, nil
+}
+
+// BuildApprovalRows returns the latest approval per token and spender, sorted by token and spender.
+func BuildApprovalRows(logs []models.EventLog, txList []models.ExternalTransaction, opts ReportOptions, walletAddress string) []models.ApprovalReportResponse {
+	eventGrants := approvalsFromLogs(logs, opts, walletAddress)
+	grants := append(eventGrants, approvalsFromCalldata(txList, eventGrants, opts, walletAddress)...)
+
+	sort.SliceStable(grants, func(i, j int) bool {
+		if grants[i].block != grants[j].block {
+			return grants[i].block < grants[j].block
+		}
+		if grants[i].txIndex != grants[j].txIndex {
+			return grants[i].txIndex < grants[j].txIndex
+		}
+		return grants[i].subIndex < grants[j].subIndex
+	})
+
+	latest := map[string]models.ApprovalReportResponse{}
+	for _, grant := range grants {
+		latest[grant.key] = grant.row
+	}
+
+	csvResp := make([]models.ApprovalReportResponse, 0, len(latest))
+	for _, row := range latest {
+		row.Chain = opts.Chain.Name
+		row.TokenLabel = opts.AddressBook.Label(row.TokenAddress)
+		row.SpenderLabel = opts.AddressBook.Label(row.SpenderAddress)
+		csvResp = append(csvResp, row)
+	}
+	// Sorted by the lower case addresses so the order is the hex order, not that of the EIP-55 case
+	sort.Slice(csvResp, func(i, j int) bool {
+		if tokenI, tokenJ := normalizeAddress(csvResp[i].TokenAddress), normalizeAddress(csvResp[j].TokenAddress); tokenI != tokenJ {
+			return tokenI < tokenJ
+		}
+		if spenderI, spenderJ := normalizeAddress(csvResp[i].SpenderAddress), normalizeAddress(csvResp[j].SpenderAddress); spenderI != spenderJ {
+			return spenderI < spenderJ
+		}
+		return csvResp[i].TokenID < csvResp[j].TokenID
+	})
+	return csvResp
+}
+
+func approvalsFromLogs(logs []models.EventLog, opts ReportOptions, walletAddress string) []approvalGrant {
+	grants := []approvalGrant{}
+	for _, log := range logs {
+		decoded, err := opts.AbiRegistry.DecodeLog(log.Address, log.Topics, log.Data)
+		if err != nil || len(decoded.Arguments) != 3 {
+			continue
+		}
+
+		// Arguments are read by position, contract ABIs may name them differently (e.g. WETH's src/guy/wad)
+		owner := decoded.Arguments[0].Value
+		if !strings.EqualFold(owner, walletAddress) {
+			continue
+		}
+
+		row := models.ApprovalReportResponse{
+			TokenAddress:    util.ChecksumAddress(log.Address),
+			SpenderAddress:  util.ChecksumAddress(decoded.Arguments[1].Value),
+			TransactionHash: log.TransactionHash,
+			Source:          constants.APPROVAL_SOURCE_EVENT_LOG,
+		}
+
+		switch decoded.Signature {
+		case approvalSignature:
+			if len(log.Topics) == 4 {
+				// ERC-721 single token approval, the spender is cleared by approving the zero address
+				row.TokenStandard = constants.TOKEN_STANDARD_ERC721
+				row.TokenID = decoded.Arguments[2].Value
+				row.Allowance = "1"
+				row.Status = approvalStatus(row.SpenderAddress != zeroAddress)
+			} else {
+				row.TokenStandard = constants.TOKEN_STANDARD_ERC20
+				row.Allowance = decoded.Arguments[2].Value
+				row.Unlimited = isUnlimitedAllowance(row.Allowance)
+				row.Status = approvalStatus(row.Allowance != "0")
+			}
+		case approvalForAllSignature:
+			row.TokenStandard = constants.TOKEN_STANDARD_ERC721
+			row.Allowance = constants.APPROVAL_ALLOWANCE_ALL
+			row.Unlimited = decoded.Arguments[2].Value == "true"
+			row.Status = approvalStatus(row.Unlimited)
+		default:
+			continue
+		}
+
+		timestamp, _ := util.HexToDecimalString(log.TimeStamp)
+		row.GrantedAt, _ = util.FormatUnixTimestampString(timestamp)
+		row.BlockNumber, _ = util.HexToDecimalString(log.BlockNumber)
+
+		grants = append(grants, approvalGrant{
+			key:      approvalKey(row),
+			row:      row,
+			block:    hexToInt(log.BlockNumber),
+			txIndex:  hexToInt(log.TransactionIndex),
+			subIndex: hexToInt(log.LogIndex),
+		})
+	}
+	return grants
+}
+
+/*
+Approvals of the calls sent by the wallet. ERC-20 and ERC-721 approve share the selector, the events
+tell them apart: calls that emitted an Approval event are covered by the event, and approve calls to
+tokens with single ERC-721 approvals in the events are ERC-721 approvals of the token ID.
+*/
+func approvalsFromCalldata(txList []models.ExternalTransaction, eventGrants []approvalGrant, opts ReportOptions, walletAddress string) []approvalGrant {
+	withEvent := map[string]bool{}
+	nftTokens := map[string]bool{}
+	for _, grant := range eventGrants {
+		withEvent[strings.ToLower(grant.row.TransactionHash)] = true
+		if grant.row.TokenStandard == constants.TOKEN_STANDARD_ERC721 && grant.row.TokenID != "" {
+			nftTokens[normalizeAddress(grant.row.TokenAddress)] = true
+		}
+	}
+
+	grants := []approvalGrant{}
+	for _, tx := range txList {
+		if !strings.EqualFold(tx.From, walletAddress) || tx.IsError == "1" || !hasCalldata(tx.Input) || withEvent[strings.ToLower(tx.Hash)] {
+			continue
+		}
+
+		call, err := opts.AbiRegistry.DecodeInput(tx.To, tx.Input)
+		if err != nil || len(call.Arguments) != 2 {
+			continue
+		}
+
+		row := models.ApprovalReportResponse{
+			TokenAddress:    util.ChecksumAddress(tx.To),
+			SpenderAddress:  util.ChecksumAddress(call.Arguments[0].Value),
+			TransactionHash: tx.Hash,
+			BlockNumber:     tx.BlockNumber,
+			Source:          constants.APPROVAL_SOURCE_CALLDATA,
+		}
+		row.GrantedAt, _ = util.FormatUnixTimestampString(tx.TimeStamp)
+
+		switch call.Signature {
+		case approveSignature:
+			if nftTokens[normalizeAddress(tx.To)] {
+				row.TokenStandard = constants.TOKEN_STANDARD_ERC721
+				row.TokenID = call.Arguments[1].Value
+				row.Allowance = "1"
+				row.Status = approvalStatus(row.SpenderAddress != zeroAddress)
+				break
+			}
+			row.TokenStandard = constants.TOKEN_STANDARD_ERC20
+			row.Allowance = call.Arguments[1].Value
+			row.Unlimited = isUnlimitedAllowance(row.Allowance)
+			row.Status = approvalStatus(row.Allowance != "0")
+		case setApprovalForAllSig:
+			row.TokenStandard = constants.TOKEN_STANDARD_ERC721
+			row.Allowance = constants.APPROVAL_ALLOWANCE_ALL
+			row.Unlimited = call.Arguments[1].Value == "true"
+			row.Status = approvalStatus(row.Unlimited)
+		default:
+			continue
+		}
+
+		block, _ := util.StringToInt(tx.BlockNumber)
+		txIndex, _ := util.StringToInt(tx.TransactionIndex)
+		grants = append(grants, approvalGrant{
+			key:      approvalKey(row),
+			row:      row,
+			block:    block,
+			txIndex:  txIndex,
+			subIndex: -1,
+		})
+	}
+	return grants
+}
+
+/*
+Approvals are tracked per token and spender. Single ERC-721 approvals are tracked per token ID
+instead since a token has exactly one approved address.
+*/
+func approvalKey(row models.ApprovalReportResponse) string {
+	if row.TokenStandard == constants.TOKEN_STANDARD_ERC721 && row.TokenID != "" {
+		return row.TokenAddress + ":id:" + row.TokenID
+	}
+	if row.Allowance == constants.APPROVAL_ALLOWANCE_ALL {
+		return row.TokenAddress + ":all:" + strings.ToLower(row.SpenderAddress)
+	}
+	return row.TokenAddress + ":" + strings.ToLower(row.SpenderAddress)
+}
+
+func approvalStatus(active bool) string {
+	if active {
+		return constants.APPROVAL_STATUS_ACTIVE
+	}
+	return constants.APPROVAL_STATUS_REVOKED
+}
+
+/*
+An allowance is considered unlimited when it is at least the uint96 maximum, which covers
+type(uint256).max as well as tokens capping allowances to uint96.
+*/
+func isUnlimitedAllowance(value string) bool {
+	allowance, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return false
+	}
+	return allowance.Cmp(maxUint96Allowance) >= 0
+}
+
+func hexToInt(value string) int64 {
+	decimal, err := util.HexToDecimalString(value)
+	if err != nil {
+		return 0
+	}
+	res, _ := util.StringToInt(decimal)
+	return res
+}
+
+// FetchExternalTransactions fetches and unmarshals the wallet's external transactions (txlist).
+func FetchExternalTransactions(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, walletAddress string) ([]models.ExternalTransaction, error) {
+	return FetchResult[models.ExternalTransaction](ctx, dataProvider, walletAddress, constants.EXTERNAL_REPORT_ACTION, constants.APPROVAL_REPORT)
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestBuildApprovalRows(t *testing.T) {
+	wallet := "0x00000000000000000000000000000000000000aa"
+	spender := "0x00000000000000000000000000000000000000bb"
+	token := "0x00000000000000000000000000000000000000cc"
+	nft := "0x00000000000000000000000000000000000000dd"
+	collection := "0x00000000000000000000000000000000000000ee"
+
+	registry := abi.NewRegistry("", "", nil)
+	for _, standard := range abi.StandardABIs() {
+		registry.AddFallback(standard)
+	}
+	opts := ReportOptions{AddressBook: NewAddressBook(nil), AbiRegistry: registry}
+
+	pad := func(hexValue string) string {
+		return strings.Repeat("0", 64-len(hexValue)) + hexValue
+	}
+	approvalTopic := "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	approvalForAllTopic := "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
+
+	txList := []models.ExternalTransaction{
+		{
+			// approve(spender, max uint256) sent first
+			Hash:             "0x01",
+			BlockNumber:      "100",
+			TransactionIndex: "1",
+			TimeStamp:        "1710298091",
+			From:             wallet,
+			To:               token,
+			IsError:          "0",
+			Input:            "0x095ea7b3" + pad(spender[2:]) + strings.Repeat("f", 64),
+		},
+		{
+			// ERC-721 approve(spender, token 42), its Approval event is fetched as well
+			Hash:             "0x05",
+			BlockNumber:      "202",
+			TransactionIndex: "0",
+			TimeStamp:        "1710298091",
+			From:             wallet,
+			To:               collection,
+			IsError:          "0",
+			Input:            "0x095ea7b3" + pad(spender[2:]) + pad("2a"),
+		},
+		{
+			// ERC-721 approve(spender, token 7) without its event, the collection is known from the one above
+			Hash:             "0x06",
+			BlockNumber:      "203",
+			TransactionIndex: "0",
+			TimeStamp:        "1710298091",
+			From:             wallet,
+			To:               collection,
+			IsError:          "0",
+			Input:            "0x095ea7b3" + pad(spender[2:]) + pad("7"),
+		},
+	}
+	logs := []models.EventLog{
+		{
+			// Later Approval event lowering the allowance to 5
+			Address:          token,
+			Topics:           []string{approvalTopic, "0x" + pad(wallet[2:]), "0x" + pad(spender[2:])},
+			Data:             "0x" + pad("5"),
+			BlockNumber:      "0xc8",
+			TransactionIndex: "0x0",
+			LogIndex:         "0x3",
+			TimeStamp:        "0x65f1146b",
+			TransactionHash:  "0x02",
+		},
+		{
+			Address:          nft,
+			Topics:           []string{approvalForAllTopic, "0x" + pad(wallet[2:]), "0x" + pad(spender[2:])},
+			Data:             "0x" + pad("1"),
+			BlockNumber:      "0xc8",
+			TransactionIndex: "0x1",
+			LogIndex:         "0x4",
+			TimeStamp:        "0x65f1146b",
+			TransactionHash:  "0x03",
+		},
+		{
+			// Approval where the wallet is the spender is not ours to audit
+			Address:          token,
+			Topics:           []string{approvalTopic, "0x" + pad(spender[2:]), "0x" + pad(wallet[2:])},
+			Data:             "0x" + pad("9"),
+			BlockNumber:      "0xc9",
+			TransactionIndex: "0x0",
+			LogIndex:         "0x0",
+			TimeStamp:        "0x65f1146b",
+			TransactionHash:  "0x04",
+		},
+		{
+			Address:          collection,
+			Topics:           []string{approvalTopic, "0x" + pad(wallet[2:]), "0x" + pad(spender[2:]), "0x" + pad("2a")},
+			Data:             "0x",
+			BlockNumber:      "0xca",
+			TransactionIndex: "0x0",
+			LogIndex:         "0x0",
+			TimeStamp:        "0x65f1146b",
+			TransactionHash:  "0x05",
+		},
+	}
+
+	rows := BuildApprovalRows(logs, txList, opts, wallet)
+	if len(rows) != 4 {
+		t.Fatalf("BuildApprovalRows returned %d rows; want 4: %+v", len(rows), rows)
+	}
+
+	erc20 := rows[0]
+	if erc20.TokenAddress != token || erc20.Allowance != "5" || erc20.Unlimited || erc20.TransactionHash != "0x02" {
+		t.Errorf("ERC-20 approval = %+v; want allowance 5 from tx 0x02", erc20)
+	}
+
+	nftApproval := rows[1]
+	if nftApproval.TokenAddress != nft || nftApproval.Allowance != constants.APPROVAL_ALLOWANCE_ALL || !nftApproval.Unlimited || nftApproval.Status != constants.APPROVAL_STATUS_ACTIVE {
+		t.Errorf("ERC-721 approval = %+v; want active approval for all", nftApproval)
+	}
+
+	// The approve call of tx 0x05 is covered by its event, no ERC-20 allowance of 42 is reported
+	tokenApproval := rows[2]
+	if tokenApproval.TokenStandard != constants.TOKEN_STANDARD_ERC721 || tokenApproval.TokenID != "42" || tokenApproval.Allowance != "1" || tokenApproval.Source != constants.APPROVAL_SOURCE_EVENT_LOG {
+		t.Errorf("ERC-721 token approval = %+v; want token 42 from the event", tokenApproval)
+	}
+	calldataApproval := rows[3]
+	if calldataApproval.TokenStandard != constants.TOKEN_STANDARD_ERC721 || calldataApproval.TokenID != "7" || calldataApproval.Allowance != "1" || calldataApproval.Source != constants.APPROVAL_SOURCE_CALLDATA {
+		t.Errorf("ERC-721 token approval = %+v; want token 7 from the calldata", calldataApproval)
+	}
+}
+
+func TestIsUnlimitedAllowance(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "115792089237316195423570985008687907853269984665640564039457584007913129639935", want: true},
+		{value: "79228162514264337593543950335", want: true}, // uint96 max
+		{value: "1000000000000000000", want: false},
+		{value: "not-a-number", want: false},
+	}
+
+	for _, tc := range tests {
+		if got := isUnlimitedAllowance(tc.value); got != tc.want {
+			t.Errorf("isUnlimitedAllowance(%q) = %v; want %v", tc.value, got, tc.want)
+		}
+	}
+}
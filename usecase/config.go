@@ -0,0 +1,308 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+LoadConfig reads the config file and applies, in this order, the keys of the named profile,
+the TRACKER_* environment overrides and the secrets. An empty profile falls back to
+TRACKER_PROFILE, without either only the top level keys are used.
+*/
+func LoadConfig(path, profile string) (models.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config := models.Config{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return models.Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if profile == "" {
+		profile = os.Getenv(constants.CONFIG_PROFILE_ENV)
+	}
+	if profile != "" {
+		if err := applyProfile(data, profile, &config); err != nil {
+			return models.Config{}, err
+		}
+	}
+
+	if err := ApplyEnvOverrides(&config); err != nil {
+		return models.Config{}, err
+	}
+	if err := ResolveSecrets(&config); err != nil {
+		return models.Config{}, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	return config, nil
+}
+
+/*
+Decode the keys of a profile over the config. Keys missing in the profile keep their value,
+nested keys and maps are merged and lists replace the list of the config.
+*/
+func applyProfile(data []byte, profile string, config *models.Config) error {
+	file := struct {
+		Profiles map[string]yaml.Node `yaml:"PROFILES"`
+	}{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config profiles: %w", err)
+	}
+
+	node, ok := file.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for name := range file.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown config profile '%s', PROFILES defines [%s]", profile, strings.Join(names, ", "))
+	}
+	if err := node.Decode(config); err != nil {
+		return fmt.Errorf("failed to parse config profile %s: %w", profile, err)
+	}
+	return nil
+}
+
+/*
+ApplyEnvOverrides overrides every config key set as TRACKER_<KEY PATH>, the path joins the
+YAML keys with an underscore, e.g. TRACKER_ETHERSCAN_API_KEY or TRACKER_WATCH_WEBHOOK_URL.
+Strings are taken verbatim, all other values are parsed as YAML, e.g. TRACKER_CHAINS='[ethereum, base]'.
+*/
+func ApplyEnvOverrides(config *models.Config) error {
+	return applyEnvOverrides(reflect.ValueOf(config).Elem(), constants.CONFIG_ENV_PREFIX)
+}
+
+func applyEnvOverrides(value reflect.Value, prefix string) error {
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + key
+		field := value.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvOverrides(field, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		override, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if field.Kind() == reflect.String {
+			field.SetString(override)
+			continue
+		}
+		// Maps and lists are replaced, not merged with the config file
+		field.Set(reflect.Zero(field.Type()))
+		if err := yaml.Unmarshal([]byte(override), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+/*
+ValidateConfig checks the config before any request is sent and returns all problems joined:
+wallet addresses (hex and EIP-55 checksum, or ENS names with a resolver), URLs, the API key and
+endpoints of every enabled provider, the ENS resolver, chains, report types, retries and the log
+config. Commands that take the wallet from elsewhere, like serve, pass requireWallets false.
+*/
+func ValidateConfig(config models.Config, providerType string, requireWallets bool) error {
+	problems := []error{}
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	// Wallets
+	ensNames := config.Ens.Resolver != ""
+	if address := strings.TrimSpace(config.WalletAddress); address != "" {
+		if err := validateWallet(address, ensNames); err != nil {
+			problem("WALLET_ADDRESS: %w", err)
+		}
+	}
+	for i, wallet := range config.Wallets {
+		address := strings.TrimSpace(wallet.Address)
+		if address == "" {
+			problem("WALLETS[%d].ADDRESS is empty", i)
+			continue
+		}
+		if err := validateWallet(address, ensNames); err != nil {
+			problem("WALLETS[%d].ADDRESS: %w", i, err)
+		}
+	}
+	if requireWallets && len(ConfiguredWallets(config)) == 0 {
+		problem("no wallet configured, set WALLET_ADDRESS or WALLETS")
+	}
+
+	// URLs
+	urls := map[string]string{
+		"ETHERSCAN.BASE_URL":  config.Etherscan.BaseURL,
+		"BLOCKSCOUT.BASE_URL": config.Blockscout.BaseURL,
+		"WATCH.WEBHOOK.URL":   config.Watch.Webhook.URL,
+		"ALERTS.WEBHOOK.URL":  config.Alerts.Webhook.URL,
+	}
+	for chain, baseURL := range config.Etherscan.BaseURLs {
+		urls["ETHERSCAN.BASE_URLS."+chain] = baseURL
+	}
+	for chain, baseURL := range config.Blockscout.BaseURLs {
+		urls["BLOCKSCOUT.BASE_URLS."+chain] = baseURL
+	}
+	for chain, endpoint := range config.Rpc.Endpoints {
+		urls["RPC.ENDPOINTS."+chain] = endpoint
+	}
+	keys := make([]string, 0, len(urls))
+	for key := range urls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if urls[key] == "" {
+			continue
+		}
+		if err := validateURL(urls[key]); err != nil {
+			problem("%s: %w", key, err)
+		}
+	}
+
+	// Chains and the providers enabled for them
+	chainList, err := chains.Resolve(config.Chains)
+	if err != nil {
+		problem("CHAINS: %w", err)
+	}
+	providers := []string{providerType}
+	if strings.EqualFold(providerType, constants.PROVIDER_FAILOVER) {
+		providers = config.Providers
+	}
+	// Replayed and offline runs send no requests, keys and endpoints are not needed
+	sendsRequests := config.Fixtures.ReplayDirectory == "" && !config.Cache.Offline
+	for _, provider := range providers {
+		switch strings.ToLower(provider) {
+		case constants.PROVIDER_ETHERSCAN:
+			if sendsRequests && config.Etherscan.ApiKey == "" {
+				problem("ETHERSCAN.API_KEY is required by the etherscan provider, set API_KEY, API_KEY_ENV or API_KEY_FILE")
+			}
+			if config.Etherscan.Retries <= 0 {
+				problem("ETHERSCAN.RETRIES must be positive, got %d", config.Etherscan.Retries)
+			}
+		case constants.PROVIDER_BLOCKSCOUT:
+			// Instances serve a single chain, BASE_URL would serve the data of one chain for all of them
+			for _, chain := range chainList {
+				switch {
+				case config.Blockscout.BaseURLs[chain.Name] != "":
+				case len(chainList) > 1:
+					problem("BLOCKSCOUT.BASE_URLS.%s is required by the blockscout provider with more than one chain", chain.Name)
+				case config.Blockscout.BaseURL == "":
+					problem("BLOCKSCOUT.BASE_URL or BLOCKSCOUT.BASE_URLS.%s is required by the blockscout provider", chain.Name)
+				}
+			}
+			if config.Blockscout.Retries <= 0 {
+				problem("BLOCKSCOUT.RETRIES must be positive, got %d", config.Blockscout.Retries)
+			}
+		case constants.PROVIDER_RPC:
+			for _, chain := range chainList {
+				if sendsRequests && config.Rpc.Endpoints[chain.Name] == "" {
+					problem("RPC.ENDPOINTS.%s is required by the rpc provider", chain.Name)
+				}
+			}
+		case constants.PROVIDER_FAILOVER:
+			problem("PROVIDERS: failover can not contain itself")
+		default:
+			problem("unknown provider '%s', expected etherscan, blockscout or rpc", provider)
+		}
+	}
+
+	// ENS
+	switch strings.ToLower(config.Ens.Resolver) {
+	case "":
+		if config.Ens.ReverseNames {
+			problem("ENS.REVERSE_NAMES requires ENS.RESOLVER")
+		}
+	case constants.ENS_RESOLVER_FILE:
+		if config.Ens.File == "" {
+			problem("ENS.FILE is required by the file resolver")
+		}
+	case constants.ENS_RESOLVER_RPC:
+		ensChain := config.Ens.Chain
+		if ensChain == "" {
+			ensChain = constants.CHAIN_ETHEREUM
+		}
+		if _, err := chains.Lookup(ensChain); err != nil {
+			problem("ENS.CHAIN: %w", err)
+		} else if config.Rpc.Endpoints[strings.ToLower(ensChain)] == "" {
+			problem("RPC.ENDPOINTS.%s is required by the rpc ENS resolver", strings.ToLower(ensChain))
+		}
+	default:
+		problem("unknown ENS.RESOLVER '%s', expected rpc or file", config.Ens.Resolver)
+	}
+
+	for _, reportType := range config.ReportTypes {
+		if _, err := LookupReportType(reportType); err != nil {
+			problem("REPORT_TYPES: %w", err)
+		}
+	}
+
+	// Retries
+	if config.Failover.FailureThreshold < 0 {
+		problem("FAILOVER.FAILURE_THRESHOLD must not be negative, got %d", config.Failover.FailureThreshold)
+	}
+	for name, webhookConfig := range map[string]models.WebhookConfig{"WATCH": config.Watch.Webhook, "ALERTS": config.Alerts.Webhook} {
+		if webhookConfig.MaxRetries < -1 {
+			problem("%s.WEBHOOK.MAX_RETRIES must be -1 or more, got %d", name, webhookConfig.MaxRetries)
+		}
+	}
+
+	if _, err := logging.New(config.Log, io.Discard); err != nil {
+		problem("LOG: %w", err)
+	}
+
+	return errors.Join(problems...)
+}
+
+// validateWallet checks a hex address and, when it is mixed case, its EIP-55 checksum, ENS names need a resolver
+func validateWallet(address string, ensNames bool) error {
+	if util.IsENSName(address) {
+		if !ensNames {
+			return fmt.Errorf("'%s' is an ENS name, set ENS.RESOLVER to resolve it", address)
+		}
+		return nil
+	}
+	if !util.IsHexAddress(address) {
+		return fmt.Errorf("'%s' is not a 0x prefixed 20 byte hex address", address)
+	}
+	if !util.IsChecksumAddress(address) {
+		return fmt.Errorf("'%s' has an invalid EIP-55 checksum, expected %s", address, util.ChecksumAddress(address))
+	}
+	return nil
+}
+
+// validateURL checks for an absolute http or https URL, the URL is redacted in the error
+func validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s'", util.RedactURL(rawURL))
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("'%s' is not an http or https URL", util.RedactURL(rawURL))
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+)
+
+const testConfigFile = `
+ETHERSCAN:
+  API_KEY: "base-key"
+  RETRIES: 3
+RPC:
+  ENDPOINTS:
+    ethereum: "https://eth.example.com"
+WALLET_ADDRESS: "0x1111111111111111111111111111111111111111"
+CHAINS: [ethereum, polygon]
+PROFILES:
+  testnet:
+    ETHERSCAN:
+      BASE_URL: "https://api-sepolia.example.com/api"
+    RPC:
+      ENDPOINTS:
+        sepolia: "https://sepolia.example.com"
+    CHAINS: [sepolia]
+`
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Base", func(t *testing.T) {
+		config, err := LoadConfig(path, "")
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if !reflect.DeepEqual(config.Chains, []string{"ethereum", "polygon"}) || config.Etherscan.BaseURL != "" {
+			t.Errorf("LoadConfig() applied a profile without one being selected: %+v", config)
+		}
+	})
+
+	t.Run("Profile", func(t *testing.T) {
+		config, err := LoadConfig(path, "testnet")
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if !reflect.DeepEqual(config.Chains, []string{"sepolia"}) {
+			t.Errorf("Chains = %v; want the list of the profile", config.Chains)
+		}
+		if config.Etherscan.BaseURL != "https://api-sepolia.example.com/api" || config.Etherscan.ApiKey != "base-key" || config.Etherscan.Retries != 3 {
+			t.Errorf("Etherscan = %+v; want the profile merged over the base", config.Etherscan)
+		}
+		if len(config.Rpc.Endpoints) != 2 {
+			t.Errorf("Rpc.Endpoints = %v; want the endpoints of the base and the profile", config.Rpc.Endpoints)
+		}
+	})
+
+	t.Run("Profile From Environment", func(t *testing.T) {
+		t.Setenv("TRACKER_PROFILE", "testnet")
+		config, err := LoadConfig(path, "")
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if !reflect.DeepEqual(config.Chains, []string{"sepolia"}) {
+			t.Errorf("Chains = %v; want the profile of TRACKER_PROFILE", config.Chains)
+		}
+	})
+
+	t.Run("Unknown Profile", func(t *testing.T) {
+		_, err := LoadConfig(path, "prod")
+		if err == nil || !strings.Contains(err.Error(), "[testnet]") {
+			t.Errorf("LoadConfig() error = %v; want the defined profiles listed", err)
+		}
+	})
+
+	t.Run("Environment Overrides", func(t *testing.T) {
+		t.Setenv("TRACKER_ETHERSCAN_API_KEY", "override-api-key")
+		t.Setenv("TRACKER_WALLET_ADDRESS", "0x2222222222222222222222222222222222222222")
+		t.Setenv("TRACKER_CHAINS", "[base]")
+		t.Setenv("TRACKER_RPC_ENDPOINTS", "{base: 'https://base.example.com'}")
+		t.Setenv("TRACKER_FAIL_FAST", "true")
+		t.Setenv("TRACKER_WATCH_WEBHOOK_MAX_RETRIES", "-1")
+
+		config, err := LoadConfig(path, "testnet")
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if config.Etherscan.ApiKey != "override-api-key" || config.WalletAddress != "0x2222222222222222222222222222222222222222" {
+			t.Errorf("string overrides not applied: %+v", config)
+		}
+		if !reflect.DeepEqual(config.Chains, []string{"base"}) || !reflect.DeepEqual(config.Rpc.Endpoints, map[string]string{"base": "https://base.example.com"}) {
+			t.Errorf("Chains = %v, Rpc.Endpoints = %v; want them replaced by the overrides", config.Chains, config.Rpc.Endpoints)
+		}
+		if !config.FailFast || config.Watch.Webhook.MaxRetries != -1 {
+			t.Errorf("FailFast = %v, MaxRetries = %d; want the overrides", config.FailFast, config.Watch.Webhook.MaxRetries)
+		}
+	})
+
+	t.Run("Invalid Override", func(t *testing.T) {
+		t.Setenv("TRACKER_START_BLOCK", "latest")
+		if _, err := LoadConfig(path, ""); err == nil || !strings.Contains(err.Error(), "TRACKER_START_BLOCK") {
+			t.Errorf("LoadConfig() error = %v; want the invalid variable named", err)
+		}
+	})
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := func() models.Config {
+		return models.Config{
+			Etherscan:     models.ThirdPartyApiConfig{ApiKey: "key", Retries: 3},
+			WalletAddress: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		}
+	}
+
+	tests := []struct {
+		name           string
+		modify         func(config *models.Config)
+		providerType   string
+		requireWallets bool
+		wantErrors     []string
+	}{
+		{name: "Valid", modify: func(config *models.Config) {}, providerType: "etherscan", requireWallets: true},
+		{
+			name:           "Empty Wallet",
+			modify:         func(config *models.Config) { config.WalletAddress = "" },
+			providerType:   "etherscan",
+			requireWallets: true,
+			wantErrors:     []string{"no wallet configured"},
+		},
+		{
+			name:         "No Wallet For Serve",
+			modify:       func(config *models.Config) { config.WalletAddress = "" },
+			providerType: "etherscan",
+		},
+		{
+			name: "Invalid Addresses",
+			modify: func(config *models.Config) {
+				config.WalletAddress = "0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+				config.Wallets = []models.WalletConfig{{Address: "0x1234"}, {Label: "Treasury"}}
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"WALLET_ADDRESS: '0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed' has an invalid EIP-55 checksum, expected 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "WALLETS[0].ADDRESS", "WALLETS[1].ADDRESS is empty"},
+		},
+		{
+			name:         "Missing API Key",
+			modify:       func(config *models.Config) { config.Etherscan.ApiKey = "" },
+			providerType: "etherscan",
+			wantErrors:   []string{"ETHERSCAN.API_KEY is required"},
+		},
+		{
+			name: "Replay Without API Key",
+			modify: func(config *models.Config) {
+				config.Etherscan.ApiKey = ""
+				config.Fixtures.ReplayDirectory = "testdata/fixtures/ethereum"
+			},
+			providerType: "etherscan",
+		},
+		{
+			name: "Failover Providers",
+			modify: func(config *models.Config) {
+				config.Providers = []string{"blockscout", "rpc", "covalent"}
+				config.Chains = []string{"ethereum", "base"}
+				config.Rpc.Endpoints = map[string]string{"ethereum": "https://eth.example.com"}
+				config.Blockscout.BaseURLs = map[string]string{"ethereum": "https://eth.blockscout.com/api", "base": "https://base.blockscout.com/api"}
+			},
+			providerType: "failover",
+			wantErrors:   []string{"RPC.ENDPOINTS.base is required", "unknown provider 'covalent'"},
+		},
+		{
+			name: "Blockscout Base URL With Several Chains",
+			modify: func(config *models.Config) {
+				config.Chains = []string{"ethereum", "polygon"}
+				config.Blockscout = models.ThirdPartyApiConfig{
+					BaseURL:  "https://eth.blockscout.com/api",
+					BaseURLs: map[string]string{"ethereum": "https://eth.blockscout.com/api"},
+					Retries:  3,
+				}
+			},
+			providerType: "blockscout",
+			wantErrors:   []string{"BLOCKSCOUT.BASE_URLS.polygon is required by the blockscout provider with more than one chain"},
+		},
+		{
+			name: "Invalid URLs",
+			modify: func(config *models.Config) {
+				config.Etherscan.BaseURL = "api.etherscan.io/v2/api"
+				config.Watch.Webhook.URL = "ftp://hooks.example.com"
+				config.Rpc.Endpoints = map[string]string{"ethereum": "wss://eth.example.com/v2/secret"}
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"ETHERSCAN.BASE_URL", "WATCH.WEBHOOK.URL", "RPC.ENDPOINTS.ethereum"},
+		},
+		{
+			name: "Invalid Retries",
+			modify: func(config *models.Config) {
+				config.Etherscan.Retries = 0
+				config.Alerts.Webhook.MaxRetries = -2
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"ETHERSCAN.RETRIES must be positive, got 0", "ALERTS.WEBHOOK.MAX_RETRIES must be -1 or more"},
+		},
+		{
+			name: "ENS Names",
+			modify: func(config *models.Config) {
+				config.WalletAddress = "treasury.eth"
+				config.Ens = models.EnsConfig{Resolver: "rpc", ReverseNames: true}
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"RPC.ENDPOINTS.ethereum is required by the rpc ENS resolver"},
+		},
+		{
+			name: "ENS Names Without Resolver",
+			modify: func(config *models.Config) {
+				config.WalletAddress = "treasury.eth"
+				config.Ens.ReverseNames = true
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"'treasury.eth' is an ENS name, set ENS.RESOLVER", "ENS.REVERSE_NAMES requires ENS.RESOLVER"},
+		},
+		{
+			name: "Unknown Names",
+			modify: func(config *models.Config) {
+				config.Chains = []string{"solana"}
+				config.ReportTypes = []string{"NFT"}
+				config.Log.Level = "verbose"
+			},
+			providerType: "etherscan",
+			wantErrors:   []string{"CHAINS:", "REPORT_TYPES:", "LOG:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(&config)
+			err := ValidateConfig(config, tt.providerType, tt.requireWallets)
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v; want none", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateConfig() error = nil; want %v", tt.wantErrors)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateConfig() error = %v; want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+/*
+DetailedReportRow returns the external transaction with its calldata decoded into method name and named
+arguments. Transactions without calldata (plain ETH transfers) have empty method columns.
+*/
+func DetailedReportRow(ctx context.Context, tx models.ExternalTransaction, opts ReportOptions) models.DetailedReportResponse {
+	dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
+	row := models.DetailedReportResponse{
+		Chain:            opts.Chain.Name,
+		TransactionHash:  tx.Hash,
+		DateTime:         dateTime,
+		FromAddress:      util.ChecksumAddress(tx.From),
+		FromLabel:        opts.AddressBook.Label(tx.From),
+		ToAddress:        util.ChecksumAddress(tx.To),
+		ToLabel:          opts.AddressBook.Label(tx.To),
+		ValueAmount:      tx.Value,
+		MethodID:         tx.MethodId,
+		IsError:          tx.IsError,
+		ExplorerFunction: tx.FunctionName,
+	}
+
+	if hasCalldata(tx.Input) {
+		call, err := opts.AbiRegistry.DecodeInput(tx.To, tx.Input)
+		if err != nil {
+			if !errors.Is(err, abi.ErrABINotFound) {
+				logging.FromContext(ctx).Warn("could not decode input", "tx", tx.Hash, logging.KeyError, err)
+			}
+			row.DecodeError = err.Error()
+		} else {
+			row.MethodID = call.MethodID
+			row.MethodName = call.MethodName
+			row.MethodSignature = call.Signature
+			row.DecodedArguments = abi.FormatArguments(call.Arguments)
+		}
+	}
+	return row
+}
+
+func hasCalldata(input string) bool {
+	return len(input) >= 10 && input != "deprecated"
+}
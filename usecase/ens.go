@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+/*
+ResolveWalletNames replaces the ENS names in WALLET_ADDRESS and WALLETS with the addresses they
+resolve to through the resolver of the ENS config. A wallet without a label is labeled with its
+name. The config is returned unchanged when no wallet is given as a name.
+*/
+func ResolveWalletNames(ctx context.Context, config models.Config) (models.Config, error) {
+	candidates := append([]models.WalletConfig{{Address: config.WalletAddress}}, config.Wallets...)
+	hasNames := false
+	for _, wallet := range candidates {
+		hasNames = hasNames || util.IsENSName(strings.TrimSpace(wallet.Address))
+	}
+	if !hasNames {
+		return config, nil
+	}
+
+	resolver, err := thirdparty.NewNameResolver(config)
+	if err != nil {
+		return config, err
+	}
+	if resolver == nil {
+		return config, fmt.Errorf("wallets are given as ENS names, set ENS.RESOLVER to rpc or file")
+	}
+
+	// WALLET_ADDRESS has no label, a name given there is moved to WALLETS to keep it as the label
+	wallets := make([]models.WalletConfig, 0, len(candidates))
+	for _, wallet := range candidates {
+		name := strings.TrimSpace(wallet.Address)
+		if !util.IsENSName(name) {
+			if wallet.Address != "" || wallet.Label != "" {
+				wallets = append(wallets, wallet)
+			}
+			continue
+		}
+		address, err := resolver.ResolveName(ctx, name)
+		if err != nil {
+			return config, err
+		}
+		if wallet.Label == "" {
+			wallet.Label = name
+		}
+		wallet.Address = address
+		logging.FromContext(ctx).Debug("resolved ENS name", "name", name, logging.KeyWallet, address)
+		wallets = append(wallets, wallet)
+	}
+	config.WalletAddress = ""
+	config.Wallets = wallets
+	return config, nil
+}
+
+/*
+ReverseNames fills the optional From/To ENS Name columns of the transfer reports with the primary
+ENS names of the addresses. Names are looked up once per address and shared by the reports of a chain,
+a failed lookup leaves the column empty. A nil ReverseNames leaves the rows unchanged.
+*/
+type ReverseNames struct {
+	resolver thirdparty.NameResolver
+	mu       sync.Mutex
+	names    map[string]string
+}
+
+// NewReverseNames creates the reverse name lookup of the resolver.
+func NewReverseNames(resolver thirdparty.NameResolver) *ReverseNames {
+	return &ReverseNames{resolver: resolver, names: map[string]string{}}
+}
+
+// Name returns the primary ENS name of the address, empty when it has none.
+func (r *ReverseNames) Name(ctx context.Context, address string) string {
+	key := normalizeAddress(address)
+	if r == nil || !util.IsHexAddress(key) {
+		return ""
+	}
+
+	r.mu.Lock()
+	name, ok := r.names[key]
+	r.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	name, err := r.resolver.LookupAddress(ctx, key)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ""
+		}
+		logging.FromContext(ctx).Debug("ENS reverse lookup failed", "address", util.ChecksumAddress(key), logging.KeyError, err)
+	}
+
+	r.mu.Lock()
+	r.names[key] = name
+	r.mu.Unlock()
+	return name
+}
+
+// Transform implements the RowTransformer interface.
+func (r *ReverseNames) Transform(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error) {
+	if r == nil {
+		return row, nil
+	}
+	row.FromENSName = r.Name(ctx, row.FromAddress)
+	row.ToENSName = r.Name(ctx, row.ToAddress)
+	return row, nil
+}
+
+// Columns returns the optional report columns filled by the reverse names.
+func (r *ReverseNames) Columns() []string {
+	if r == nil {
+		return nil
+	}
+	return []string{constants.ENS_FROM_NAME_COLUMN, constants.ENS_TO_NAME_COLUMN}
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// TestENSWallets replays the ERC-20 report of a wallet given as ENS name with the reverse name columns.
+func TestENSWallets(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdirTemp(t)
+
+	names := "treasury.eth: \"0x1111111111111111111111111111111111111111\"\nbob.eth: \"0x2222222222222222222222222222222222222222\"\n"
+	if err := os.WriteFile("ens.yml", []byte(names), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := models.Config{
+		WalletAddress: "treasury.eth",
+		Chains:        []string{constants.CHAIN_ETHEREUM},
+		ReportTypes:   []string{constants.ERC20_REPORT},
+		Fixtures:      models.FixtureConfig{ReplayDirectory: fixtures},
+		Ens:           models.EnsConfig{Resolver: constants.ENS_RESOLVER_FILE, File: "ens.yml", ReverseNames: true},
+	}
+
+	resolved, err := ResolveWalletNames(context.Background(), config)
+	if err != nil {
+		t.Fatalf("ResolveWalletNames() error = %v", err)
+	}
+	wallets := ConfiguredWallets(resolved)
+	if len(wallets) != 1 || wallets[0].Address != "0x1111111111111111111111111111111111111111" || wallets[0].Label != "treasury.eth" {
+		t.Errorf("ConfiguredWallets() = %+v; want the resolved address labeled with the name", wallets)
+	}
+
+	if _, err := GenerateTransactionReports(context.Background(), constants.PROVIDER_ETHERSCAN, config); err != nil {
+		t.Fatalf("GenerateTransactionReports() error = %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join("files", "reports", "0x1111111111111111111111111111111111111111_ethereum_erc-20_report.csv"))
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(report)), "\n")
+	if !strings.HasSuffix(lines[0], ",From ENS Name,To ENS Name") || !strings.HasSuffix(lines[1], ",treasury.eth,bob.eth") {
+		t.Errorf("report = %s; want the ENS name columns filled", report)
+	}
+
+	config.Ens.Resolver = ""
+	if _, err := ResolveWalletNames(context.Background(), config); err == nil {
+		t.Error("ResolveWalletNames() without resolver returned no error")
+	}
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+/*
+Fetch every event log in which the wallet is an indexed topic.
+Etherscan returns at most LOGS_PAGE_SIZE logs per page and caps page * offset at
+LOGS_MAX_RESULT_WINDOW. Once the window is exhausted the query restarts at the block of the
+last log seen, logs of that block which were already collected are skipped.
+*/
+func FetchEventLogs(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, walletAddress string) ([]models.EventLog, error) {
+	logsProvider, ok := dataProvider.(thirdparty.EventLogProvider)
+	if !ok {
+		return nil, fmt.Errorf("data provider does not support event logs")
+	}
+
+	logs := []models.EventLog{}
+	seen := map[string]bool{}
+	fromBlock := ""
+	page := 1
+
+	for {
+		url := logsProvider.BuildLogsRequestURL(walletAddress, fromBlock, page, constants.LOGS_PAGE_SIZE)
+		res, err := dataProvider.FetchTransactionData(ctx, url, constants.EVENT_LOG_REPORT)
+		if err != nil {
+			return nil, err
+		}
+
+		pageLogs, err := parseEventLogPage(res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range pageLogs {
+			key := strings.ToLower(log.TransactionHash) + ":" + log.LogIndex
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			logs = append(logs, log)
+		}
+
+		if len(pageLogs) < constants.LOGS_PAGE_SIZE {
+			break
+		}
+
+		if page*constants.LOGS_PAGE_SIZE < constants.LOGS_MAX_RESULT_WINDOW {
+			page++
+			continue
+		}
+
+		// Result window exhausted, continue from the block of the last log
+		lastBlock, err := util.HexToDecimalString(pageLogs[len(pageLogs)-1].BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if lastBlock == fromBlock {
+			return nil, fmt.Errorf("block %s holds more than %d logs for wallet %s, can not paginate further", lastBlock, constants.LOGS_MAX_RESULT_WINDOW, walletAddress)
+		}
+		fromBlock = lastBlock
+		page = 1
+	}
+
+	return logs, nil
+}
+
+func parseEventLogPage(res string) ([]models.EventLog, error) {
+	resp := models.EtherscanBaseResponse{}
+	if err := json.Unmarshal([]byte(res), &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling event logs: %w", err)
+	}
+
+	if resp.Status != "1" {
+		// An empty result is reported as an error with an empty result array
+		if strings.Contains(strings.ToLower(resp.Message), "no records found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("event log request failed: %s - %s", resp.Message, string(resp.Result))
+	}
+
+	logs := []models.EventLog{}
+	if err := json.Unmarshal(resp.Result, &logs); err != nil {
+		return nil, fmt.Errorf("error unmarshalling event logs: %w", err)
+	}
+	return logs, nil
+}
+
+// generateEventLogReport fetches every page of event logs before the report is written.
+func generateEventLogReport(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, opts ReportOptions, walletAddress string) (int, error) {
+	logs, err := FetchEventLogs(ctx, dataProvider, walletAddress)
+	if err != nil {
+		logging.FromContext(ctx).Error("fetching event logs failed", logging.KeyError, err)
+		return 0, err
+	}
+	// Reports are only written from complete data
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return EventLogReport(ctx, logs, opts, walletAddress)
+}
+
+// EventLogReport writes the event logs decoded through the contract and token standard ABIs.
+func EventLogReport(ctx context.Context, logs []models.EventLog, opts ReportOptions, walletAddress string) (int, error) {
+	if len(logs) == 0 {
+		logging.FromContext(ctx).Info("no event logs found", logging.KeyWallet, walletAddress)
+		return 0, nil
+	}
+
+	report, err := newReportWriter[models.EventLogReportResponse](ctx, opts, walletAddress, constants.EVENT_LOG_REPORT)
+	if err != nil {
+		logging.FromContext(ctx).Error("resolving report path failed", logging.KeyError, err)
+		return 0, err
+	}
+	for _, row := range BuildEventLogRows(logs, opts) {
+		if err := report.Write(row); err != nil {
+			return finishReports(err, report)
+		}
+	}
+
+	rows, err := finishReports(nil, report)
+	if err != nil {
+		logging.FromContext(ctx).Error("writing event log report failed", logging.KeyError, err)
+		return 0, err
+	}
+	return rows, nil
+}
+
+// BuildEventLogRows returns the rows of the event log report, logs are decoded through the contract and token standard ABIs.
+func BuildEventLogRows(logs []models.EventLog, opts ReportOptions) []models.EventLogReportResponse {
+	rows := make([]models.EventLogReportResponse, 0, len(logs))
+	for _, log := range logs {
+		timestamp, _ := util.HexToDecimalString(log.TimeStamp)
+		dateTime, _ := util.FormatUnixTimestampString(timestamp)
+		blockNumber, _ := util.HexToDecimalString(log.BlockNumber)
+		logIndex, _ := util.HexToDecimalString(log.LogIndex)
+
+		row := models.EventLogReportResponse{
+			Chain:           opts.Chain.Name,
+			TransactionHash: log.TransactionHash,
+			DateTime:        dateTime,
+			BlockNumber:     blockNumber,
+			LogIndex:        logIndex,
+			ContractAddress: util.ChecksumAddress(log.Address),
+			ContractLabel:   opts.AddressBook.Label(log.Address),
+		}
+		if len(log.Topics) > 0 {
+			row.Topic0 = log.Topics[0]
+		}
+
+		decoded, err := opts.AbiRegistry.DecodeLog(log.Address, log.Topics, log.Data)
+		if err != nil {
+			row.DecodeError = err.Error()
+		} else {
+			row.EventName = decoded.EventName
+			row.EventSignature = decoded.Signature
+			row.DecodedArguments = abi.FormatArguments(decoded.Arguments)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
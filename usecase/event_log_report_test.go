@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// logsStubProvider serves a fixed number of logs, numLogsPerBlock logs per block
+type logsStubProvider struct {
+	numLogs         int
+	numLogsPerBlock int
+	requests        []string
+}
+
+func (p *logsStubProvider) BuildRequestURL(action, walletAddress string) string {
+	return "stub://?action=" + action
+}
+
+func (p *logsStubProvider) BuildLogsRequestURL(walletAddress, fromBlock string, page, offset int) string {
+	if fromBlock == "" {
+		fromBlock = "0"
+	}
+	return fmt.Sprintf("stub://?fromBlock=%s&page=%d&offset=%d", fromBlock, page, offset)
+}
+
+func (p *logsStubProvider) FetchTransactionData(ctx context.Context, rawURL, tag string) (string, error) {
+	p.requests = append(p.requests, rawURL)
+
+	parsed, _ := url.Parse(rawURL)
+	fromBlock, _ := strconv.Atoi(parsed.Query().Get("fromBlock"))
+	page, _ := strconv.Atoi(parsed.Query().Get("page"))
+	offset, _ := strconv.Atoi(parsed.Query().Get("offset"))
+
+	// Logs matching the query are the ones at or after fromBlock
+	matching := []models.EventLog{}
+	for i := 0; i < p.numLogs; i++ {
+		block := i / p.numLogsPerBlock
+		if block < fromBlock {
+			continue
+		}
+		matching = append(matching, models.EventLog{
+			TransactionHash: fmt.Sprintf("0x%x", i),
+			LogIndex:        "0x0",
+			BlockNumber:     fmt.Sprintf("0x%x", block),
+		})
+	}
+
+	start := (page - 1) * offset
+	end := min(start+offset, len(matching))
+	if start >= len(matching) {
+		return `{"status":"0","message":"No records found","result":[]}`, nil
+	}
+
+	result, _ := json.Marshal(matching[start:end])
+	return fmt.Sprintf(`{"status":"1","message":"OK","result":%s}`, result), nil
+}
+
+func TestFetchEventLogsPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		numLogs      int
+		wantRequests int
+	}{
+		{name: "Single Page", numLogs: 10, wantRequests: 1},
+		{name: "Exact Page Size", numLogs: constants.LOGS_PAGE_SIZE, wantRequests: 2},
+		{name: "Beyond Result Window", numLogs: constants.LOGS_MAX_RESULT_WINDOW + 2500, wantRequests: 13},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &logsStubProvider{numLogs: tc.numLogs, numLogsPerBlock: 3}
+
+			logs, err := FetchEventLogs(context.Background(), provider, "0x1111111111111111111111111111111111111111")
+			if err != nil {
+				t.Fatalf("FetchEventLogs unexpected error: %v", err)
+			}
+			if len(logs) != tc.numLogs {
+				t.Errorf("FetchEventLogs returned %d logs; want %d", len(logs), tc.numLogs)
+			}
+			if len(provider.requests) != tc.wantRequests {
+				t.Errorf("FetchEventLogs made %d requests; want %d", len(provider.requests), tc.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// ManifestRecorder collects the reports written by the concurrent report tasks of a run.
+type ManifestRecorder struct {
+	mu       sync.Mutex
+	manifest models.Manifest
+}
+
+// NewManifestRecorder creates the manifest of a run, the block range is the one requested from the provider.
+func NewManifestRecorder(providerType string, config models.Config) *ManifestRecorder {
+	startBlock, endBlock := requestedBlockRange(providerType, config)
+
+	return &ManifestRecorder{manifest: models.Manifest{
+		Provider:   providerType,
+		StartBlock: startBlock,
+		EndBlock:   endBlock,
+		Files:      []models.ManifestEntry{},
+	}}
+}
+
+// Add records a written report, reports written without a recorder (e.g. in tests) are not listed.
+func (r *ManifestRecorder) Add(entry models.ManifestEntry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Files = append(r.manifest.Files, entry)
+}
+
+// Write writes the manifest into the directory, file paths are stored relative to it.
+func (r *ManifestRecorder) Write(dir string) error {
+	r.mu.Lock()
+	manifest := r.manifest
+	manifest.Files = append([]models.ManifestEntry{}, r.manifest.Files...)
+	r.mu.Unlock()
+
+	manifest.GeneratedAt = time.Now().UTC()
+	for i, entry := range manifest.Files {
+		if rel, err := filepath.Rel(dir, entry.File); err == nil {
+			manifest.Files[i].File = filepath.ToSlash(rel)
+		}
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].File < manifest.Files[j].File })
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create reports directory %s: %w", dir, err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return util.WriteFileAtomic(filepath.Join(dir, constants.MANIFEST_FILE), data, 0o644)
+}
+
+/*
+VerifyManifest checks every file listed in the manifest against its SHA-256 checksum.
+The returned error lists all missing and altered files.
+*/
+func VerifyManifest(manifestPath string) (models.Manifest, error) {
+	manifest := models.Manifest{}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return manifest, fmt.Errorf("error reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("error unmarshalling manifest: %w", err)
+	}
+
+	dir := filepath.Dir(manifestPath)
+	errs := []error{}
+	for _, entry := range manifest.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.File)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.File, err))
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if checksum := hex.EncodeToString(sum[:]); !strings.EqualFold(checksum, entry.SHA256) {
+			errs = append(errs, fmt.Errorf("%s: checksum %s does not match the manifest %s", entry.File, checksum, entry.SHA256))
+		}
+	}
+	return manifest, errors.Join(errs...)
+}
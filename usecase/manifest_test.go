@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewManifestRecorder(constants.PROVIDER_RPC, models.Config{
+		Rpc: models.RpcConfig{StartBlock: 100, EndBlock: 200},
+	})
+	opts := ReportOptions{Chain: models.Chain{Name: constants.CHAIN_ETHEREUM}, Manifest: recorder, StartBlock: 100, EndBlock: 150}
+
+	output, err := NewOutputLayout(constants.PROVIDER_RPC, models.Config{Output: models.OutputConfig{Directory: dir}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Output = output
+	report, err := newReportWriter[models.ReportResponse](context.Background(), opts, "0xwallet", constants.EXTERNAL_REPORT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "0xwallet_ethereum_external_report.csv")
+	for _, hash := range []string{"0x01", "0x02"} {
+		if err := report.Write(models.ReportResponse{TransactionHash: hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := finishReports(nil, report); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	manifestPath := filepath.Join(dir, constants.MANIFEST_FILE)
+	manifest, err := VerifyManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("VerifyManifest() error = %v", err)
+	}
+	if manifest.StartBlock != 100 || manifest.EndBlock != 200 || len(manifest.Files) != 1 {
+		t.Fatalf("manifest = %+v; want blocks 100-200 and one file", manifest)
+	}
+	entry := manifest.Files[0]
+	if entry.File != "0xwallet_ethereum_external_report.csv" || entry.Rows != 2 || entry.Wallet != "0xwallet" || entry.ReportType != constants.EXTERNAL_REPORT || entry.StartBlock != 100 || entry.EndBlock != 150 {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	if err := os.WriteFile(filePath, []byte("tampered\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyManifest(manifestPath); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("VerifyManifest() of an altered report error = %v; want checksum mismatch", err)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyManifest(manifestPath); err == nil {
+		t.Error("VerifyManifest() of a missing report succeeded")
+	}
+}
+
+// headStubProvider serves the head of the chain
+type headStubProvider struct {
+	responseStubProvider
+	head uint64
+	err  error
+}
+
+func (p *headStubProvider) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	return p.head, p.err
+}
+
+func TestResolveBlockRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    models.Config
+		provider  thirdparty.BlockchainDataProvider
+		wantStart uint64
+		wantEnd   uint64
+	}{
+		{name: "Requested End", config: models.Config{StartBlock: 10, EndBlock: 500}, provider: &headStubProvider{head: 900}, wantStart: 10, wantEnd: 500},
+		{name: "Latest Block", config: models.Config{StartBlock: 10}, provider: &headStubProvider{head: 900}, wantStart: 10, wantEnd: 900},
+		{name: "Head Unavailable", config: models.Config{StartBlock: 10}, provider: &headStubProvider{err: errors.New("down")}, wantStart: 10, wantEnd: 0},
+		{name: "No Head Provider", config: models.Config{StartBlock: 10}, provider: &responseStubProvider{}, wantStart: 10, wantEnd: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := resolveBlockRange(context.Background(), tt.provider, constants.PROVIDER_ETHERSCAN, tt.config)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("resolveBlockRange() = %d-%d; want %d-%d", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
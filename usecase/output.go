@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+var (
+	templateVariable = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+	unsafeFileChars  = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+/*
+OutputLayout resolves where the reports of a run are written. The filename template supports
+the variables {wallet}, {label}, {chain}, {type}, {range} (requested block range, e.g. 0-latest),
+{from} and {to} (dates of the first and last row of the report), {date} and {timestamp} (start
+of the run in UTC). With run subdirectories every run writes into its own directory named after
+the run timestamp instead of overwriting the previous reports.
+*/
+type OutputLayout struct {
+	Directory string // Directory of the run, includes the run subdirectory
+	template  string
+	vars      map[string]string // Variables shared by all reports of the run
+	labels    map[string]string // Wallet address -> label
+
+	mu     sync.Mutex
+	claims map[string]string // Report path -> report written to it by the run
+}
+
+// DateRange holds the dates (UTC, e.g. 2024-05-01) of the first and last row of a report.
+type DateRange struct {
+	From string
+	To   string
+}
+
+// Add extends the range by the date of a row, given as a date time such as 2024-05-01 12:30:00.
+func (r *DateRange) Add(dateTime string) {
+	date, _, _ := strings.Cut(dateTime, " ")
+	// Zero timestamps are formatted as 00-00-0000 and have no date
+	if date == "" || strings.HasPrefix(date, "00-") {
+		return
+	}
+	if r.From == "" || date < r.From {
+		r.From = date
+	}
+	if date > r.To {
+		r.To = date
+	}
+}
+
+// NewOutputLayout creates the output layout of a run started at runStartedAt.
+func NewOutputLayout(providerType string, config models.Config, runStartedAt time.Time) (*OutputLayout, error) {
+	directory := config.Output.Directory
+	if directory == "" {
+		directory = constants.DEFAULT_OUTPUT_DIRECTORY
+	}
+	if !filepath.IsAbs(directory) {
+		dir, err := util.GetCurrentWorkingDirectory()
+		if err != nil {
+			return nil, err
+		}
+		directory = filepath.Join(dir, directory)
+	}
+
+	template := config.Output.FilenameTemplate
+	if template == "" {
+		template = constants.DEFAULT_FILENAME_TEMPLATE
+	}
+	if err := validateFilenameTemplate(template); err != nil {
+		return nil, err
+	}
+
+	timestamp := runStartedAt.UTC().Format(constants.RUN_TIMESTAMP_LAYOUT)
+	if config.Output.RunSubdirectory {
+		directory = filepath.Join(directory, timestamp)
+	}
+
+	startBlock, endBlock := requestedBlockRange(providerType, config)
+	end := "latest"
+	if endBlock > 0 {
+		end = strconv.FormatUint(endBlock, 10)
+	}
+
+	labels := map[string]string{}
+	for _, wallet := range ConfiguredWallets(config) {
+		labels[normalizeAddress(wallet.Address)] = wallet.Label
+	}
+
+	return &OutputLayout{
+		Directory: directory,
+		template:  template,
+		vars: map[string]string{
+			"range":     strconv.FormatUint(startBlock, 10) + "-" + end,
+			"date":      runStartedAt.UTC().Format("2006-01-02"),
+			"timestamp": timestamp,
+		},
+		labels: labels,
+		claims: map[string]string{},
+	}, nil
+}
+
+/*
+ReportPath returns the file path of a report whose rows cover the given dates. Reports written
+without an output layout (e.g. in tests) use the default directory and filename template.
+*/
+func (l *OutputLayout) ReportPath(reportType, walletAddress, chain string, dates DateRange) (string, error) {
+	if l == nil {
+		layout, err := NewOutputLayout("", models.Config{}, time.Now())
+		if err != nil {
+			return "", err
+		}
+		l = layout
+	}
+
+	walletAddress = util.ChecksumAddress(strings.TrimSpace(walletAddress))
+	label := l.labels[normalizeAddress(walletAddress)]
+	if label == "" {
+		label = walletAddress
+	}
+	registered, err := LookupReportType(reportType)
+	if err != nil {
+		return "", err
+	}
+	vars := map[string]string{
+		"wallet": walletAddress,
+		"label":  label,
+		"chain":  chain,
+		"type":   registered.FileType,
+		"from":   dates.From,
+		"to":     dates.To,
+	}
+
+	name := templateVariable.ReplaceAllStringFunc(l.template, func(match string) string {
+		key := strings.Trim(match, "{}")
+		value, ok := vars[key]
+		if !ok {
+			value = l.vars[key]
+		}
+		return unsafeFileChars.ReplaceAllString(value, "_")
+	})
+	return filepath.Join(l.Directory, name), nil
+}
+
+/*
+Claim reserves the path for a report of the run. It fails when another report of the run was
+written to the same path, e.g. for wallets sharing a label in a template without {wallet}.
+*/
+func (l *OutputLayout) Claim(filePath, report string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if claimed, ok := l.claims[filePath]; ok && claimed != report {
+		return fmt.Errorf("report %s would overwrite report %s at %s, make OUTPUT.FILENAME_TEMPLATE tell them apart, e.g. with {wallet}", report, claimed, filePath)
+	}
+	l.claims[filePath] = report
+	return nil
+}
+
+/*
+The template may only use known variables and must tell apart the reports of a run,
+so it needs the report type, the chain and the wallet or its label.
+*/
+func validateFilenameTemplate(template string) error {
+	used := map[string]bool{}
+	for _, match := range templateVariable.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "wallet", "label", "chain", "type", "range", "from", "to", "date", "timestamp":
+			used[match[1]] = true
+		default:
+			return fmt.Errorf("unknown variable {%s} in filename template %q", match[1], template)
+		}
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("filename template %q must not contain a directory", template)
+	}
+	if !used["type"] || !used["chain"] || !(used["wallet"] || used["label"]) {
+		return fmt.Errorf("filename template %q must contain {type}, {chain} and {wallet} or {label}", template)
+	}
+	return nil
+}
+
+// requestedBlockRange returns the block range requested from the provider, an end block of 0 is the latest block.
+func requestedBlockRange(providerType string, config models.Config) (uint64, uint64) {
+	if providerType == constants.PROVIDER_RPC {
+		return config.Rpc.StartBlock, config.Rpc.EndBlock
+	}
+	return config.StartBlock, config.EndBlock
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestOutputLayoutReportPath(t *testing.T) {
+	dir := t.TempDir()
+	startedAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	wallet := "0x1111111111111111111111111111111111111111"
+
+	tests := []struct {
+		name   string
+		config models.Config
+		dates  DateRange
+		want   string
+	}{
+		{
+			name:   "default template",
+			config: models.Config{Output: models.OutputConfig{Directory: dir}},
+			want:   filepath.Join(dir, wallet+"_ethereum_erc-20_report.csv"),
+		},
+		{
+			name: "label and block range",
+			config: models.Config{
+				Output:     models.OutputConfig{Directory: dir, FilenameTemplate: "{label}_{chain}_{type}_{range}.csv"},
+				Wallets:    []models.WalletConfig{{Address: wallet, Label: "Cold Storage/1"}},
+				StartBlock: 100,
+			},
+			want: filepath.Join(dir, "Cold_Storage_1_ethereum_erc-20_100-latest.csv"),
+		},
+		{
+			name: "unlabeled wallet",
+			config: models.Config{
+				Output:        models.OutputConfig{Directory: dir, FilenameTemplate: "{label}_{chain}_{type}_{date}.csv"},
+				WalletAddress: wallet,
+			},
+			want: filepath.Join(dir, wallet+"_ethereum_erc-20_2024-05-01.csv"),
+		},
+		{
+			name: "report dates",
+			config: models.Config{
+				Output:        models.OutputConfig{Directory: dir, FilenameTemplate: "{wallet}_{chain}_{type}_{from}_{to}.csv"},
+				WalletAddress: wallet,
+			},
+			dates: DateRange{From: "2023-01-15", To: "2024-04-30"},
+			want:  filepath.Join(dir, wallet+"_ethereum_erc-20_2023-01-15_2024-04-30.csv"),
+		},
+		{
+			name:   "run subdirectory",
+			config: models.Config{Output: models.OutputConfig{Directory: dir, RunSubdirectory: true}},
+			want:   filepath.Join(dir, "20240501T123000Z", wallet+"_ethereum_erc-20_report.csv"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := NewOutputLayout(constants.PROVIDER_ETHERSCAN, tt.config, startedAt)
+			if err != nil {
+				t.Fatalf("NewOutputLayout() error = %v", err)
+			}
+			got, err := layout.ReportPath(constants.ERC20_REPORT, wallet, constants.CHAIN_ETHEREUM, tt.dates)
+			if err != nil {
+				t.Fatalf("ReportPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReportPath() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputLayoutInvalidTemplate(t *testing.T) {
+	templates := []string{
+		"{wallet}_{chain}_{kind}.csv",    // unknown variable
+		"{wallet}_{chain}.csv",           // reports would overwrite each other
+		"{type}/{wallet}_{chain}.csv",    // directory
+		"{type}_{chain}_{timestamp}.csv", // wallets would overwrite each other
+	}
+	for _, template := range templates {
+		config := models.Config{Output: models.OutputConfig{Directory: t.TempDir(), FilenameTemplate: template}}
+		if _, err := NewOutputLayout(constants.PROVIDER_ETHERSCAN, config, time.Now()); err == nil {
+			t.Errorf("NewOutputLayout() with template %q succeeded; want error", template)
+		}
+	}
+}
+
+func TestDateRangeAdd(t *testing.T) {
+	var dates DateRange
+	for _, dateTime := range []string{"2024-03-13 02:48:11", "2023-12-31 23:59:59", "00-00-0000 00:00:00", "", "2024-01-02 00:00:00"} {
+		dates.Add(dateTime)
+	}
+	if want := (DateRange{From: "2023-12-31", To: "2024-03-13"}); dates != want {
+		t.Errorf("DateRange = %+v; want %+v", dates, want)
+	}
+}
+
+func TestReportWriterPathCollision(t *testing.T) {
+	dir := t.TempDir()
+	config := models.Config{
+		Output: models.OutputConfig{Directory: dir, FilenameTemplate: "{label}_{chain}_{type}_{from}.csv"},
+		Wallets: []models.WalletConfig{
+			{Address: "0x1111111111111111111111111111111111111111", Label: "Treasury"},
+			{Address: "0x2222222222222222222222222222222222222222", Label: "Treasury"},
+		},
+	}
+	layout, err := NewOutputLayout(constants.PROVIDER_ETHERSCAN, config, time.Now())
+	if err != nil {
+		t.Fatalf("NewOutputLayout() error = %v", err)
+	}
+	opts := ReportOptions{Chain: models.Chain{Name: constants.CHAIN_ETHEREUM}, Output: layout}
+
+	write := func(wallet, dateTime string) error {
+		writer, err := newReportWriter[models.ReportResponse](context.Background(), opts, wallet, constants.ERC20_REPORT)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(models.ReportResponse{DateTime: dateTime}); err != nil {
+			return err
+		}
+		return writer.finish(false)
+	}
+
+	if err := write(config.Wallets[0].Address, "2024-05-01 10:00:00"); err != nil {
+		t.Fatalf("first report error = %v", err)
+	}
+	// Different first dates resolve to different files
+	if err := write(config.Wallets[1].Address, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("report of another date error = %v", err)
+	}
+	if err := write(config.Wallets[1].Address, "2024-05-01 11:00:00"); err == nil {
+		t.Fatal("report at the path of another wallet succeeded; want error")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "Treasury_ethereum_erc-20_2024-05-01.csv"),
+		filepath.Join(dir, "Treasury_ethereum_erc-20_2024-05-02.csv"),
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("files = %v; want %v", files, want)
+	}
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+// RowTransformer enriches or rewrites a row of a transfer report, e.g. with labels, prices or decoded calldata.
+type RowTransformer interface {
+	// Transform is called with the mapped row and the transaction it was mapped from
+	Transform(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error)
+}
+
+// RowFilter decides whether a row of a transfer report is written, e.g. to drop spam tokens or failed transactions.
+type RowFilter interface {
+	// Keep is called after all transforms with the row and the transaction it was mapped from
+	Keep(row models.ReportResponse, source any, opts ReportOptions) bool
+}
+
+// RowTransformFunc adapts a function to the RowTransformer interface.
+type RowTransformFunc func(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error)
+
+func (f RowTransformFunc) Transform(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error) {
+	return f(ctx, row, source, opts)
+}
+
+// RowFilterFunc adapts a function to the RowFilter interface.
+type RowFilterFunc func(row models.ReportResponse, source any, opts ReportOptions) bool
+
+func (f RowFilterFunc) Keep(row models.ReportResponse, source any, opts ReportOptions) bool {
+	return f(row, source, opts)
+}
+
+/*
+Pipeline streams the result array of an account endpoint into a transfer report:
+source -> decode -> map -> transforms -> filters -> alerts -> sink. T is the result model of the endpoint.
+The address book labels and ENS reverse names are always applied first, followed by the transforms
+of the pipeline and then those of the report options, filters run in the same order.
+*/
+type Pipeline[T any] struct {
+	Map        func(tx T, opts ReportOptions) models.ReportResponse
+	Transforms []RowTransformer
+	Filters    []RowFilter
+}
+
+// Run decodes the result array, every row kept by the filters is passed to the sink together with its transaction.
+func (p Pipeline[T]) Run(ctx context.Context, result *json.Decoder, opts ReportOptions, sink func(row models.ReportResponse, tx T) error) error {
+	transforms := append([]RowTransformer{opts.AddressBook, opts.ReverseNames}, p.Transforms...)
+	transforms = append(transforms, opts.Transforms...)
+	filters := append(append([]RowFilter{}, p.Filters...), opts.Filters...)
+
+	return util.DecodeJSONArray(result, func(tx T) error {
+		row := p.Map(tx, opts)
+		for _, transform := range transforms {
+			var err error
+			row, err = transform.Transform(ctx, row, tx, opts)
+			if err != nil {
+				return err
+			}
+		}
+		for _, filter := range filters {
+			if !filter.Keep(row, tx, opts) {
+				return nil
+			}
+		}
+		opts.Alerts.ObserveTransfer(ctx, row, tx, opts)
+		return sink(row, tx)
+	})
+}
+
+// ExcludeFailedFilter drops external and internal transactions that reverted.
+func ExcludeFailedFilter() RowFilter {
+	return RowFilterFunc(func(row models.ReportResponse, source any, opts ReportOptions) bool {
+		switch tx := source.(type) {
+		case models.ExternalTransaction:
+			return tx.IsError != "1"
+		case models.InternalTransaction:
+			return tx.IsError != "1"
+		}
+		return true
+	})
+}
+
+// ExcludeContractsFilter drops transfers of the given token contracts, e.g. known spam tokens.
+func ExcludeContractsFilter(contracts ...string) RowFilter {
+	excluded := map[string]bool{}
+	for _, contract := range contracts {
+		excluded[normalizeAddress(contract)] = true
+	}
+	return RowFilterFunc(func(row models.ReportResponse, source any, opts ReportOptions) bool {
+		return !excluded[normalizeAddress(row.AssetContractAddress)]
+	})
+}
+
+// BlockRangeFilter keeps transactions from the start up to and including the end block, an end block of 0 is the latest block.
+func BlockRangeFilter(startBlock, endBlock uint64) RowFilter {
+	return RowFilterFunc(func(row models.ReportResponse, source any, opts ReportOptions) bool {
+		blockNumber := ""
+		switch tx := source.(type) {
+		case models.ExternalTransaction:
+			blockNumber = tx.BlockNumber
+		case models.InternalTransaction:
+			blockNumber = tx.BlockNumber
+		case models.TokenTransaction:
+			blockNumber = tx.BlockNumber
+		case models.NftTransaction:
+			blockNumber = tx.BlockNumber
+		}
+		block, err := strconv.ParseUint(blockNumber, 10, 64)
+		if err != nil {
+			return true
+		}
+		return block >= startBlock && (endBlock == 0 || block <= endBlock)
+	})
+}
+
+// DateRangeFilter keeps rows from the start up to and excluding the end, a zero time leaves the range open.
+func DateRangeFilter(from, to time.Time) RowFilter {
+	return RowFilterFunc(func(row models.ReportResponse, source any, opts ReportOptions) bool {
+		dateTime, err := time.Parse(constants.DATE_FORMAT_YYYY_MM_DD_HH_MM_SS, strings.TrimSpace(row.DateTime))
+		if err != nil {
+			return true
+		}
+		return (from.IsZero() || !dateTime.Before(from)) && (to.IsZero() || dateTime.Before(to))
+	})
+}
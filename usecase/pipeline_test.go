@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+)
+
+func TestPipelineRun(t *testing.T) {
+	result := `[
+		{"hash":"0x01","timeStamp":"1704067200","from":"0xaa","to":"0xbb","contractAddress":"","isError":"0"},
+		{"hash":"0x02","timeStamp":"1704153600","from":"0xaa","to":"0xcc","contractAddress":"","isError":"1"},
+		{"hash":"0x03","timeStamp":"1704240000","from":"0xaa","to":"0xbb","contractAddress":"0xspam","isError":"0"},
+		{"hash":"0x04","timeStamp":"1706745600","from":"0xaa","to":"0xbb","contractAddress":"","isError":"0"}
+	]`
+	book := NewAddressBook([]models.AddressBookEntry{{Address: "0xBB", Label: "Exchange"}})
+
+	// Transforms see the labels of the address book and run in order
+	price := RowTransformFunc(func(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error) {
+		row.AssetSymbolName = "ETH@" + row.ToLabel
+		return row, nil
+	})
+	// Alerts only see the rows that are written
+	alerts, err := NewAlertEngine(models.AlertConfig{Rules: []models.AlertRule{{Name: "Any transfer", Event: "transfer"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerted := []string{}
+	alerts.AddNotifier(AlertNotifierFunc(func(ctx context.Context, alert models.Alert) error {
+		alerted = append(alerted, alert.Transfer.TransactionHash+" "+alert.Transfer.ToLabel)
+		return nil
+	}))
+
+	opts := ReportOptions{
+		AddressBook: book,
+		Alerts:      alerts,
+		Transforms:  []RowTransformer{price},
+		Filters: []RowFilter{
+			ExcludeContractsFilter("0xSPAM"),
+			DateRangeFilter(time.Time{}, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+	pipeline := Pipeline[models.ExternalTransaction]{
+		Map:     externalRow,
+		Filters: []RowFilter{ExcludeFailedFilter()},
+	}
+
+	got := []string{}
+	err = pipeline.Run(context.Background(), json.NewDecoder(strings.NewReader(result)), opts, func(row models.ReportResponse, tx models.ExternalTransaction) error {
+		if row.TransactionHash != tx.Hash {
+			t.Errorf("row %s passed to the sink with transaction %s", row.TransactionHash, tx.Hash)
+		}
+		got = append(got, row.TransactionHash+" "+row.AssetSymbolName)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{"0x01 ETH@Exchange"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() rows = %v; want %v", got, want)
+	}
+	if want := []string{"0x01 Exchange"}; !reflect.DeepEqual(alerted, want) {
+		t.Errorf("Run() alerts = %v; want %v", alerted, want)
+	}
+}
+
+func TestPipelineTransformError(t *testing.T) {
+	errPrice := errors.New("price unavailable")
+	opts := ReportOptions{Transforms: []RowTransformer{RowTransformFunc(func(ctx context.Context, row models.ReportResponse, source any, opts ReportOptions) (models.ReportResponse, error) {
+		return row, errPrice
+	})}}
+	pipeline := Pipeline[models.TokenTransaction]{Map: erc20Row}
+
+	err := pipeline.Run(context.Background(), json.NewDecoder(strings.NewReader(`[{"hash":"0x01"}]`)), opts, func(row models.ReportResponse, tx models.TokenTransaction) error {
+		t.Error("sink called after a failed transform")
+		return nil
+	})
+	if !errors.Is(err, errPrice) {
+		t.Errorf("Run() error = %v; want %v", err, errPrice)
+	}
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+// Regenerate the golden reports with: go test ./usecase -run TestGenerateTransactionReportsReplay -update
+var update = flag.Bool("update", false, "update the golden report files")
+
+// TestGenerateTransactionReportsReplay generates every report offline from recorded provider fixtures.
+func TestGenerateTransactionReportsReplay(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chdirTemp(t)
+
+	config := models.Config{
+		WalletAddress: "0x1111111111111111111111111111111111111111",
+		Chains:        []string{constants.CHAIN_ETHEREUM},
+		Fixtures:      models.FixtureConfig{ReplayDirectory: fixtures},
+	}
+	result, err := GenerateTransactionReports(context.Background(), constants.PROVIDER_ETHERSCAN, config)
+	if err != nil {
+		t.Fatalf("GenerateTransactionReports() error = %v", err)
+	}
+	if result.Status != constants.RUN_STATUS_SUCCEEDED || result.Succeeded != 6 {
+		t.Errorf("result status = %s with %d succeeded tasks; want %s with 6", result.Status, result.Succeeded, constants.RUN_STATUS_SUCCEEDED)
+	}
+
+	data, err := os.ReadFile(filepath.Join("files", "reports", constants.RUN_SUMMARY_FILE))
+	if err != nil {
+		t.Fatalf("run summary not written: %v", err)
+	}
+	summary := models.RunResult{}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid run summary: %v", err)
+	}
+	rows := map[string]int{}
+	for _, task := range summary.Tasks {
+		rows[task.ReportType] = task.Rows
+	}
+	wantRows := map[string]int{
+		constants.EXTERNAL_REPORT:  2,
+		constants.INTERNAL_REPORT:  1,
+		constants.ERC20_REPORT:     1,
+		constants.ERC721_REPORT:    1,
+		constants.EVENT_LOG_REPORT: 2,
+		constants.APPROVAL_REPORT:  1,
+	}
+	for reportType, want := range wantRows {
+		if rows[reportType] != want {
+			t.Errorf("summary rows of %s = %d; want %d", reportType, rows[reportType], want)
+		}
+	}
+
+	manifest, err := VerifyManifest(filepath.Join("files", "reports", constants.MANIFEST_FILE))
+	if err != nil {
+		t.Errorf("VerifyManifest() error = %v", err)
+	}
+	if len(manifest.Files) != 6 || manifest.Provider != constants.PROVIDER_ETHERSCAN {
+		t.Errorf("manifest lists %d files of provider %q; want 6 of %q", len(manifest.Files), manifest.Provider, constants.PROVIDER_ETHERSCAN)
+	}
+
+	reports := []string{
+		"external_report.csv",
+		"internal_report.csv",
+		"erc-20_report.csv",
+		"erc-721_report.csv",
+		"event_log_report.csv",
+		"approval_report.csv",
+	}
+	for _, report := range reports {
+		t.Run(report, func(t *testing.T) {
+			name := config.WalletAddress + "_" + constants.CHAIN_ETHEREUM + "_" + report
+			got, err := os.ReadFile(filepath.Join("files", "reports", name))
+			if err != nil {
+				t.Fatalf("report not written: %v", err)
+			}
+
+			goldenPath := filepath.Join(golden, report)
+			if *update {
+				if err := os.MkdirAll(golden, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("golden report missing, run with -update: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s mismatch\ngot:\n%s\nwant:\n%s", report, got, want)
+			}
+		})
+	}
+}
+
+func TestReplayProviderMissingFixture(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdirTemp(t)
+
+	config := models.Config{
+		WalletAddress: "0x9999999999999999999999999999999999999999",
+		Chains:        []string{constants.CHAIN_ETHEREUM},
+		Fixtures:      models.FixtureConfig{ReplayDirectory: fixtures},
+	}
+
+	result, err := GenerateTransactionReports(context.Background(), constants.PROVIDER_ETHERSCAN, config)
+	if err == nil {
+		t.Fatal("expected an error for a wallet without fixtures")
+	}
+
+	// Every task is reported, not only the first failure
+	if result.Failed != len(result.Tasks) || len(result.Tasks) != 6 {
+		t.Errorf("failed tasks = %d of %d; want 6 of 6", result.Failed, len(result.Tasks))
+	}
+	if !errors.Is(err, thirdparty.ErrFixtureNotFound) {
+		t.Errorf("error = %v; want it to wrap %v", err, thirdparty.ErrFixtureNotFound)
+	}
+}
+
+// chdirTemp moves into a temporary directory for the test, reports are written relative to the working directory
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestGenerateTransactionReportsCanceled(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdirTemp(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	config := models.Config{
+		WalletAddress: "0x1111111111111111111111111111111111111111",
+		Chains:        []string{constants.CHAIN_ETHEREUM},
+		Fixtures:      models.FixtureConfig{ReplayDirectory: fixtures},
+	}
+	if _, err := GenerateTransactionReports(ctx, constants.PROVIDER_ETHERSCAN, config); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateTransactionReports() error = %v; want %v", err, context.Canceled)
+	}
+
+	// No report may be written from incomplete data
+	reports, _ := filepath.Glob(filepath.Join("files", "reports", "*.csv"))
+	if len(reports) != 0 {
+		t.Errorf("reports written after cancellation: %v", reports)
+	}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/abi"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+// ReportOptions holds the dependencies shared by all report builders of a chain.
+type ReportOptions struct {
+	Chain          models.Chain
+	AddressBook    *AddressBook
+	AbiRegistry    *abi.Registry
+	DetailedReport bool
+	Manifest       *ManifestRecorder // Shared by all chains of a run, optional
+	Output         *OutputLayout     // Shared by all chains of a run, optional
+	Transforms     []RowTransformer  // Applied to the rows of every transfer report after the address book labels
+	Filters        []RowFilter       // Rows rejected by any filter are not written
+	Alerts         *AlertEngine      // Evaluated against every written transfer and approval, optional
+	ReverseNames   *ReverseNames     // Fills the optional ENS name columns, the columns are omitted without it
+	StartBlock     uint64            // First block covered by the reports, recorded in the manifest
+	EndBlock       uint64            // Last block covered by the reports, resolved per task, 0 when unknown
+}
+
+/*
+Build the report options of a chain from the config. The ABI registry always knows the token
+standards, contract specific ABIs are resolved from the local directory or through the data
+provider. Fetched ABIs are cached per chain since contract addresses are chain specific.
+ABI requests are canceled together with the given context.
+*/
+func NewReportOptions(ctx context.Context, config models.Config, dataProvider thirdparty.BlockchainDataProvider, addressBook *AddressBook, chain models.Chain) ReportOptions {
+	opts := ReportOptions{
+		Chain:          chain,
+		AddressBook:    addressBook,
+		DetailedReport: config.Abi.DetailedReport,
+	}
+
+	var fetcher abi.Fetcher
+	if abiProvider, ok := dataProvider.(thirdparty.ContractABIProvider); ok && config.Abi.FetchRemote {
+		fetcher = func(address string) ([]byte, error) {
+			return abiProvider.FetchContractABI(ctx, address)
+		}
+	}
+	cacheDirectory := config.Abi.CacheDirectory
+	if cacheDirectory != "" {
+		cacheDirectory = filepath.Join(cacheDirectory, chain.Name)
+	}
+
+	opts.AbiRegistry = abi.NewRegistry(config.Abi.Directory, cacheDirectory, fetcher)
+	for _, standard := range abi.StandardABIs() {
+		opts.AbiRegistry.AddFallback(standard)
+	}
+
+	// ENS names are looked up on the ENS chain whatever the chain of the reports
+	if config.Ens.ReverseNames {
+		resolver, err := thirdparty.NewNameResolver(config)
+		switch {
+		case err != nil:
+			logging.FromContext(ctx).Warn("ENS reverse names are disabled", logging.KeyError, err)
+		case resolver == nil:
+			logging.FromContext(ctx).Warn("ENS reverse names are disabled, ENS.RESOLVER is not set")
+		default:
+			opts.ReverseNames = NewReverseNames(resolver)
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+/*
+ReportType declares how a report is built. Reports of an account endpoint set Action and Write,
+the result array of the action is streamed into Write. Reports that need other or several
+requests (e.g. paginated event logs) set Generate and fetch their data themselves.
+*/
+type ReportType struct {
+	Name     string // e.g. EXTERNAL_REPORT, used in REPORT_TYPES, logs and the run summary
+	FileType string // {type} in the filename template, e.g. erc-20
+	Action   string // Provider action, e.g. tokentx
+	// Write decodes the result array of Action and writes the report, returns the rows written
+	Write func(ctx context.Context, result *json.Decoder, opts ReportOptions, walletAddress string) (int, error)
+	// Stream decodes the result array of Action and passes every row to the sink, set for transfer reports
+	Stream func(ctx context.Context, result *json.Decoder, opts ReportOptions, sink func(row models.ReportResponse) error) error
+	// Generate fetches the data and writes the report, used instead of Action and Write
+	Generate func(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, opts ReportOptions, walletAddress string) (int, error)
+	// WrittenBy is set for reports written in the same pass as another report, they are never run on their own
+	WrittenBy string
+}
+
+var (
+	reportTypesMu sync.RWMutex
+	reportTypes   = map[string]ReportType{}
+)
+
+func init() {
+	builtin := []ReportType{
+		externalReportType(),
+		{Name: constants.EXTERNAL_DETAILED_REPORT, FileType: "external_detailed", WrittenBy: constants.EXTERNAL_REPORT},
+		TransferReport(constants.INTERNAL_REPORT, "internal", constants.INTERNAL_REPORT_ACTION, internalRow),
+		TransferReport(constants.ERC20_REPORT, "erc-20", constants.ERC20_REPORT_ACTION, erc20Row),
+		TransferReport(constants.ERC721_REPORT, "erc-721", constants.ERC721_REPORT_ACTION, erc721Row),
+		{Name: constants.EVENT_LOG_REPORT, FileType: "event_log", Action: constants.EVENT_LOG_REPORT_ACTION, Generate: generateEventLogReport},
+		{Name: constants.APPROVAL_REPORT, FileType: "approval", Action: constants.APPROVAL_REPORT_ACTION, Generate: generateApprovalReport},
+	}
+	for _, reportType := range builtin {
+		if err := RegisterReportType(reportType); err != nil {
+			panic(err)
+		}
+	}
+}
+
+/*
+TransferReport declares a report of an account endpoint that maps every transaction of the
+result model T to one row of the transfer report, e.g. tokentx to the ERC-20 report.
+Use TransferPipeline to add transforms or filters specific to the report type.
+*/
+func TransferReport[T any](name, fileType, action string, mapRow func(tx T, opts ReportOptions) models.ReportResponse) ReportType {
+	return TransferPipeline(name, fileType, action, Pipeline[T]{Map: mapRow})
+}
+
+// TransferPipeline declares a report of an account endpoint whose rows are produced by the pipeline.
+func TransferPipeline[T any](name, fileType, action string, pipeline Pipeline[T]) ReportType {
+	reportType := ReportType{Name: name, FileType: fileType, Action: action}
+	reportType.Stream = func(ctx context.Context, result *json.Decoder, opts ReportOptions, sink func(row models.ReportResponse) error) error {
+		return pipeline.Run(ctx, result, opts, func(row models.ReportResponse, tx T) error {
+			return sink(row)
+		})
+	}
+	reportType.Write = func(ctx context.Context, result *json.Decoder, opts ReportOptions, walletAddress string) (int, error) {
+		report, err := newReportWriter[models.ReportResponse](ctx, opts, walletAddress, name)
+		if err != nil {
+			logging.FromContext(ctx).Error("resolving report path failed", logging.KeyError, err)
+			return 0, err
+		}
+
+		err = reportType.Stream(ctx, result, opts, report.Write)
+
+		rows, err := finishReports(err, report)
+		if err != nil {
+			logging.FromContext(ctx).Error("writing report failed", "file_type", fileType, logging.KeyError, err)
+			return 0, err
+		}
+		if rows == 0 {
+			logging.FromContext(ctx).Info("no transactions found", "file_type", fileType, logging.KeyWallet, walletAddress)
+		}
+		return rows, nil
+	}
+	return reportType
+}
+
+// The external report writes the detailed report in the same pass
+func externalReportType() ReportType {
+	reportType := TransferPipeline(constants.EXTERNAL_REPORT, "external", constants.EXTERNAL_REPORT_ACTION, externalPipeline)
+	reportType.Write = ExternalReport
+	return reportType
+}
+
+// RegisterReportType adds a report type, names are unique (case-insensitive).
+func RegisterReportType(reportType ReportType) error {
+	if reportType.Name == "" || reportType.FileType == "" {
+		return fmt.Errorf("report type requires a name and a file type")
+	}
+	if reportType.WrittenBy == "" && reportType.Generate == nil && (reportType.Action == "" || reportType.Write == nil) {
+		return fmt.Errorf("report type %s requires an action and a writer, or a generator", reportType.Name)
+	}
+
+	reportTypesMu.Lock()
+	defer reportTypesMu.Unlock()
+	key := strings.ToUpper(reportType.Name)
+	if _, ok := reportTypes[key]; ok {
+		return fmt.Errorf("report type %s is already registered", reportType.Name)
+	}
+	reportTypes[key] = reportType
+	return nil
+}
+
+// LookupReportType returns the report type registered under the given name (case-insensitive).
+func LookupReportType(name string) (ReportType, error) {
+	reportTypesMu.RLock()
+	reportType, ok := reportTypes[strings.ToUpper(strings.TrimSpace(name))]
+	reportTypesMu.RUnlock()
+	if !ok {
+		return ReportType{}, fmt.Errorf("unknown report type '%s', supported report types are: %s", name, strings.Join(ReportTypeNames(), ", "))
+	}
+	return reportType, nil
+}
+
+// ReportTypeNames returns the names of all report types that can be run, sorted.
+func ReportTypeNames() []string {
+	reportTypesMu.RLock()
+	defer reportTypesMu.RUnlock()
+
+	names := make([]string, 0, len(reportTypes))
+	for _, reportType := range reportTypes {
+		if reportType.WrittenBy == "" {
+			names = append(names, reportType.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Resolve the configured report types, sorted by name. An empty list results in all report types,
+duplicates are dropped.
+*/
+func ResolveReportTypes(names []string) ([]ReportType, error) {
+	if len(names) == 0 {
+		names = ReportTypeNames()
+	}
+
+	resolved := []ReportType{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		reportType, err := LookupReportType(name)
+		if err != nil {
+			return nil, err
+		}
+		if reportType.WrittenBy != "" {
+			return nil, fmt.Errorf("report type %s is written together with %s", reportType.Name, reportType.WrittenBy)
+		}
+		if seen[reportType.Name] {
+			continue
+		}
+		seen[reportType.Name] = true
+		resolved = append(resolved, reportType)
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].Name < resolved[j].Name })
+	return resolved, nil
+}
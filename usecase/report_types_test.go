@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestResolveReportTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     []string
+		want      []string
+		expectErr bool
+	}{
+		{
+			name: "All Report Types",
+			want: []string{constants.APPROVAL_REPORT, constants.ERC20_REPORT, constants.ERC721_REPORT, constants.EVENT_LOG_REPORT, constants.EXTERNAL_REPORT, constants.INTERNAL_REPORT},
+		},
+		{
+			name:  "Selected Report Types",
+			names: []string{"erc20_report", constants.EXTERNAL_REPORT, " ERC20_REPORT "},
+			want:  []string{constants.ERC20_REPORT, constants.EXTERNAL_REPORT},
+		},
+		{name: "Unknown Report Type", names: []string{"BEACON_WITHDRAWAL_REPORT"}, expectErr: true},
+		{name: "Written With Another Report", names: []string{constants.EXTERNAL_DETAILED_REPORT}, expectErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := ResolveReportTypes(tc.names)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ResolveReportTypes() error = %v; expectErr %v", err, tc.expectErr)
+			}
+			got := []string{}
+			for _, reportType := range resolved {
+				got = append(got, reportType.Name)
+			}
+			if !tc.expectErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ResolveReportTypes() = %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// A new report type only needs to be registered, the pipeline is unchanged
+func TestRegisterReportType(t *testing.T) {
+	reportType := TransferReport("TEST_INTERNAL_REPORT", "test_internal", constants.INTERNAL_REPORT_ACTION,
+		func(tx models.InternalTransaction, opts ReportOptions) models.ReportResponse {
+			return models.ReportResponse{Chain: opts.Chain.Name, TransactionHash: tx.Hash}
+		})
+	if err := RegisterReportType(reportType); err != nil {
+		t.Fatalf("RegisterReportType() error = %v", err)
+	}
+	t.Cleanup(func() {
+		reportTypesMu.Lock()
+		delete(reportTypes, "TEST_INTERNAL_REPORT")
+		reportTypesMu.Unlock()
+	})
+	if err := RegisterReportType(reportType); err == nil {
+		t.Error("RegisterReportType() of a registered name succeeded")
+	}
+
+	chdirTemp(t)
+	wallet := "0x1111111111111111111111111111111111111111"
+	provider := &responseStubProvider{response: `{"status":"1","message":"OK","result":[{"hash":"0xaa"},{"hash":"0xbb"}]}`}
+	chain, _ := chains.Lookup(constants.CHAIN_ETHEREUM)
+	opts := NewReportOptions(context.Background(), models.Config{}, provider, NewAddressBook(nil), chain)
+
+	rows, err := GenerateReports(context.Background(), provider, opts, wallet, "TEST_INTERNAL_REPORT")
+	if err != nil || rows != 2 {
+		t.Fatalf("GenerateReports() = %d, %v; want 2 rows", rows, err)
+	}
+	if _, err := os.Stat(filepath.Join("files", "reports", wallet+"_ethereum_test_internal_report.csv")); err != nil {
+		t.Errorf("report not written: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
+)
+
+/*
+reportWriter streams rows into a report file, the file is only created with the first row.
+The final file name is resolved on finish, once the dates of the rows are known.
+Completed reports are recorded in the manifest of the run.
+*/
+type reportWriter[T any] struct {
+	opts       ReportOptions
+	servedBy   *thirdparty.ServedProviders
+	wallet     string
+	reportType string
+	filePath   string
+	dates      DateRange
+	writer     *util.CSVWriter[T]
+}
+
+func newReportWriter[T any](ctx context.Context, opts ReportOptions, walletAddress, reportType string) (*reportWriter[T], error) {
+	// The path without dates names the temporary file, which is in the same directory
+	filePath, err := opts.Output.ReportPath(reportType, walletAddress, opts.Chain.Name, DateRange{})
+	if err != nil {
+		return nil, err
+	}
+	return &reportWriter[T]{
+		opts:       opts,
+		servedBy:   thirdparty.ServedProvidersFromContext(ctx),
+		wallet:     walletAddress,
+		reportType: reportType,
+		filePath:   filePath,
+	}, nil
+}
+
+func (w *reportWriter[T]) Write(row T) error {
+	if w.writer == nil {
+		writer, err := util.NewCSVWriter[T](w.filePath, constants.CSV_FLUSH_ROWS, w.opts.ReverseNames.Columns()...)
+		if err != nil {
+			return err
+		}
+		w.writer = writer
+	}
+	w.dates.Add(rowDateTime(row))
+	return w.writer.Write(row)
+}
+
+// rowDateTime returns the date time of a report row, empty for rows without one
+func rowDateTime(row any) string {
+	switch row := row.(type) {
+	case models.ReportResponse:
+		return row.DateTime
+	case models.DetailedReportResponse:
+		return row.DateTime
+	case models.EventLogReportResponse:
+		return row.DateTime
+	case models.ApprovalReportResponse:
+		return row.GrantedAt
+	}
+	return ""
+}
+
+func (w *reportWriter[T]) Rows() int {
+	if w.writer == nil {
+		return 0
+	}
+	return w.writer.Rows()
+}
+
+// finish closes the file, or removes it when the rows could not be written completely
+func (w *reportWriter[T]) finish(failed bool) error {
+	if w.writer == nil {
+		return nil
+	}
+	if failed {
+		return w.writer.Abort()
+	}
+
+	filePath, err := w.opts.Output.ReportPath(w.reportType, w.wallet, w.opts.Chain.Name, w.dates)
+	if err != nil {
+		return errors.Join(err, w.writer.Abort())
+	}
+	report := fmt.Sprintf("%s of %s on %s", w.reportType, w.wallet, w.opts.Chain.Name)
+	if err := w.opts.Output.Claim(filePath, report); err != nil {
+		return errors.Join(err, w.writer.Abort())
+	}
+	if err := w.writer.CloseAs(filePath); err != nil {
+		return err
+	}
+	w.filePath = filePath
+
+	w.opts.Manifest.Add(models.ManifestEntry{
+		File:        w.filePath,
+		ReportType:  w.reportType,
+		Chain:       w.opts.Chain.Name,
+		Wallet:      w.wallet,
+		Rows:        w.writer.Rows(),
+		SHA256:      w.writer.Checksum(),
+		ServedBy:    w.servedBy.String(),
+		StartBlock:  w.opts.StartBlock,
+		EndBlock:    w.opts.EndBlock,
+		GeneratedAt: time.Now().UTC(),
+	})
+	return nil
+}
+
+type finisher interface {
+	Rows() int
+	finish(failed bool) error
+}
+
+/*
+Finish the report files written in one pass. When streaming failed, e.g. the connection dropped or
+the run was canceled, every temporary file is removed and the reports of the previous run are kept.
+Returns the rows of the first (main) report.
+*/
+func finishReports(streamErr error, reports ...finisher) (int, error) {
+	errs := []error{streamErr}
+	for _, report := range reports {
+		errs = append(errs, report.finish(streamErr != nil))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+	return reports[0].Rows(), nil
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+/*
+Count the task outcomes and set the status of the run. The returned error joins the errors of the
+failed tasks. Tasks canceled by the caller (SIGINT, run deadline) add the cause of the cancellation,
+tasks canceled by fail fast do not since the failure that triggered it is already part of the error.
+*/
+func summarizeRun(ctx context.Context, result *models.RunResult, taskErrs []error) error {
+	errs := []error{}
+	for i, task := range result.Tasks {
+		switch task.Status {
+		case constants.RUN_STATUS_SUCCEEDED:
+			result.Succeeded++
+		case constants.RUN_STATUS_CANCELED:
+			result.Canceled++
+		default:
+			result.Failed++
+			errs = append(errs, fmt.Errorf("report generation failed for chain '%s', wallet '%s' and key '%s': %w", task.Chain, task.Wallet, task.ReportType, taskErrs[i]))
+		}
+	}
+	if result.Canceled > 0 && ctx.Err() != nil {
+		errs = append(errs, fmt.Errorf("%d report generation tasks canceled: %w", result.Canceled, context.Cause(ctx)))
+	}
+
+	switch {
+	case result.Failed > 0:
+		result.Status = constants.RUN_STATUS_FAILED
+	case result.Canceled > 0:
+		result.Status = constants.RUN_STATUS_CANCELED
+	default:
+		result.Status = constants.RUN_STATUS_SUCCEEDED
+	}
+	return errors.Join(errs...)
+}
+
+// logRunResult logs the outcome of every task, failed tasks at error level
+func logRunResult(ctx context.Context, result models.RunResult) {
+	logger := logging.FromContext(ctx)
+	for _, task := range result.Tasks {
+		args := []any{logging.KeyChain, task.Chain, logging.KeyWallet, task.Wallet, logging.KeyReportType, task.ReportType,
+			"status", task.Status, "duration_ms", task.DurationMillis, "rows", task.Rows}
+		switch task.Status {
+		case constants.RUN_STATUS_FAILED:
+			logger.Error("report task finished", append(args, logging.KeyError, task.Error)...)
+		case constants.RUN_STATUS_CANCELED:
+			logger.Warn("report task finished", append(args, logging.KeyError, task.Error)...)
+		default:
+			logger.Info("report task finished", args...)
+		}
+	}
+}
+
+// WriteRunSummary writes the run result as JSON into the reports directory of the run.
+func WriteRunSummary(reportsDir string, result models.RunResult) error {
+	if err := os.MkdirAll(reportsDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create reports directory %s: %w", reportsDir, err)
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run summary: %w", err)
+	}
+	return util.WriteFileAtomic(filepath.Join(reportsDir, constants.RUN_SUMMARY_FILE), data, 0o644)
+}
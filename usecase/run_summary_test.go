@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+func TestSummarizeRun(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		statuses    []string
+		errs        []error
+		wantStatus  string
+		wantErr     []error
+		wantNoError bool
+	}{
+		{
+			name:        "All Succeeded",
+			ctx:         context.Background(),
+			statuses:    []string{constants.RUN_STATUS_SUCCEEDED, constants.RUN_STATUS_SUCCEEDED},
+			errs:        []error{nil, nil},
+			wantStatus:  constants.RUN_STATUS_SUCCEEDED,
+			wantNoError: true,
+		},
+		{
+			name:       "Fail Fast Cancels Siblings",
+			ctx:        context.Background(),
+			statuses:   []string{constants.RUN_STATUS_FAILED, constants.RUN_STATUS_CANCELED},
+			errs:       []error{errFetch, context.Canceled},
+			wantStatus: constants.RUN_STATUS_FAILED,
+			wantErr:    []error{errFetch},
+		},
+		{
+			name:       "Canceled By Caller",
+			ctx:        canceledCtx,
+			statuses:   []string{constants.RUN_STATUS_SUCCEEDED, constants.RUN_STATUS_CANCELED},
+			errs:       []error{nil, context.Canceled},
+			wantStatus: constants.RUN_STATUS_CANCELED,
+			wantErr:    []error{context.Canceled},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := models.RunResult{}
+			for _, status := range tc.statuses {
+				result.Tasks = append(result.Tasks, models.ReportTaskResult{Status: status})
+			}
+
+			err := summarizeRun(tc.ctx, &result, tc.errs)
+			if result.Status != tc.wantStatus {
+				t.Errorf("Status = %s; want %s", result.Status, tc.wantStatus)
+			}
+			if tc.wantNoError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for _, want := range tc.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("error = %v; want it to wrap %v", err, want)
+				}
+			}
+		})
+	}
+}
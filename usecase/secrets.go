@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+/*
+Load the secrets of the config so they do not need to be stored in plaintext in config.yml.
+Explorer API keys are read from API_KEY_FILE, API_KEY_ENV or API_KEY, in that order.
+RPC endpoints often carry the key in the URL, ${VAR} references in them are read from the environment.
+Webhook secrets are read from SECRET_ENV or SECRET.
+Every secret is registered for redaction so it never shows up in logs or errors.
+*/
+func ResolveSecrets(config *models.Config) error {
+	for name, apiConfig := range map[string]*models.ThirdPartyApiConfig{
+		"ETHERSCAN":  &config.Etherscan,
+		"BLOCKSCOUT": &config.Blockscout,
+	} {
+		apiKey, err := resolveApiKey(*apiConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load %s API key: %w", name, err)
+		}
+		apiConfig.ApiKey = apiKey
+		util.RegisterSecret(apiKey)
+	}
+
+	for chain, endpoint := range config.Rpc.Endpoints {
+		var missing []string
+		config.Rpc.Endpoints[chain] = os.Expand(endpoint, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			util.RegisterSecret(value)
+			return value
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("environment variables %s of the %s RPC endpoint are not set", strings.Join(missing, ", "), chain)
+		}
+	}
+
+	for name, webhookConfig := range map[string]*models.WebhookConfig{
+		"WATCH":  &config.Watch.Webhook,
+		"ALERTS": &config.Alerts.Webhook,
+	} {
+		if webhookConfig.SecretEnv != "" {
+			secret, ok := os.LookupEnv(webhookConfig.SecretEnv)
+			if !ok {
+				return fmt.Errorf("environment variable %s of the %s webhook secret is not set", webhookConfig.SecretEnv, name)
+			}
+			webhookConfig.Secret = strings.TrimSpace(secret)
+		}
+		util.RegisterSecret(webhookConfig.Secret)
+	}
+
+	return nil
+}
+
+func resolveApiKey(apiConfig models.ThirdPartyApiConfig) (string, error) {
+	if apiConfig.ApiKeyFile != "" {
+		data, err := os.ReadFile(apiConfig.ApiKeyFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if apiConfig.ApiKeyEnv != "" {
+		apiKey, ok := os.LookupEnv(apiConfig.ApiKeyEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", apiConfig.ApiKeyEnv)
+		}
+		return strings.TrimSpace(apiKey), nil
+	}
+
+	return apiConfig.ApiKey, nil
+}
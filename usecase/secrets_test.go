@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/util"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "blockscout_key")
+	if err := os.WriteFile(keyFile, []byte("blockscout-file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ETHERSCAN_KEY", "etherscan-env-key")
+	t.Setenv("TEST_RPC_KEY", "rpc-env-key")
+	t.Setenv("TEST_WEBHOOK_SECRET", "webhook-env-secret")
+
+	config := models.Config{
+		Etherscan:  models.ThirdPartyApiConfig{ApiKey: "plaintext", ApiKeyEnv: "TEST_ETHERSCAN_KEY"},
+		Blockscout: models.ThirdPartyApiConfig{ApiKeyFile: keyFile},
+		Rpc:        models.RpcConfig{Endpoints: map[string]string{"ethereum": "https://eth.example.com/v2/${TEST_RPC_KEY}"}},
+		Watch:      models.WatchConfig{Webhook: models.WebhookConfig{Secret: "plaintext", SecretEnv: "TEST_WEBHOOK_SECRET"}},
+	}
+	if err := ResolveSecrets(&config); err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+
+	if config.Etherscan.ApiKey != "etherscan-env-key" {
+		t.Errorf("Etherscan.ApiKey = %q; want the key from the environment", config.Etherscan.ApiKey)
+	}
+	if config.Blockscout.ApiKey != "blockscout-file-key" {
+		t.Errorf("Blockscout.ApiKey = %q; want the key from the file", config.Blockscout.ApiKey)
+	}
+	if endpoint := config.Rpc.Endpoints["ethereum"]; endpoint != "https://eth.example.com/v2/rpc-env-key" {
+		t.Errorf("Endpoints[ethereum] = %q; want the expanded endpoint", endpoint)
+	}
+	if config.Watch.Webhook.Secret != "webhook-env-secret" {
+		t.Errorf("Watch.Webhook.Secret = %q; want the secret from the environment", config.Watch.Webhook.Secret)
+	}
+	if got := util.Redact("https://eth.example.com/v2/rpc-env-key?k=etherscan-env-key"); got != "https://eth.example.com/v2/REDACTED?k=REDACTED" {
+		t.Errorf("secrets are not registered for redaction: %q", got)
+	}
+
+	config = models.Config{Etherscan: models.ThirdPartyApiConfig{ApiKeyEnv: "TEST_UNSET_KEY"}}
+	if err := ResolveSecrets(&config); err == nil {
+		t.Error("expected an error for an unset environment variable")
+	}
+}
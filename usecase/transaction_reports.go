@@ -1,313 +1,555 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
 	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
 	"github.com/coin-tracker/transaction-tracker/shared/util"
 	thirdparty "github.com/coin-tracker/transaction-tracker/third-party"
 )
 
-func GenerateTransactionReports(providerType string, config models.Config) error {
+/*
+Generate every report for every configured wallet and chain concurrently. All requests are
+canceled once the context is done, e.g. on SIGINT or when the run deadline is exceeded.
+Every task is run to completion unless FAIL_FAST is set, the result lists the outcome of each
+task and the returned error joins the errors of all failed tasks.
+*/
+func GenerateTransactionReports(ctx context.Context, providerType string, config models.Config) (models.RunResult, error) {
+	// Every line logged by the run carries its run ID
+	runID := logging.NewRunID()
+	ctx = logging.With(ctx, logging.KeyRunID, runID, logging.KeyProvider, providerType)
+	logger := logging.FromContext(ctx)
+
+	config, err := ResolveWalletNames(ctx, config)
+	if err != nil {
+		logger.Error("resolving ENS names failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
+	wallets := ConfiguredWallets(config)
+	if len(wallets) == 0 {
+		return models.RunResult{}, fmt.Errorf("no wallet address configured")
+	}
+
+	chainList, err := chains.Resolve(config.Chains)
+	if err != nil {
+		logger.Error("resolving chains failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
 
-	dataProvider, err := thirdparty.NewDataProvider(providerType, config)
+	addressBook, err := LoadAddressBook(config.AddressBookPath, wallets)
 	if err != nil {
-		fmt.Printf("Error creating data provider: %v\n", err)
-		return err
-	}
-
-	/*
-		Result from txlist -> External Transaction
-		Result from txlistinternal -> Internal Transaction
-		Result from tokentx -> ERC-20 Token Transfer
-		Result from tokennfttx -> ERC-721/ERC-1155 (NFT) Token Transfer
-	*/
-	actionTagMap := map[string]string{
-		constants.EXTERNAL_REPORT: constants.EXTERNAL_REPORT_ACTION,
-		constants.INTERNAL_REPORT: constants.INTERNAL_REPORT_ACTION,
-		constants.ERC20_REPORT:    constants.ERC20_REPORT_ACTION,
-		constants.ERC721_REPORT:   constants.ERC721_REPORT_ACTION,
-	}
-	numTasks := len(actionTagMap)
-	// Create a buffered channel to receive potential errors.
-	// Buffer size equals the number of tasks to prevent goroutines from blocking on send.
-	errChan := make(chan error, numTasks)
+		logger.Error("loading address book failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
+
+	// With fail fast the first failed task cancels all others
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+
+	startedAt := time.Now()
+	output, err := NewOutputLayout(providerType, config, startedAt)
+	if err != nil {
+		logger.Error("resolving the output directory failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
+
+	// Every report written by the run is listed with its checksum in the manifest
+	manifest := NewManifestRecorder(providerType, config)
+
+	alerts, err := NewAlertEngine(config.Alerts)
+	if err != nil {
+		logger.Error("loading alert rules failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
+
+	// Reports are generated per wallet per chain, each chain has its own provider
+	dataProviders := make([]thirdparty.BlockchainDataProvider, 0, len(chainList))
+	chainOpts := make([]ReportOptions, 0, len(chainList))
+	for _, chain := range chainList {
+		dataProvider, err := thirdparty.NewDataProvider(providerType, config, chain)
+		if err != nil {
+			logger.Error("creating data provider failed", logging.KeyChain, chain.Name, logging.KeyError, err)
+			return models.RunResult{}, err
+		}
+		opts := NewReportOptions(runCtx, config, dataProvider, addressBook, chain)
+		opts.Manifest = manifest
+		opts.Output = output
+		opts.Alerts = alerts
+		dataProviders = append(dataProviders, dataProvider)
+		chainOpts = append(chainOpts, opts)
+	}
+
+	reportTypes, err := ResolveReportTypes(config.ReportTypes)
+	if err != nil {
+		logger.Error("resolving report types failed", logging.KeyError, err)
+		return models.RunResult{}, err
+	}
+
+	tasks := []reportTask{}
+	for i := range chainList {
+		for _, wallet := range wallets {
+			for _, reportType := range reportTypes {
+				tasks = append(tasks, reportTask{
+					dataProvider: dataProviders[i],
+					opts:         chainOpts[i],
+					wallet:       wallet.Address,
+					reportType:   reportType.Name,
+				})
+			}
+		}
+	}
+
+	result := models.RunResult{
+		RunID:     runID,
+		StartedAt: startedAt,
+		FailFast:  config.FailFast,
+		Tasks:     make([]models.ReportTaskResult, len(tasks)),
+	}
+	taskErrs := make([]error, len(tasks))
 
 	// Use a WaitGroup to wait for all goroutines to finish.
 	var wg sync.WaitGroup
 
-	fmt.Printf("Starting concurrent generation of %d reports...\n", numTasks)
-	for key, value := range actionTagMap {
+	logger.Info("starting concurrent report generation", "reports", len(tasks))
+	for i, task := range tasks {
 		// Increment the WaitGroup counter for each goroutine we are about to launch.
 		wg.Add(1)
-		go func(k, v string) {
+		go func(i int, task reportTask) {
 			// Decrement the counter when the goroutine finishes, regardless of success or failure.
 			defer wg.Done()
 
-			fmt.Printf("[%s] Starting report generation...\n", k)
-			err = GenerateReports(dataProvider, config.WalletAddress, v, k)
+			chainName := task.opts.Chain.Name
+			taskCtx := logging.With(runCtx, logging.KeyChain, chainName, logging.KeyWallet, task.wallet, logging.KeyReportType, task.reportType)
+			logger := logging.FromContext(taskCtx)
+			logger.Info("starting report generation")
+
+			// The failover chain records the providers that served the task, other providers serve it alone
+			servedBy := &thirdparty.ServedProviders{}
+			if !strings.EqualFold(providerType, constants.PROVIDER_FAILOVER) {
+				servedBy.Record(strings.ToLower(providerType))
+			}
+			taskCtx = thirdparty.WithServedProviders(taskCtx, servedBy)
+
+			// The reports cover the blocks up to the head known before their data is requested
+			opts := task.opts
+			opts.StartBlock, opts.EndBlock = resolveBlockRange(taskCtx, task.dataProvider, providerType, config)
+
+			startedAt := time.Now()
+			rows, err := GenerateReports(taskCtx, task.dataProvider, opts, task.wallet, task.reportType)
+
+			taskResult := models.ReportTaskResult{
+				Chain:          chainName,
+				Wallet:         task.wallet,
+				ReportType:     task.reportType,
+				Status:         constants.RUN_STATUS_SUCCEEDED,
+				StartedAt:      startedAt,
+				DurationMillis: time.Since(startedAt).Milliseconds(),
+				Rows:           rows,
+				ServedBy:       servedBy.String(),
+			}
 			if err != nil {
-				fmt.Printf("[%s] Error generating report: %v\n", k, err)
-				// Send the error to the error channel. Wrap it for context.
-				errChan <- fmt.Errorf("report generation failed for key '%s': %w", k, err)
+				taskResult.Error = err.Error()
+				if runCtx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+					taskResult.Status = constants.RUN_STATUS_CANCELED
+				} else {
+					taskResult.Status = constants.RUN_STATUS_FAILED
+					logger.Error("report generation failed", logging.KeyError, err)
+					if config.FailFast {
+						cancelRun(fmt.Errorf("fail fast after %s report of wallet %s on %s failed", task.reportType, task.wallet, chainName))
+					}
+				}
 			}
-		}(key, value)
+
+			metrics.ReportRows.Add(float64(rows), chainName, task.reportType)
+			metrics.ReportDuration.Observe(time.Since(startedAt).Seconds(), chainName, task.reportType, taskResult.Status)
+
+			// Every goroutine owns its slot of the results
+			result.Tasks[i] = taskResult
+			taskErrs[i] = err
+		}(i, task)
 	}
 
 	// Wait for all goroutines launched in the loop to finish.
-	fmt.Println("Waiting for report generation tasks to complete...")
+	logger.Info("waiting for report generation tasks to complete")
 	wg.Wait()
-	fmt.Println("All report generation tasks finished.")
-
-	// Close the error channel *after* all goroutines are guaranteed to be done.
-	// This signals to the reading loop below that no more errors will be sent.
-	close(errChan)
-
-	// Check if any errors were sent to the channel.
-	// We'll collect the first error encountered. If multiple goroutines fail,
-	// only the first error received here will be returned.
-	var firstError error
-	for err := range errChan {
-		if firstError == nil {
-			firstError = err
+	logger.Info("all report generation tasks finished")
+
+	for _, dataProvider := range dataProviders {
+		if failover, ok := dataProvider.(*thirdparty.FailoverProvider); ok {
+			for _, health := range failover.Health() {
+				logger.Info("provider health", "name", health.Name, "state", health.State, "successes", health.Successes, "failures", health.Failures)
+			}
 		}
 	}
 
-	// Return the first error encountered, or nil if all succeeded.
-	if firstError != nil {
-		fmt.Printf("One or more report generation tasks failed. Returning first error: %v\n", firstError)
-		return firstError
-	}
+	result.FinishedAt = time.Now()
+	result.DurationMillis = result.FinishedAt.Sub(result.StartedAt).Milliseconds()
+	runErr := summarizeRun(ctx, &result, taskErrs)
 
-	fmt.Println("All reports generated successfully.")
+	logRunResult(ctx, result)
+	if err := WriteRunSummary(output.Directory, result); err != nil {
+		logger.Error("writing run summary failed", logging.KeyError, err)
+		runErr = errors.Join(runErr, err)
+	}
+	if err := manifest.Write(output.Directory); err != nil {
+		logger.Error("writing manifest failed", logging.KeyError, err)
+		runErr = errors.Join(runErr, err)
+	}
 
-	return nil
-}
+	if runErr != nil {
+		logger.Error("report generation tasks failed", "failed", result.Failed, "canceled", result.Canceled, "tasks", len(result.Tasks))
+		return result, runErr
+	}
 
-func GenerateReports(dataProvider thirdparty.BlockchainDataProvider, walletAddress, action, tag string) error {
+	logger.Info("all reports generated successfully")
 
-	url := dataProvider.BuildRequestURL(action, walletAddress) // Use the helper
+	return result, nil
+}
 
-	fmt.Printf("Request URL for [%s]- %s", tag, url)
+// reportTask is one report type for one wallet on one chain
+type reportTask struct {
+	dataProvider thirdparty.BlockchainDataProvider
+	opts         ReportOptions
+	wallet       string
+	reportType   string
+}
 
-	res, err := dataProvider.FetchTransactionData(url, tag)
-	if err != nil {
-		fmt.Printf("Error fetching transaction data: %v\n", err)
-		return err
+/*
+Collect the wallets from the config. WALLET_ADDRESS is kept for backwards compatibility
+and is merged with the WALLETS list, duplicates are dropped. Addresses are in EIP-55 form.
+*/
+func ConfiguredWallets(config models.Config) []models.WalletConfig {
+	wallets := []models.WalletConfig{}
+	seen := map[string]bool{}
+
+	candidates := append([]models.WalletConfig{{Address: config.WalletAddress}}, config.Wallets...)
+	for _, wallet := range candidates {
+		wallet.Address = util.ChecksumAddress(strings.TrimSpace(wallet.Address))
+		key := normalizeAddress(wallet.Address)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		wallets = append(wallets, wallet)
 	}
+	return wallets
+}
 
-	resp := models.EtherscanBaseResponse{}
-	err = json.Unmarshal([]byte(res), &resp)
+/*
+GenerateReports generates one report for a wallet, tag is the name of a registered report type.
+Reports of an account endpoint are streamed while the response is received.
+*/
+func GenerateReports(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, opts ReportOptions, walletAddress, tag string) (int, error) {
+	reportType, err := LookupReportType(tag)
 	if err != nil {
-		fmt.Printf("Error unmarshalling transaction data: %v\n", err)
-		return err
+		return 0, err
+	}
+	if reportType.Generate != nil {
+		return reportType.Generate(ctx, dataProvider, opts, walletAddress)
 	}
 
-	switch tag {
-	case constants.EXTERNAL_REPORT:
-		err = ExternalReport(resp.Result, walletAddress)
-	case constants.INTERNAL_REPORT:
-		err = InternalReport(resp.Result, walletAddress)
-	case constants.ERC20_REPORT:
-		err = Erc20Report(resp.Result, walletAddress)
-	case constants.ERC721_REPORT:
-		err = Erc721Report(resp.Result, walletAddress)
+	body, result, message, err := openResult(ctx, dataProvider, reportType.Action, walletAddress, tag)
+	if err != nil {
+		return 0, err
 	}
+	defer body.Close()
 
+	rows, err := reportType.Write(ctx, result, opts, walletAddress)
 	if err != nil {
-		fmt.Printf("Error generating transaction report: %v\n", err)
-		return err
+		err = withProviderMessage(message, err)
+		logging.FromContext(ctx).Error("generating transaction report failed", logging.KeyError, err)
+		return 0, err
 	}
 
-	return nil
+	return rows, nil
 }
 
-func ExternalReport(res json.RawMessage, walletAddress string) error {
+/*
+ReportRows returns the rows of a transfer report (e.g. ERC20_REPORT) instead of writing the report file.
+The rows pass through the same pipeline as the written report.
+*/
+func ReportRows(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, opts ReportOptions, walletAddress, tag string) ([]models.ReportResponse, error) {
+	reportType, err := LookupReportType(tag)
+	if err != nil {
+		return nil, err
+	}
+	if reportType.Stream == nil {
+		return nil, fmt.Errorf("report type %s does not produce transfer rows", reportType.Name)
+	}
 
-	txList := []models.ExternalTransaction{}
-	err := json.Unmarshal(res, &txList)
+	body, result, message, err := openResult(ctx, dataProvider, reportType.Action, walletAddress, tag)
 	if err != nil {
-		fmt.Printf("Error unmarshalling transaction data: %v\n", err)
-		return err
+		return nil, err
 	}
+	defer body.Close()
 
-	if len(txList) == 0 {
-		fmt.Printf("No External transactions found for wallet address: %s\n", walletAddress)
+	rows := []models.ReportResponse{}
+	err = reportType.Stream(ctx, result, opts, func(row models.ReportResponse) error {
+		rows = append(rows, row)
 		return nil
+	})
+	if err != nil {
+		return nil, withProviderMessage(message, err)
 	}
+	return rows, nil
+}
 
-	csvResp := []models.ReportResponse{}
-	for _, tx := range txList {
-
-		dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
-		csvResp = append(csvResp, models.ReportResponse{
-			TransactionHash:      tx.Hash,
-			DateTime:             dateTime,
-			FromAddress:          tx.From,
-			ToAddress:            tx.To,
-			TransactionType:      constants.TRANSACTION_TYPE_ETH_TRANSFER,
-			AssetContractAddress: tx.ContractAddress,
-			AssetSymbolName:      constants.TOKEN_SYMBOL_ETH,
-			TokenID:              "",
-			ValueAmount:          tx.Value,
-			GasFeeEth:            tx.Gas,
-		})
+// FetchResult returns the result array of an account endpoint (e.g. txlist) decoded into the result model T.
+func FetchResult[T any](ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, walletAddress, action, tag string) ([]T, error) {
+	body, result, message, err := openResult(ctx, dataProvider, action, walletAddress, tag)
+	if err != nil {
+		return nil, err
 	}
+	defer body.Close()
 
-	dir, err := util.GetCurrentWorkingDirectory()
+	txList := []T{}
+	err = util.DecodeJSONArray(result, func(tx T) error {
+		txList = append(txList, tx)
+		return nil
+	})
 	if err != nil {
-		fmt.Printf("Error getting current working directory: %v\n", err)
-		return err
+		return nil, withProviderMessage(message, fmt.Errorf("error unmarshalling transaction data: %w", err))
 	}
+	return txList, nil
+}
+
+/*
+openResult requests the action for the wallet and positions the decoder at the result array, the
+caller must close the body. The array is decoded while it is received, one transaction at a time.
+*/
+func openResult(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, action, walletAddress, tag string) (io.ReadCloser, *json.Decoder, string, error) {
+	url := dataProvider.BuildRequestURL(action, walletAddress)
+	logger := logging.FromContext(ctx)
+	logger.Debug("requesting transaction data", "tag", tag, "url", util.RedactURL(url))
 
-	filePath := filepath.Join(dir, "/files/reports", walletAddress+"_external_report.csv")
-	err = util.WriteCSV(filePath, csvResp)
+	body, err := openTransactionData(ctx, dataProvider, url, tag)
 	if err != nil {
-		fmt.Printf("Error writing external report to file: %v\n", err)
-		return err
+		logger.Error("fetching transaction data failed", "tag", tag, logging.KeyError, err)
+		return nil, nil, "", err
 	}
 
-	return nil
+	if err := ctx.Err(); err != nil {
+		body.Close()
+		return nil, nil, "", err
+	}
 
+	result := json.NewDecoder(body)
+	message, err := seekResult(result)
+	if err != nil {
+		body.Close()
+		logger.Error("unmarshalling transaction data failed", "tag", tag, logging.KeyError, err)
+		return nil, nil, "", err
+	}
+	return body, result, message, nil
 }
 
-func InternalReport(res json.RawMessage, walletAddress string) error {
-	txList := []models.InternalTransaction{}
-	err := json.Unmarshal(res, &txList)
-	if err != nil {
-		fmt.Printf("Error unmarshalling transaction data: %v\n", err)
-		return err
+// Explorer errors come with a message such as "NOTOK" and the reason as the result
+func withProviderMessage(message string, err error) error {
+	if message != "" && message != "OK" {
+		return fmt.Errorf("%s: %w", message, err)
 	}
+	return err
+}
 
-	if len(txList) == 0 {
-		fmt.Printf("No Internal transactions found for wallet address: %s\n", walletAddress)
-		return nil
+// openTransactionData streams the response when the provider supports it, otherwise the buffered response is read
+func openTransactionData(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, url, tag string) (io.ReadCloser, error) {
+	if streaming, ok := dataProvider.(thirdparty.StreamingDataProvider); ok {
+		return streaming.StreamTransactionData(ctx, url, tag)
 	}
 
-	csvResp := []models.ReportResponse{}
-	for _, tx := range txList {
-		dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
-		csvResp = append(csvResp, models.ReportResponse{
-			TransactionHash:      tx.Hash,
-			DateTime:             dateTime,
-			FromAddress:          tx.From,
-			ToAddress:            tx.To,
-			TransactionType:      constants.TRANSACTION_TYPE_INTERNAL_TRANSFER,
-			AssetContractAddress: tx.ContractAddress,
-			AssetSymbolName:      constants.TOKEN_SYMBOL_ETH,
-			TokenID:              "",
-			ValueAmount:          tx.Value,
-			GasFeeEth:            tx.Gas,
-		})
-	}
-
-	dir, err := util.GetCurrentWorkingDirectory()
+	res, err := dataProvider.FetchTransactionData(ctx, url, tag)
 	if err != nil {
-		fmt.Printf("Error getting current working directory: %v\n", err)
-		return err
+		return nil, err
 	}
+	return io.NopCloser(strings.NewReader(res)), nil
+}
 
-	filePath := filepath.Join(dir, "/files/reports", walletAddress+"_internal_report.csv")
-	err = util.WriteCSV(filePath, csvResp)
-	if err != nil {
-		fmt.Printf("Error writing external report to file: %v\n", err)
-		return err
+/*
+Position the decoder at the value of the "result" field of an Etherscan response without decoding it.
+Etherscan sends status and message first, the message is returned for error context.
+*/
+func seekResult(dec *json.Decoder) (string, error) {
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return "", fmt.Errorf("invalid provider response, expected a JSON object: %v", err)
 	}
-	return nil
+
+	message := ""
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("invalid provider response: %w", err)
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "result":
+			return message, nil
+		case "message":
+			if err := dec.Decode(&message); err != nil {
+				return "", fmt.Errorf("invalid provider response message: %w", err)
+			}
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return "", fmt.Errorf("invalid provider response: %w", err)
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid provider response, no result: %s", message)
 }
 
-func Erc20Report(res json.RawMessage, walletAddress string) error {
-	txList := []models.TokenTransaction{}
-	err := json.Unmarshal(res, &txList)
+/*
+ExternalReport streams the external transactions of the result array, the decoder is positioned
+at the start of the array. The detailed report is written in the same pass.
+*/
+func ExternalReport(ctx context.Context, result *json.Decoder, opts ReportOptions, walletAddress string) (int, error) {
+	report, err := newReportWriter[models.ReportResponse](ctx, opts, walletAddress, constants.EXTERNAL_REPORT)
+	if err != nil {
+		logging.FromContext(ctx).Error("resolving report path failed", logging.KeyError, err)
+		return 0, err
+	}
+	detailed, err := newReportWriter[models.DetailedReportResponse](ctx, opts, walletAddress, constants.EXTERNAL_DETAILED_REPORT)
 	if err != nil {
-		fmt.Printf("Error unmarshalling transaction data: %v\n", err)
-		return err
+		logging.FromContext(ctx).Error("resolving report path failed", logging.KeyError, err)
+		return 0, err
 	}
 
-	if len(txList) == 0 {
-		fmt.Printf("No ERC-20 transactions found for wallet address: %s\n", walletAddress)
+	err = externalPipeline.Run(ctx, result, opts, func(row models.ReportResponse, tx models.ExternalTransaction) error {
+		if err := report.Write(row); err != nil {
+			return err
+		}
+
+		if opts.DetailedReport {
+			return detailed.Write(DetailedReportRow(ctx, tx, opts))
+		}
 		return nil
-	}
+	})
 
-	csvResp := []models.ReportResponse{}
-	for _, tx := range txList {
-		dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
-		csvResp = append(csvResp, models.ReportResponse{
-			TransactionHash:      tx.Hash,
-			DateTime:             dateTime,
-			FromAddress:          tx.From,
-			ToAddress:            tx.To,
-			TransactionType:      constants.TRANSACTION_TYPE_ERC20_TRANSFER,
-			AssetContractAddress: tx.ContractAddress,
-			AssetSymbolName:      tx.TokenSymbol + " " + tx.TokenName,
-			TokenID:              "",
-			ValueAmount:          tx.Value,
-			GasFeeEth:            tx.Gas,
-		})
-	}
-
-	dir, err := util.GetCurrentWorkingDirectory()
+	rows, err := finishReports(err, report, detailed)
 	if err != nil {
-		fmt.Printf("Error getting current working directory: %v\n", err)
-		return err
+		logging.FromContext(ctx).Error("writing external report failed", logging.KeyError, err)
+		return 0, err
+	}
+	if rows == 0 {
+		logging.FromContext(ctx).Info("no external transactions found", logging.KeyWallet, walletAddress)
 	}
+	return rows, nil
+}
 
-	filePath := filepath.Join(dir, "/files/reports", walletAddress+"_erc-20_report.csv")
-	err = util.WriteCSV(filePath, csvResp)
-	if err != nil {
-		fmt.Printf("Error writing external report to file: %v\n", err)
-		return err
+var externalPipeline = Pipeline[models.ExternalTransaction]{Map: externalRow}
+
+// externalRow maps a transaction of txlist to a row of the external report.
+func externalRow(tx models.ExternalTransaction, opts ReportOptions) models.ReportResponse {
+	dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
+	gasFee, _ := util.CalculateGasFee(tx.GasUsed, tx.GasPrice, opts.Chain.Decimals)
+	return models.ReportResponse{
+		Chain:                opts.Chain.Name,
+		TransactionHash:      tx.Hash,
+		DateTime:             dateTime,
+		FromAddress:          util.ChecksumAddress(tx.From),
+		ToAddress:            util.ChecksumAddress(tx.To),
+		TransactionType:      NativeTransferType(opts.Chain),
+		AssetContractAddress: util.ChecksumAddress(tx.ContractAddress),
+		AssetSymbolName:      opts.Chain.NativeSymbol,
+		TokenID:              "",
+		ValueAmount:          tx.Value,
+		GasFeeNative:         gasFee,
 	}
+}
 
-	return nil
+// NativeTransferType returns the transaction type of native currency transfers on the chain, e.g. POL Transfer.
+func NativeTransferType(chain models.Chain) string {
+	symbol := chain.NativeSymbol
+	if symbol == "" {
+		symbol = constants.TOKEN_SYMBOL_ETH
+	}
+	return fmt.Sprintf(constants.TRANSACTION_TYPE_NATIVE_TRANSFER, symbol)
 }
 
-func Erc721Report(res json.RawMessage, walletAddress string) error {
-	txList := []models.NftTransaction{}
-	err := json.Unmarshal(res, &txList)
-	if err != nil {
-		fmt.Printf("Error unmarshalling transaction data: %v\n", err)
-		return err
+// internalRow maps an internal transaction of txlistinternal to a row of the internal report.
+func internalRow(tx models.InternalTransaction, opts ReportOptions) models.ReportResponse {
+	dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
+	return models.ReportResponse{
+		Chain:                opts.Chain.Name,
+		TransactionHash:      tx.Hash,
+		DateTime:             dateTime,
+		FromAddress:          util.ChecksumAddress(tx.From),
+		ToAddress:            util.ChecksumAddress(tx.To),
+		TransactionType:      constants.TRANSACTION_TYPE_INTERNAL_TRANSFER,
+		AssetContractAddress: util.ChecksumAddress(tx.ContractAddress),
+		AssetSymbolName:      opts.Chain.NativeSymbol,
+		TokenID:              "",
+		ValueAmount:          tx.Value,
+		GasFeeNative:         "", // Paid by the parent transaction, see the external report
 	}
+}
 
-	if len(txList) == 0 {
-		fmt.Printf("No ERC-721 transactions found for wallet address: %s\n", walletAddress)
-		return nil
+// erc20Row maps a token transfer of tokentx to a row of the ERC-20 report.
+func erc20Row(tx models.TokenTransaction, opts ReportOptions) models.ReportResponse {
+	dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
+	gasFee, _ := util.CalculateGasFee(tx.GasUsed, tx.GasPrice, opts.Chain.Decimals)
+	return models.ReportResponse{
+		Chain:                opts.Chain.Name,
+		TransactionHash:      tx.Hash,
+		DateTime:             dateTime,
+		FromAddress:          util.ChecksumAddress(tx.From),
+		ToAddress:            util.ChecksumAddress(tx.To),
+		TransactionType:      constants.TRANSACTION_TYPE_ERC20_TRANSFER,
+		AssetContractAddress: util.ChecksumAddress(tx.ContractAddress),
+		AssetSymbolName:      tx.TokenSymbol + " " + tx.TokenName,
+		TokenID:              "",
+		ValueAmount:          tx.Value,
+		GasFeeNative:         gasFee,
 	}
+}
 
-	csvResp := []models.ReportResponse{}
-	for _, tx := range txList {
-		dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
-		csvResp = append(csvResp, models.ReportResponse{
-			TransactionHash:      tx.Hash,
-			DateTime:             dateTime,
-			FromAddress:          tx.From,
-			ToAddress:            tx.To,
-			TransactionType:      constants.TRANSACTION_TYPE_ERC721_TRANSFER,
-			AssetContractAddress: tx.ContractAddress,
-			AssetSymbolName:      tx.TokenSymbol + " " + tx.TokenName,
-			TokenID:              tx.TokenID,
-			ValueAmount:          tx.TransactionIndex,
-			GasFeeEth:            tx.Gas,
-		})
-	}
-
-	dir, err := util.GetCurrentWorkingDirectory()
-	if err != nil {
-		fmt.Printf("Error getting current working directory: %v\n", err)
-		return err
+// erc721Row maps an NFT transfer of tokennfttx to a row of the ERC-721 report.
+func erc721Row(tx models.NftTransaction, opts ReportOptions) models.ReportResponse {
+	dateTime, _ := util.FormatUnixTimestampString(tx.TimeStamp)
+	gasFee, _ := util.CalculateGasFee(tx.GasUsed, tx.GasPrice, opts.Chain.Decimals)
+	return models.ReportResponse{
+		Chain:                opts.Chain.Name,
+		TransactionHash:      tx.Hash,
+		DateTime:             dateTime,
+		FromAddress:          util.ChecksumAddress(tx.From),
+		ToAddress:            util.ChecksumAddress(tx.To),
+		TransactionType:      constants.TRANSACTION_TYPE_ERC721_TRANSFER,
+		AssetContractAddress: util.ChecksumAddress(tx.ContractAddress),
+		AssetSymbolName:      tx.TokenSymbol + " " + tx.TokenName,
+		TokenID:              tx.TokenID,
+		ValueAmount:          tx.TransactionIndex,
+		GasFeeNative:         gasFee,
 	}
+}
 
-	filePath := filepath.Join(dir, "/files/reports", walletAddress+"_erc-721_report.csv")
-	err = util.WriteCSV(filePath, csvResp)
+/*
+resolveBlockRange returns the block range requested from the provider with an open end replaced by the
+current head of the chain. The end is 0 when the provider can not tell its head, e.g. in replayed runs.
+*/
+func resolveBlockRange(ctx context.Context, dataProvider thirdparty.BlockchainDataProvider, providerType string, config models.Config) (uint64, uint64) {
+	startBlock, endBlock := requestedBlockRange(providerType, config)
+	if endBlock != 0 {
+		return startBlock, endBlock
+	}
+	headProvider, ok := dataProvider.(thirdparty.ChainHeadProvider)
+	if !ok {
+		return startBlock, 0
+	}
+	head, err := headProvider.LatestBlockNumber(ctx)
 	if err != nil {
-		fmt.Printf("Error writing external report to file: %v\n", err)
-		return err
+		logging.FromContext(ctx).Warn("could not resolve the latest block, the manifest lists no end block", logging.KeyError, err)
+		return startBlock, 0
 	}
-
-	return nil
+	return startBlock, head
 }
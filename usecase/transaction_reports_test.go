@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+)
+
+// responseStubProvider returns a fixed response for every request
+type responseStubProvider struct {
+	response string
+}
+
+func (p *responseStubProvider) BuildRequestURL(action, walletAddress string) string {
+	return "stub://?action=" + action
+}
+
+func (p *responseStubProvider) FetchTransactionData(ctx context.Context, url, tag string) (string, error) {
+	return p.response, nil
+}
+
+func TestGenerateReportsStreaming(t *testing.T) {
+	wallet := "0x1111111111111111111111111111111111111111"
+	tx := `{"hash":"0xaa","timeStamp":"1704067200","from":"0x1111111111111111111111111111111111111111","to":"0x2222222222222222222222222222222222222222","value":"1","gasUsed":"21000","gasPrice":"1"}`
+
+	tests := []struct {
+		name      string
+		response  string
+		wantRows  int
+		wantFile  bool
+		expectErr bool
+	}{
+		{name: "Complete Response", response: `{"status":"1","message":"OK","result":[` + tx + `,` + tx + `]}`, wantRows: 2, wantFile: true},
+		{name: "No Transactions", response: `{"status":"0","message":"No transactions found","result":[]}`, wantRows: 0, wantFile: false},
+		{name: "Error Result", response: `{"status":"0","message":"NOTOK","result":"Invalid API Key"}`, expectErr: true},
+		{name: "Truncated Response", response: `{"status":"1","message":"OK","result":[` + tx + `,{"hash":"0x`, expectErr: true},
+	}
+
+	chain, _ := chains.Lookup(constants.CHAIN_ETHEREUM)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			chdirTemp(t)
+
+			provider := &responseStubProvider{response: tc.response}
+			opts := NewReportOptions(context.Background(), models.Config{}, provider, NewAddressBook(nil), chain)
+			rows, err := GenerateReports(context.Background(), provider, opts, wallet, constants.EXTERNAL_REPORT)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("GenerateReports() error = %v; expectErr %v", err, tc.expectErr)
+			}
+			if rows != tc.wantRows {
+				t.Errorf("GenerateReports() rows = %d; want %d", rows, tc.wantRows)
+			}
+
+			// Incomplete streams must not leave a half written report behind
+			_, statErr := os.Stat(filepath.Join("files", "reports", wallet+"_ethereum_external_report.csv"))
+			if exists := statErr == nil; exists != tc.wantFile {
+				t.Errorf("report file exists = %v; want %v", exists, tc.wantFile)
+			}
+		})
+	}
+}
+
+func TestExternalRowNativeTransfer(t *testing.T) {
+	tests := []struct {
+		chain    string
+		wantType string
+	}{
+		{chain: constants.CHAIN_ETHEREUM, wantType: "ETH Transfer"},
+		{chain: constants.CHAIN_POLYGON, wantType: "POL Transfer"},
+		{chain: constants.CHAIN_BSC, wantType: "BNB Transfer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			chain, _ := chains.Lookup(tt.chain)
+			row := externalRow(models.ExternalTransaction{Hash: "0xaa", Value: "1"}, ReportOptions{Chain: chain})
+			if row.TransactionType != tt.wantType || row.AssetSymbolName != chain.NativeSymbol {
+				t.Errorf("externalRow() type = %q, asset = %q; want %q, %q", row.TransactionType, row.AssetSymbolName, tt.wantType, chain.NativeSymbol)
+			}
+
+			activity, _ := NewWalletActivity(models.ExternalTransaction{Hash: "0xaa", Value: "1"}, "", ReportOptions{Chain: chain, AddressBook: NewAddressBook(nil)})
+			if activity.TransactionType != tt.wantType {
+				t.Errorf("NewWalletActivity() type = %q; want %q", activity.TransactionType, tt.wantType)
+			}
+		})
+	}
+}
+
+// The gas fee is gasUsed * gasPrice, not the gas limit, internal transactions have none of their own
+func TestRowGasFee(t *testing.T) {
+	chain, _ := chains.Lookup(constants.CHAIN_ETHEREUM)
+	opts := ReportOptions{Chain: chain}
+
+	tests := []struct {
+		name string
+		row  models.ReportResponse
+		want string
+	}{
+		{
+			name: "External",
+			row:  externalRow(models.ExternalTransaction{Gas: "50000", GasUsed: "21000", GasPrice: "20000000000"}, opts),
+			want: "0.00042",
+		},
+		{
+			name: "ERC-20",
+			row:  erc20Row(models.TokenTransaction{Gas: "90000", GasUsed: "65000", GasPrice: "10000000000"}, opts),
+			want: "0.00065",
+		},
+		{
+			name: "ERC-721",
+			row:  erc721Row(models.NftTransaction{Gas: "120000", GasUsed: "100000", GasPrice: "1000000000"}, opts),
+			want: "0.0001",
+		},
+		{
+			name: "Internal",
+			row:  internalRow(models.InternalTransaction{Gas: "2300", GasUsed: "0"}, opts),
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.row.GasFeeNative != tt.want {
+				t.Errorf("GasFeeNative = %q; want %q", tt.row.GasFeeNative, tt.want)
+			}
+		})
+	}
+}
+
+// TestGenerateTransactionReportsFailover runs the reports through a failover chain whose first provider is rate limited
+func TestGenerateTransactionReportsFailover(t *testing.T) {
+	chdirTemp(t)
+	wallet := "0x1111111111111111111111111111111111111111"
+	tx := `{"hash":"0xaa","timeStamp":"1704067200","from":"` + wallet + `","to":"0x2222222222222222222222222222222222222222","value":"1","gasUsed":"21000","gasPrice":"1"}`
+
+	rateLimited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`)
+	}))
+	defer rateLimited.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"1","message":"OK","result":[`+tx+`]}`)
+	}))
+	defer healthy.Close()
+
+	config := models.Config{
+		WalletAddress: wallet,
+		Chains:        []string{constants.CHAIN_ETHEREUM},
+		ReportTypes:   []string{constants.EXTERNAL_REPORT, constants.ERC20_REPORT},
+		Providers:     []string{constants.PROVIDER_ETHERSCAN, constants.PROVIDER_BLOCKSCOUT},
+		Etherscan:     models.ThirdPartyApiConfig{ApiKey: "key", BaseURL: rateLimited.URL},
+		Blockscout:    models.ThirdPartyApiConfig{BaseURL: healthy.URL},
+	}
+	result, err := GenerateTransactionReports(context.Background(), constants.PROVIDER_FAILOVER, config)
+	if err != nil {
+		t.Fatalf("GenerateTransactionReports() error = %v", err)
+	}
+	for _, task := range result.Tasks {
+		if task.ServedBy != constants.PROVIDER_BLOCKSCOUT {
+			t.Errorf("task %s ServedBy = %q; want %q", task.ReportType, task.ServedBy, constants.PROVIDER_BLOCKSCOUT)
+		}
+	}
+
+	manifest, err := VerifyManifest(filepath.Join("files", "reports", constants.MANIFEST_FILE))
+	if err != nil {
+		t.Fatalf("VerifyManifest() error = %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("manifest files = %+v; want both reports", manifest.Files)
+	}
+	for _, entry := range manifest.Files {
+		if entry.ServedBy != constants.PROVIDER_BLOCKSCOUT {
+			t.Errorf("manifest entry %s ServedBy = %q; want %q", entry.File, entry.ServedBy, constants.PROVIDER_BLOCKSCOUT)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+/*
+Package watch polls the configured wallets for new transfers and sends every transfer as a
+signed webhook. The last polled block is kept per wallet, chain and transfer type, every poll
+only asks the provider for the blocks from there on (startblock). The last block is polled again
+because explorers index the transfers of a block one after another, transfers that were
+already sent are recognized by their ID.
+*/
+package watch
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/chains"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/logging"
+	"github.com/coin-tracker/transaction-tracker/shared/metrics"
+	"github.com/coin-tracker/transaction-tracker/shared/webhook"
+	"github.com/coin-tracker/transaction-tracker/tracker"
+	"github.com/coin-tracker/transaction-tracker/usecase"
+)
+
+// Watcher polls wallets for new transfers.
+type Watcher struct {
+	config    models.Config
+	wallets   []models.WalletConfig
+	trackers  []*tracker.Tracker // One per chain
+	book      *usecase.AddressBook
+	sender    *webhook.Sender
+	alerts    *usecase.AlertEngine // Evaluated against every new transfer and approval, optional
+	kinds     []watchKind
+	interval  time.Duration
+	statePath string
+	state     models.WatchState
+}
+
+/*
+New creates a Watcher for the wallets and chains of the config. The tracker options are applied
+to the tracker of every chain, e.g. to pass an HTTP client.
+*/
+func New(providerType string, config models.Config, options ...tracker.Option) (*Watcher, error) {
+	config, err := usecase.ResolveWalletNames(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	wallets := usecase.ConfiguredWallets(config)
+	if len(wallets) == 0 {
+		return nil, fmt.Errorf("no wallets configured, set WALLET_ADDRESS or WALLETS")
+	}
+	if config.Watch.Webhook.URL == "" {
+		return nil, fmt.Errorf("no webhook configured, set WATCH.WEBHOOK.URL")
+	}
+	chainList, err := chains.Resolve(config.Chains)
+	if err != nil {
+		return nil, err
+	}
+	book, err := usecase.LoadAddressBook(config.AddressBookPath, wallets)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		config:    config,
+		wallets:   wallets,
+		book:      book,
+		interval:  time.Duration(config.Watch.IntervalSeconds) * time.Second,
+		statePath: config.Watch.StateFile,
+	}
+	if w.interval <= 0 {
+		w.interval = constants.WATCH_DEFAULT_INTERVAL_SECONDS * time.Second
+	}
+	if w.statePath == "" {
+		w.statePath = constants.WATCH_DEFAULT_STATE_FILE
+	}
+	deadLetterFile := config.Watch.DeadLetterFile
+	if deadLetterFile == "" {
+		deadLetterFile = constants.WATCH_DEFAULT_DEAD_LETTER_FILE
+	}
+	w.sender = webhook.NewSender(config.Watch.Webhook, deadLetterFile)
+
+	if w.alerts, err = usecase.NewAlertEngine(config.Alerts); err != nil {
+		return nil, err
+	}
+	w.kinds = transferKinds
+	if w.alerts.HasRules(constants.ALERT_EVENT_APPROVAL) {
+		w.kinds = append(slices.Clone(transferKinds), approvalKind)
+	}
+
+	for _, chain := range chainList {
+		// The default logger of the command, callers can pass their own with tracker.WithLogger
+		chainOptions := append([]tracker.Option{
+			tracker.WithLogger(slog.Default()),
+			tracker.WithConfig(config),
+			tracker.WithProviderType(providerType),
+			tracker.WithChain(chain.Name),
+			tracker.WithAddressBook(book),
+		}, options...)
+		t, err := tracker.New(chainOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", chain.Name, err)
+		}
+		w.trackers = append(w.trackers, t)
+	}
+
+	if err := w.loadState(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run polls every interval until the context is canceled, failed polls are retried at the next interval.
+func (w *Watcher) Run(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+	logger.Info("watching wallets", "wallets", len(w.wallets), "chains", len(w.trackers), "interval", w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		sent, err := w.Poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			logger.Error("polling wallets failed", logging.KeyError, err)
+		}
+		if sent > 0 {
+			logger.Info("sent new transfers", "transfers", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+Poll checks every wallet once and returns the number of transfers sent. Without a saved position
+the first poll of a wallet only records the head of the chain, earlier transfers are not sent unless
+START_BLOCK is set.
+*/
+func (w *Watcher) Poll(ctx context.Context) (int, error) {
+	sent := 0
+	var errs []error
+	for _, t := range w.trackers {
+		for _, wallet := range w.wallets {
+			for _, kind := range w.kinds {
+				n, err := w.pollTransfers(ctx, t, wallet, kind)
+				sent += n
+				if ctx.Err() != nil {
+					return sent, ctx.Err()
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("[%s %s %s] %w", t.Chain().Name, wallet.Address, kind.reportType, err))
+					continue
+				}
+				if cursor, ok := w.state.Cursors[stateKey(t.Chain().Name, wallet.Address, kind.reportType)]; ok {
+					metrics.LastSyncedBlock.Set(float64(cursor.Block), t.Chain().Name, wallet.Address, kind.reportType)
+				}
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (w *Watcher) pollTransfers(ctx context.Context, t *tracker.Tracker, wallet models.WalletConfig, kind watchKind) (int, error) {
+	key := stateKey(t.Chain().Name, wallet.Address, kind.reportType)
+	cursor, known := w.state.Cursors[key]
+	if !known && w.config.StartBlock == 0 {
+		// First poll of the wallet, transfers are sent from the next block on. The history is not
+		// fetched, explorers return at most 10,000 of the oldest transfers of a wallet.
+		head, err := t.LatestBlock(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("resolving the latest block failed, set START_BLOCK to watch without it: %w", err)
+		}
+		w.state.Cursors[key] = models.WatchCursor{Block: head + 1}
+		return 0, w.saveState()
+	}
+	if !known {
+		cursor.Block = w.config.StartBlock
+	}
+
+	opts := usecase.ReportOptions{Chain: t.Chain(), AddressBook: w.book}
+	items, err := kind.fetch(ctx, t, wallet.Address, tracker.BlockRange{From: cursor.Block}, opts)
+	if err != nil {
+		return 0, err
+	}
+	assignIDs(items)
+	slices.SortStableFunc(items, func(a, b watchItem) int {
+		return cmp.Compare(a.block, b.block)
+	})
+
+	sent := 0
+	for _, item := range items {
+		if item.block == cursor.Block && slices.Contains(cursor.Seen, item.id) {
+			continue
+		}
+		if item.activity != nil {
+			item.activity.ID = item.id
+			item.activity.DetectedAt = time.Now().UTC()
+			w.alerts.Evaluate(ctx, usecase.TransferAlertEvent(*item.activity))
+			// Reverted transactions did not move any value, they only reach the alert rules
+			if item.activity.Status != constants.TRANSACTION_STATUS_FAILED {
+				if err := w.sender.Send(ctx, *item.activity); err != nil {
+					return sent, err
+				}
+				sent++
+			}
+		}
+		if item.approval != nil {
+			w.alerts.ObserveApproval(ctx, *item.approval, wallet.Address, opts)
+		}
+
+		cursor = advance(cursor, item)
+		w.state.Cursors[key] = cursor
+		if err := w.saveState(); err != nil {
+			return sent, err
+		}
+	}
+	if !known {
+		w.state.Cursors[key] = cursor
+		return sent, w.saveState()
+	}
+	return sent, nil
+}
+
+// watchItem is a transfer or an approval found by a poll
+type watchItem struct {
+	id       string
+	block    uint64
+	activity *models.WalletActivity
+	approval *models.ApprovalReportResponse
+}
+
+// assignIDs derives the ID of every item from its content, identical transfers of one transaction are numbered
+func assignIDs(items []watchItem) {
+	occurrences := map[string]int{}
+	for i := range items {
+		var fields []string
+		if a := items[i].activity; a != nil {
+			fields = []string{a.Chain, a.Wallet, a.TransactionType, a.TransactionHash, a.FromAddress, a.ToAddress, a.AssetContractAddress, a.TokenID, a.Value}
+		}
+		if a := items[i].approval; a != nil {
+			fields = []string{a.Chain, constants.ALERT_EVENT_APPROVAL, a.TransactionHash, a.TokenAddress, a.SpenderAddress, a.TokenID, a.Allowance, a.Status}
+		}
+		sum := sha256.Sum256([]byte(strings.ToLower(strings.Join(fields, "|"))))
+		base := hex.EncodeToString(sum[:16])
+		items[i].id = fmt.Sprintf("%s-%d", base, occurrences[base])
+		occurrences[base]++
+	}
+}
+
+// advance moves the cursor to the block of a handled item
+func advance(cursor models.WatchCursor, item watchItem) models.WatchCursor {
+	switch {
+	case item.block > cursor.Block:
+		return models.WatchCursor{Block: item.block, Seen: []string{item.id}}
+	case item.block == cursor.Block:
+		cursor.Seen = append(slices.Clone(cursor.Seen), item.id)
+	}
+	return cursor
+}
+
+func stateKey(chain, wallet, reportType string) string {
+	return chain + "/" + strings.ToLower(wallet) + "/" + reportType
+}
+
+func (w *Watcher) loadState() error {
+	w.state = models.WatchState{Cursors: map[string]models.WatchCursor{}}
+	data, err := os.ReadFile(w.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, &w.state); err != nil {
+		return fmt.Errorf("invalid watch state %s: %w", w.statePath, err)
+	}
+	if w.state.Cursors == nil {
+		w.state.Cursors = map[string]models.WatchCursor{}
+	}
+	return nil
+}
+
+// saveState replaces the state file atomically so a crash never leaves a truncated state behind
+func (w *Watcher) saveState() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(w.statePath)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create watch state directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), w.statePath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
+
+// watchKind fetches the transfers of one account endpoint, or the approvals of the wallet
+type watchKind struct {
+	reportType string
+	fetch      func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error)
+}
+
+var transferKinds = []watchKind{
+	{reportType: constants.EXTERNAL_REPORT, fetch: func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error) {
+		txList, err := t.FetchExternal(ctx, wallet, blocks)
+		return transferItems(txList, wallet, opts), err
+	}},
+	{reportType: constants.INTERNAL_REPORT, fetch: func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error) {
+		txList, err := t.FetchInternal(ctx, wallet, blocks)
+		return transferItems(txList, wallet, opts), err
+	}},
+	{reportType: constants.ERC20_REPORT, fetch: func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error) {
+		txList, err := t.FetchERC20(ctx, wallet, blocks)
+		return transferItems(txList, wallet, opts), err
+	}},
+	{reportType: constants.ERC721_REPORT, fetch: func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error) {
+		txList, err := t.FetchERC721(ctx, wallet, blocks)
+		return transferItems(txList, wallet, opts), err
+	}},
+}
+
+// Approvals are only polled for alert rules, they are not sent to the webhook
+var approvalKind = watchKind{reportType: constants.APPROVAL_REPORT, fetch: func(ctx context.Context, t *tracker.Tracker, wallet string, blocks tracker.BlockRange, opts usecase.ReportOptions) ([]watchItem, error) {
+	rows, err := t.BuildApprovalReport(ctx, wallet, blocks)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]watchItem, 0, len(rows))
+	for _, row := range rows {
+		block, _ := strconv.ParseUint(row.BlockNumber, 10, 64)
+		items = append(items, watchItem{block: block, approval: &row})
+	}
+	return items, nil
+}}
+
+func transferItems[T any](txList []T, wallet string, opts usecase.ReportOptions) []watchItem {
+	items := make([]watchItem, 0, len(txList))
+	for _, tx := range txList {
+		activity, ok := usecase.NewWalletActivity(tx, wallet, opts)
+		if ok {
+			items = append(items, watchItem{block: activity.BlockNumber, activity: &activity})
+		}
+	}
+	return items
+}
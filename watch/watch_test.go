@@ -0,0 +1,360 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coin-tracker/transaction-tracker/models"
+	"github.com/coin-tracker/transaction-tracker/shared/constants"
+	"github.com/coin-tracker/transaction-tracker/shared/webhook"
+)
+
+const wallet = "0x1111111111111111111111111111111111111111"
+
+// webhookReceiver records the activities of valid webhook deliveries
+type webhookReceiver struct {
+	mu         sync.Mutex
+	activities []models.WalletActivity
+}
+
+func (r *webhookReceiver) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		timestamp := req.Header.Get(constants.WEBHOOK_TIMESTAMP_HEADER)
+		if req.Header.Get(constants.WEBHOOK_SIGNATURE_HEADER) != webhook.Sign("watch-secret", timestamp, body) {
+			t.Errorf("invalid webhook signature")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		activity := models.WalletActivity{}
+		if err := json.Unmarshal(body, &activity); err != nil {
+			t.Errorf("invalid webhook payload: %v", err)
+		}
+		r.mu.Lock()
+		r.activities = append(r.activities, activity)
+		r.mu.Unlock()
+	})
+}
+
+func newTestWatcher(t *testing.T, webhookURL, stateFile string, startBlock uint64) *Watcher {
+	t.Helper()
+	config := models.Config{
+		WalletAddress: wallet,
+		StartBlock:    startBlock,
+		Fixtures:      models.FixtureConfig{ReplayDirectory: filepath.Join("..", "usecase", "testdata", "fixtures")},
+		Watch: models.WatchConfig{
+			StateFile:      stateFile,
+			DeadLetterFile: filepath.Join(filepath.Dir(stateFile), "dead_letter.jsonl"),
+			Webhook:        models.WebhookConfig{URL: webhookURL, Secret: "watch-secret"},
+		},
+	}
+	watcher, err := New(constants.PROVIDER_ETHERSCAN, config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return watcher
+}
+
+func TestWatcherPoll(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(t))
+	defer server.Close()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	// Fixture transfers are in the blocks 19000000 to 19000400
+	watcher := newTestWatcher(t, server.URL, stateFile, 19000000)
+	sent, err := watcher.Poll(context.Background())
+	if err != nil || sent != 5 {
+		t.Fatalf("Poll() = %d, %v; want 5 transfers", sent, err)
+	}
+
+	directions := map[string]int{}
+	for _, activity := range receiver.activities {
+		directions[activity.Direction]++
+		if activity.ID == "" || activity.Chain != constants.CHAIN_ETHEREUM || activity.BlockNumber < 19000000 {
+			t.Errorf("activity = %+v; want an ID, chain and block", activity)
+		}
+	}
+	if directions[constants.DIRECTION_IN]+directions[constants.DIRECTION_OUT]+directions[constants.DIRECTION_SELF] != 5 {
+		t.Errorf("directions = %v; want one per transfer", directions)
+	}
+
+	// Transfers that were sent are not sent again, also not after a restart
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 0 {
+		t.Errorf("second Poll() = %d, %v; want 0", sent, err)
+	}
+	restarted := newTestWatcher(t, server.URL, stateFile, 19000000)
+	if sent, err := restarted.Poll(context.Background()); err != nil || sent != 0 {
+		t.Errorf("Poll() after a restart = %d, %v; want 0", sent, err)
+	}
+}
+
+// explorerStub serves an Etherscan compatible API with the external transfers of the wallet, sorted
+// ascending and capped at 10,000 rows like txlist, and the head of the chain
+type explorerStub struct {
+	mu        sync.Mutex
+	head      uint64
+	transfers []uint64 // Block of every external transfer of the wallet
+}
+
+func (e *explorerStub) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		query := r.URL.Query()
+		switch query.Get("action") {
+		case constants.BLOCK_NUMBER_ACTION:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":83,"result":"0x%x"}`, e.head)
+		case constants.EXTERNAL_REPORT_ACTION:
+			startBlock, _ := strconv.ParseUint(query.Get("startblock"), 10, 64)
+			rows := []string{}
+			for i, block := range e.transfers {
+				if block >= startBlock && len(rows) < 10000 {
+					rows = append(rows, fmt.Sprintf(`{"blockNumber":"%d","timeStamp":"1704067200","hash":"0x%064x","from":"0x2222222222222222222222222222222222222222","to":"%s","value":"1","gasUsed":"21000","gasPrice":"1","isError":"0","txreceipt_status":"1"}`, block, i, wallet))
+				}
+			}
+			if len(rows) == 0 {
+				fmt.Fprint(w, `{"status":"0","message":"No transactions found","result":[]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"status":"1","message":"OK","result":[%s]}`, strings.Join(rows, ","))
+		default:
+			fmt.Fprint(w, `{"status":"0","message":"No transactions found","result":[]}`)
+		}
+	})
+}
+
+func TestWatcherFirstPoll(t *testing.T) {
+	receiver := &webhookReceiver{}
+	webhookServer := httptest.NewServer(receiver.handler(t))
+	defer webhookServer.Close()
+
+	// More transfers than txlist returns, the newest ones are beyond the cap
+	explorer := &explorerStub{head: 20000}
+	for block := uint64(1); block <= 12000; block++ {
+		explorer.transfers = append(explorer.transfers, block)
+	}
+	explorerServer := httptest.NewServer(explorer.handler(t))
+	defer explorerServer.Close()
+
+	dir := t.TempDir()
+	config := models.Config{
+		WalletAddress: wallet,
+		Etherscan:     models.ThirdPartyApiConfig{BaseURL: explorerServer.URL, ApiKey: "key"},
+		Watch: models.WatchConfig{
+			StateFile:      filepath.Join(dir, "state.json"),
+			DeadLetterFile: filepath.Join(dir, "dead_letter.jsonl"),
+			Webhook:        models.WebhookConfig{URL: webhookServer.URL, Secret: "watch-secret"},
+		},
+	}
+	watcher, err := New(constants.PROVIDER_ETHERSCAN, config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Without a start block the history of the wallet is not sent
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("first Poll() = %d, %v; want 0", sent, err)
+	}
+	cursor := watcher.state.Cursors[stateKey(constants.CHAIN_ETHEREUM, wallet, constants.EXTERNAL_REPORT)]
+	if cursor.Block != 20001 {
+		t.Errorf("external cursor = %+v; want the block after the head 20000", cursor)
+	}
+
+	// Only the transfer after the first poll is sent
+	explorer.mu.Lock()
+	explorer.transfers = append(explorer.transfers, 20005)
+	explorer.head = 20010
+	explorer.mu.Unlock()
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("second Poll() = %d, %v; want the 1 new transfer", sent, err)
+	}
+	if got := receiver.activities[0].BlockNumber; got != 20005 {
+		t.Errorf("sent transfer of block %d; want 20005", got)
+	}
+}
+
+// rpcNodeStub serves a JSON-RPC node without trace_filter, so external transfers are found by
+// scanning the blocks up to the head
+type rpcNodeStub struct {
+	mu        sync.Mutex
+	head      uint64
+	transfers []uint64 // Block of every external transfer of the wallet
+}
+
+func (n *rpcNodeStub) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		request := struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid rpc request: %v", err)
+			return
+		}
+
+		result := "[]"
+		switch request.Method {
+		case "eth_blockNumber":
+			result = fmt.Sprintf(`"0x%x"`, n.head)
+		case "trace_filter":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"the method trace_filter does not exist"}}`, request.ID)
+			return
+		case "eth_getBlockByNumber":
+			var hexNumber string
+			json.Unmarshal(request.Params[0], &hexNumber)
+			number, _ := strconv.ParseUint(strings.TrimPrefix(hexNumber, "0x"), 16, 64)
+			transactions := []string{}
+			if slices.Contains(n.transfers, number) {
+				transactions = append(transactions, fmt.Sprintf(`{"hash":"0x%064x","nonce":"0x0","blockHash":"0x%064x","blockNumber":"0x%x","transactionIndex":"0x0","from":"0x2222222222222222222222222222222222222222","to":"%s","value":"0x1","gas":"0x5208","gasPrice":"0x1","input":"0x"}`, number, number, number, wallet))
+			}
+			result = fmt.Sprintf(`{"number":"0x%x","hash":"0x%064x","timestamp":"0x65920080","transactions":[%s]}`, number, number, strings.Join(transactions, ","))
+		case "eth_getTransactionReceipt":
+			var hash string
+			json.Unmarshal(request.Params[0], &hash)
+			result = fmt.Sprintf(`{"transactionHash":"%s","gasUsed":"0x5208","effectiveGasPrice":"0x1","cumulativeGasUsed":"0x5208","contractAddress":null,"status":"0x1"}`, hash)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID, result)
+	})
+}
+
+func TestWatcherRpcHead(t *testing.T) {
+	receiver := &webhookReceiver{}
+	webhookServer := httptest.NewServer(receiver.handler(t))
+	defer webhookServer.Close()
+	node := &rpcNodeStub{head: 100}
+	nodeServer := httptest.NewServer(node.handler(t))
+	defer nodeServer.Close()
+
+	dir := t.TempDir()
+	config := models.Config{
+		WalletAddress: wallet,
+		Provider:      constants.PROVIDER_RPC,
+		Rpc:           models.RpcConfig{Endpoints: map[string]string{constants.CHAIN_ETHEREUM: nodeServer.URL}},
+		Watch: models.WatchConfig{
+			StateFile:      filepath.Join(dir, "state.json"),
+			DeadLetterFile: filepath.Join(dir, "dead_letter.jsonl"),
+			Webhook:        models.WebhookConfig{URL: webhookServer.URL, Secret: "watch-secret"},
+		},
+	}
+	watcher, err := New(constants.PROVIDER_RPC, config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	key := stateKey(constants.CHAIN_ETHEREUM, wallet, constants.EXTERNAL_REPORT)
+
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("first Poll() = %d, %v; want 0", sent, err)
+	}
+	if cursor := watcher.state.Cursors[key]; cursor.Block != 101 {
+		t.Fatalf("external cursor = %+v; want the block after the head 100", cursor)
+	}
+
+	// The head moves without a transfer, the next poll queries the same range again
+	node.mu.Lock()
+	node.head = 110
+	node.mu.Unlock()
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("second Poll() = %d, %v; want 0", sent, err)
+	}
+
+	// The provider of that range must not keep the head of the previous poll
+	node.mu.Lock()
+	node.transfers = append(node.transfers, 115)
+	node.head = 120
+	node.mu.Unlock()
+	if sent, err := watcher.Poll(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("third Poll() = %d, %v; want the transfer of block 115", sent, err)
+	}
+	if got := receiver.activities[0].BlockNumber; got != 115 {
+		t.Errorf("sent transfer of block %d; want 115", got)
+	}
+}
+
+func TestAssignIDs(t *testing.T) {
+	transfer := models.WalletActivity{Chain: "ethereum", TransactionHash: "0xabc", FromAddress: "0x1", ToAddress: "0x2", Value: "5"}
+	other := models.WalletActivity{Chain: "ethereum", TransactionHash: "0xdef"}
+	items := []watchItem{{activity: &transfer}, {activity: &transfer}, {activity: &other}}
+	assignIDs(items)
+
+	if items[0].id == items[1].id || items[0].id == items[2].id {
+		t.Errorf("IDs = %s, %s, %s; want unique IDs", items[0].id, items[1].id, items[2].id)
+	}
+	again := []watchItem{{activity: &transfer}}
+	assignIDs(again)
+	if again[0].id != items[0].id {
+		t.Errorf("ID = %s; want the stable ID %s", again[0].id, items[0].id)
+	}
+}
+
+func TestWatcherAlerts(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(t))
+	defer server.Close()
+	dir := t.TempDir()
+	alertsFile := filepath.Join(dir, "alerts.jsonl")
+
+	unlabeled := false
+	config := models.Config{
+		WalletAddress: wallet,
+		StartBlock:    19000000,
+		Fixtures:      models.FixtureConfig{ReplayDirectory: filepath.Join("..", "usecase", "testdata", "fixtures")},
+		Watch: models.WatchConfig{
+			StateFile: filepath.Join(dir, "state.json"),
+			Webhook:   models.WebhookConfig{URL: server.URL, Secret: "watch-secret"},
+		},
+		Alerts: models.AlertConfig{
+			File: alertsFile,
+			Rules: []models.AlertRule{
+				{Name: "Outgoing ETH", Event: "transfer", Direction: "out", Asset: "ETH", AmountAbove: "1"},
+				{Name: "Unknown spender", Event: "approval", Status: "active", CounterpartyLabeled: &unlabeled},
+			},
+		},
+	}
+	watcher, err := New(constants.PROVIDER_ETHERSCAN, config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	data, err := os.ReadFile(alertsFile)
+	if err != nil {
+		t.Fatalf("alerts file not written: %v", err)
+	}
+	rules := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		alert := models.Alert{}
+		if err := json.Unmarshal([]byte(line), &alert); err != nil {
+			t.Fatalf("invalid alert %s: %v", line, err)
+		}
+		rules = append(rules, alert.Rule)
+	}
+	slices.Sort(rules)
+	if !slices.Equal(rules, []string{"Outgoing ETH", "Unknown spender"}) {
+		t.Errorf("alerts = %v; want the 1.5 ETH transfer and the approval", rules)
+	}
+
+	// Alerts are sent once, like the webhooks
+	if _, err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("second Poll() error = %v", err)
+	}
+	if again, _ := os.ReadFile(alertsFile); len(again) != len(data) {
+		t.Errorf("second Poll() sent alerts again")
+	}
+}